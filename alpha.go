@@ -0,0 +1,146 @@
+package alpha
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// Limits bounds the resources a running program can use, zero means no
+// limit
+type Limits = interpreter.Limits
+
+// LimitError is returned by programs exceeding one of their limits
+type LimitError = interpreter.LimitError
+
+// RuntimeError is returned by programs failing while running, it carries
+// the alpha call stack at the time of the failure
+type RuntimeError = interpreter.RuntimeError
+
+// The Engine structure compiles and runs alpha programs for Go applications,
+// an engine can be reused to compile and run any number of programs.
+type Engine struct {
+	// SearchPath lists the directories imports are looked up in
+	SearchPath []string
+	// Limits bounds every program run by the engine
+	Limits Limits
+
+	library *stdlib.Library
+	globals []global
+}
+
+type global struct {
+	name  string
+	t     types.Type
+	value any
+}
+
+// Program is a compiled program ready to be run by the engine
+type Program struct {
+	program *loader.Program
+	globals map[*types.Symbol]any
+}
+
+// Root returns the tree of the program
+func (p *Program) Root() ast.Command {
+	return p.program.Root
+}
+
+// NewEngine returns an engine providing the standard builtins
+func NewEngine() *Engine {
+	return &Engine{
+		library: stdlib.Prelude(),
+	}
+}
+
+// Library returns the builtins available to the programs of the engine
+func (e *Engine) Library() *stdlib.Library {
+	return e.library
+}
+
+// RegisterFunc exposes a Go function to the programs compiled from now on,
+// see stdlib.Library.RegisterFunc for how its signature is derived.
+func (e *Engine) RegisterFunc(name string, fn any) error {
+	return e.library.RegisterFunc(name, fn)
+}
+
+// SetGlobal declares a variable for the programs compiled from now on, its
+// type is derived from the Go value and every run starts with that value.
+func (e *Engine) SetGlobal(name string, value any) error {
+	if !stdlib.IsIdentifier(name) {
+		return fmt.Errorf("global %s: not a valid identifier", name)
+	}
+	if value == nil {
+		return fmt.Errorf("global %s: unsupported type %T", name, value)
+	}
+	t := stdlib.TypeOf(reflect.TypeOf(value))
+	if t == types.Invalid || t == types.Any {
+		return fmt.Errorf("global %s: unsupported type %T", name, value)
+	}
+
+	g := global{name: name, t: t, value: stdlib.ValueOf(reflect.ValueOf(value))}
+	for i := range e.globals {
+		if e.globals[i].name == name {
+			e.globals[i] = g
+			return nil
+		}
+	}
+	e.globals = append(e.globals, g)
+	return nil
+}
+
+// Compile parses and checks the program in src, imports are resolved
+// relative to the current directory
+func (e *Engine) Compile(src string) (*Program, error) {
+	return e.compile(func(l *loader.Loader) (*loader.Program, error) {
+		return l.LoadSource("<input>", src)
+	})
+}
+
+// CompileFile parses and checks the program in the named file
+func (e *Engine) CompileFile(name string) (*Program, error) {
+	return e.compile(func(l *loader.Loader) (*loader.Program, error) {
+		return l.Load(name)
+	})
+}
+
+func (e *Engine) compile(load func(*loader.Loader) (*loader.Program, error)) (*Program, error) {
+	scope := types.NewScope(e.library.Scope(types.Universe()))
+	globals := map[*types.Symbol]any{}
+	for _, g := range e.globals {
+		sym := &types.Symbol{Name: g.name, Kind: types.Var, Type: g.t}
+		scope.Insert(sym)
+		globals[sym] = g.value
+	}
+
+	program, err := load(loader.NewLoader(e.SearchPath, scope))
+	if err != nil {
+		return nil, err
+	}
+	return &Program{
+		program: program,
+		globals: globals,
+	}, nil
+}
+
+// Run executes a compiled program reading from stdin and writing to stdout.
+// A program calling exit returns a *stdlib.ExitError with its status code,
+// failing returns a *RuntimeError, exceeding a limit returns a *LimitError
+// and a program stopped through ctx returns a *RuntimeError wrapping
+// ctx.Err(). The last two carry the position and the call stack of the
+// program when it stopped.
+func (e *Engine) Run(ctx context.Context, program *Program, stdin io.Reader, stdout io.Writer) error {
+	in := interpreter.NewInterpreter(program.program.Info, e.library, stdin, stdout)
+	in.SetLimits(e.Limits)
+	for sym, value := range program.globals {
+		in.SetValue(sym, value)
+	}
+	return in.Run(ctx, program.program.Root)
+}
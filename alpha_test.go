@@ -0,0 +1,45 @@
+package alpha
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSetGlobal(t *testing.T) {
+	e := NewEngine()
+	tests := []struct {
+		name  string
+		value any
+		err   string
+	}{
+		{name: "x", value: nil, err: "global x: unsupported type <nil>"},
+		{name: "x", value: []int{1}, err: "global x: unsupported type []int"},
+		{name: "if", value: 1, err: "global if: not a valid identifier"},
+		{name: "two words", value: 1, err: "global two words: not a valid identifier"},
+		{name: "", value: 1, err: "global : not a valid identifier"},
+		{name: "1x", value: 1, err: "global 1x: not a valid identifier"},
+	}
+	for _, test := range tests {
+		err := e.SetGlobal(test.name, test.value)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("SetGlobal(%q, %v) = %v, want %q", test.name, test.value, err, test.err)
+		}
+	}
+
+	if err := e.SetGlobal("user", "Aaron"); err != nil {
+		t.Fatal(err)
+	}
+	program, err := e.Compile("println(concat(\"hola \", user))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := e.Run(context.Background(), program, strings.NewReader(""), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hola Aaron\n" {
+		t.Errorf("printed %q", out.String())
+	}
+}
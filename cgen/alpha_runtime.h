@@ -0,0 +1,151 @@
+/* alpha_runtime.h: runtime support for C programs generated by alpha. */
+#ifndef ALPHA_RUNTIME_H
+#define ALPHA_RUNTIME_H
+
+#include <math.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static inline void alpha_fail(const char *pos, const char *msg) {
+    fflush(stdout);
+    fprintf(stderr, "%s: runtime error: %s\n", pos, msg);
+    exit(1);
+}
+
+/* the messages the builtins fail with, cgen defines them from the table of
+   the stdlib package */
+/* failures */
+
+static inline void alpha_failf(const char *pos, const char *format, ...) {
+    va_list args;
+    fflush(stdout);
+    fprintf(stderr, "%s: runtime error: ", pos);
+    va_start(args, format);
+    vfprintf(stderr, format, args);
+    va_end(args);
+    fputc('\n', stderr);
+    exit(1);
+}
+
+/* alpha_quote quotes s the way %q does in Go, bytes outside of ASCII are
+   copied as they are */
+static inline const char *alpha_quote(const char *s) {
+    static const char controls[] = "\a\b\f\n\r\t\v", letters[] = "abfnrtv";
+    char *out = malloc(4 * strlen(s) + 3), *p = out;
+    *p++ = '"';
+    for (; *s != '\0'; s++) {
+        unsigned char c = (unsigned char)*s;
+        const char *control = strchr(controls, c);
+        if (c == '"' || c == '\\') {
+            *p++ = '\\';
+            *p++ = (char)c;
+        } else if (control != NULL) {
+            *p++ = '\\';
+            *p++ = letters[control - controls];
+        } else if (c < 0x20 || c == 0x7f) {
+            p += sprintf(p, "\\x%02x", c);
+        } else {
+            *p++ = (char)c;
+        }
+    }
+    *p++ = '"';
+    *p = '\0';
+    return out;
+}
+
+static inline void alpha_print_int(long long value) { printf("%lld", value); }
+/* infinities and NaN are printed the way Go prints them */
+static inline void alpha_print_float(double value) {
+    if (isnan(value)) {
+        fputs("NaN", stdout);
+    } else if (isinf(value)) {
+        fputs(value > 0 ? "+Inf" : "-Inf", stdout);
+    } else {
+        printf("%.6g", value);
+    }
+}
+static inline void alpha_print_bool(int value) { fputs(value ? "true" : "false", stdout); }
+static inline void alpha_print_str(const char *value) { fputs(value, stdout); }
+
+/* integer arithmetic wraps around like it does in Go, signed overflow is
+   undefined in C so it goes through unsigned integers */
+static inline long long alpha_add(long long a, long long b) { return (long long)((unsigned long long)a + (unsigned long long)b); }
+static inline long long alpha_sub(long long a, long long b) { return (long long)((unsigned long long)a - (unsigned long long)b); }
+static inline long long alpha_mul(long long a, long long b) { return (long long)((unsigned long long)a * (unsigned long long)b); }
+
+static inline long long alpha_div(long long a, long long b, const char *pos) {
+    if (b == 0) {
+        alpha_fail(pos, "integer division by zero");
+    }
+    if (b == -1) {
+        return alpha_sub(0, a);
+    }
+    return a / b;
+}
+
+static inline const char *alpha_readLine(void) {
+    size_t len = 0, cap = 64;
+    char *line = malloc(cap);
+    int c;
+    while ((c = getchar()) != EOF && c != '\n') {
+        if (len + 1 >= cap) {
+            cap *= 2;
+            line = realloc(line, cap);
+        }
+        line[len++] = (char)c;
+    }
+    if (len > 0 && line[len - 1] == '\r') {
+        len--;
+    }
+    line[len] = '\0';
+    return line;
+}
+
+static inline long long alpha_readInt(const char *pos) {
+    const char *line = alpha_readLine();
+    char *end;
+    long long value = strtoll(line, &end, 10);
+    while (*end == ' ' || *end == '\t') {
+        end++;
+    }
+    if (end == line || *end != '\0') {
+        alpha_failf(pos, ALPHA_FAILURE_readInt, alpha_quote(line));
+    }
+    return value;
+}
+
+static inline long long alpha_length(const char *s) { return (long long)strlen(s); }
+
+static inline const char *alpha_concat(const char *a, const char *b) {
+    size_t la = strlen(a), lb = strlen(b);
+    char *out = malloc(la + lb + 1);
+    memcpy(out, a, la);
+    memcpy(out + la, b, lb + 1);
+    return out;
+}
+
+static inline const char *alpha_substr(const char *s, long long start, long long n, const char *pos) {
+    long long len = (long long)strlen(s);
+    if (start < 0 || n < 0 || start > len || n > len - start) {
+        alpha_failf(pos, ALPHA_FAILURE_substr, n, start, len);
+    }
+    char *out = malloc((size_t)n + 1);
+    memcpy(out, s + start, (size_t)n);
+    out[n] = '\0';
+    return out;
+}
+
+static inline long long alpha_toInt(double f) { return (long long)f; }
+static inline double alpha_toFloat(long long i) { return (double)i; }
+static inline double alpha_abs(double f) { return fabs(f); }
+static inline double alpha_sqrt(double f) { return sqrt(f); }
+static inline double alpha_pow(double x, double y) { return pow(x, y); }
+
+static inline void alpha_exit(long long code) {
+    fflush(stdout);
+    exit((int)code);
+}
+
+#endif
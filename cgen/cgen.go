@@ -0,0 +1,357 @@
+package cgen
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// RuntimeHeader is the name generated programs include the runtime as
+const RuntimeHeader = "alpha_runtime.h"
+
+// Runtime is the header generated programs are compiled along with
+var Runtime = runtime()
+
+//go:embed alpha_runtime.h
+var header []byte
+
+// runtime defines the messages of stdlib.Failures in the header, as
+// ALPHA_FAILURE_ followed by the name of the builtin
+func runtime() []byte {
+	names := []string{}
+	for name := range stdlib.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var defines strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&defines, "#define ALPHA_FAILURE_%s %s\n", name, quote(stdlib.Printf(name)))
+	}
+	return bytes.Replace(header, []byte("/* failures */\n"), []byte(defines.String()), 1)
+}
+
+// The Generator structure translates functions of the intermediate
+// representation into portable C99. Every variable is declared at the top
+// of main and every basic block becomes a label jumped to with goto.
+type Generator struct {
+	fn     *ir.Func
+	out    bytes.Buffer
+	labels map[*ir.Block]bool
+	errors []error
+}
+
+func NewGenerator(fn *ir.Func) *Generator {
+	return &Generator{
+		fn:     fn,
+		labels: map[*ir.Block]bool{},
+	}
+}
+
+// Generate translates fn, which must not be in SSA form, into a C source
+// file that includes RuntimeHeader
+func Generate(fn *ir.Func) ([]byte, error) {
+	return NewGenerator(fn).Program()
+}
+
+func (g *Generator) errorf(pos ast.Position, format string, args ...any) {
+	g.errors = append(g.errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+// line writes a statement of main
+func (g *Generator) line(format string, args ...any) {
+	g.out.WriteString("    ")
+	fmt.Fprintf(&g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+// next returns the block laid out after b, control falls through to it
+// without a goto
+func (g *Generator) next(b *ir.Block) *ir.Block {
+	if b.Index+1 < len(g.fn.Blocks) {
+		return g.fn.Blocks[b.Index+1]
+	}
+	return nil
+}
+
+// Program translates the function into main, only the blocks some goto
+// jumps to get a label
+func (g *Generator) Program() ([]byte, error) {
+	if g.fn.SSA {
+		return nil, fmt.Errorf("cgen: %s is in SSA form", g.fn.Name)
+	}
+	for _, b := range g.fn.Blocks {
+		for i, succ := range b.Succs {
+			if succ != g.next(b) || b.Terminator().Op == ir.Branch && i == 0 {
+				g.labels[succ] = true
+			}
+		}
+	}
+
+	g.out.WriteString("/* Code generated by alpha compile. DO NOT EDIT. */\n")
+	fmt.Fprintf(&g.out, "#include \"%s\"\n\nint main(void) {\n", RuntimeHeader)
+	// alpha allows unused declarations, every variable is marked as used
+	for _, v := range g.fn.Vars() {
+		g.line("%s %s = %s; (void)%s;", cType(v.T), v.Ident(), zero(v.T), v.Ident())
+	}
+	for _, b := range g.fn.Blocks {
+		g.Block(b)
+	}
+	g.out.WriteString("}\n")
+
+	if len(g.errors) > 0 {
+		return nil, g.errors[0]
+	}
+	return g.out.Bytes(), nil
+}
+
+// Block translates the instructions of a basic block
+func (g *Generator) Block(b *ir.Block) {
+	if g.labels[b] {
+		fmt.Fprintf(&g.out, "%s:;\n", b)
+	}
+	for _, instr := range b.Instrs {
+		switch {
+		case instr.Op == ir.Copy || instr.Op == ir.Declare:
+			g.line("%s = %s;", instr.Dst.Ident(), g.value(instr.Args[0]))
+		case instr.Op == ir.Convert:
+			g.line("%s = (double)%s;", instr.Dst.Ident(), g.value(instr.Args[0]))
+		case instr.Op.IsBinary():
+			g.line("%s = %s;", instr.Dst.Ident(), g.binary(instr))
+		case instr.Op == ir.Call:
+			g.call(instr)
+		case instr.Op == ir.Assert:
+			g.line("if (!%s) alpha_fail(%s, %s);", g.value(instr.Args[0]), strconv.Quote(instr.Pos.String()), g.value(instr.Args[1]))
+		case instr.Op == ir.Jump:
+			{
+				if b.Succs[0] != g.next(b) {
+					g.line("goto %s;", b.Succs[0])
+				}
+			}
+		case instr.Op == ir.Branch:
+			{
+				cond := g.value(instr.Args[0])
+				g.line("if (%s) goto %s;", cond, b.Succs[0])
+				if b.Succs[1] != g.next(b) {
+					g.line("goto %s;", b.Succs[1])
+				}
+			}
+		case instr.Op == ir.Return:
+			g.line("return 0;")
+		default:
+			g.errorf(instr.Pos, "%s can't be translated to C", instr.Op)
+		}
+	}
+}
+
+// value returns the C expression for an operand
+func (g *Generator) value(value ir.Value) string {
+	v, ok := value.(*ir.Var)
+	if ok {
+		return v.Ident()
+	}
+	switch c := value.(*ir.Const).Value.(type) {
+	case int:
+		{
+			// the literal of the smallest integer doesn't fit in a long long
+			if c == math.MinInt64 {
+				return "(-9223372036854775807LL - 1)"
+			}
+			return strconv.Itoa(c) + "LL"
+		}
+	case float64:
+		return formatFloat(c)
+	case string:
+		return quote(c)
+	case bool:
+		{
+			if c {
+				return "1"
+			}
+			return "0"
+		}
+	}
+	return zero(value.Type())
+}
+
+// binary returns the C expression of an operation, strings are compared
+// with strcmp and integer arithmetic goes through the runtime, which wraps
+// around and checks divisors
+func (g *Generator) binary(instr *ir.Instr) string {
+	lhs, rhs := g.value(instr.Args[0]), g.value(instr.Args[1])
+	switch t := instr.Args[0].Type(); {
+	case t == types.String:
+		{
+			if instr.Op != ir.Equal {
+				g.errorf(instr.Pos, "operator %s not defined on strings", instr.Op)
+			}
+			return fmt.Sprintf("strcmp(%s, %s) == 0", lhs, rhs)
+		}
+	case t == types.Integer && instr.Op == ir.Div:
+		return fmt.Sprintf("alpha_div(%s, %s, %s)", lhs, rhs, strconv.Quote(instr.Pos.String()))
+	case t == types.Integer && !instr.Op.IsComparison():
+		return fmt.Sprintf("alpha_%s(%s, %s)", arithmetic[instr.Op], lhs, rhs)
+	}
+	return fmt.Sprintf("%s %s %s", lhs, operators[instr.Op], rhs)
+}
+
+// call translates a call to a builtin, print and println take values of any
+// type so they are expanded into a call per argument.
+func (g *Generator) call(instr *ir.Instr) {
+	sym := instr.Callee
+	switch {
+	case sym.Name == "print" || sym.Name == "println":
+		{
+			for _, arg := range instr.Args {
+				g.line("alpha_print_%s(%s);", printer(arg.Type()), g.value(arg))
+			}
+			if sym.Name == "println" {
+				g.line("alpha_print_str(\"\\n\");")
+			}
+			return
+		}
+	case !builtins[sym.Name]:
+		{
+			g.errorf(instr.Pos, "%s can't be translated to C, only the prelude builtins can", sym.Name)
+			return
+		}
+	}
+
+	values := make([]string, len(instr.Args))
+	for i, arg := range instr.Args {
+		values[i] = g.value(arg)
+	}
+	if failing[sym.Name] {
+		values = append(values, strconv.Quote(instr.Pos.String()))
+	}
+	call := fmt.Sprintf("alpha_%s(%s)", sym.Name, strings.Join(values, ", "))
+	if instr.Dst == nil {
+		g.line("%s;", call)
+		return
+	}
+	g.line("%s = %s;", instr.Dst.Ident(), call)
+}
+
+// formatFloat writes a float literal C won't mistake for an integer
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "INFINITY"
+	case math.IsInf(f, -1):
+		return "(-INFINITY)"
+	case math.IsNaN(f):
+		return "NAN"
+	}
+	value := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(value, ".e") {
+		value += ".0"
+	}
+	return value
+}
+
+// quote writes a C string literal, every byte outside of printable ASCII is
+// escaped in octal so it can't merge with the characters following it.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, "\\%03o", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// operators maps the operations to the C operators, booleans share the
+// equality of integers
+var operators = map[ir.Op]string{
+	ir.Add:          "+",
+	ir.Sub:          "-",
+	ir.Mul:          "*",
+	ir.Div:          "/",
+	ir.Less:         "<",
+	ir.Greater:      ">",
+	ir.LessEqual:    "<=",
+	ir.GreaterEqual: ">=",
+	ir.Equal:        "==",
+}
+
+// arithmetic maps the integer operations to the runtime functions
+// computing them
+var arithmetic = map[ir.Op]string{
+	ir.Add: "add",
+	ir.Sub: "sub",
+	ir.Mul: "mul",
+}
+
+// builtins lists the prelude builtins the runtime implements
+var builtins = map[string]bool{
+	"readLine": true,
+	"readInt":  true,
+	"length":   true,
+	"concat":   true,
+	"substr":   true,
+	"toInt":    true,
+	"toFloat":  true,
+	"abs":      true,
+	"sqrt":     true,
+	"pow":      true,
+	"exit":     true,
+}
+
+// failing lists the builtins that take the position of the call to report
+// their runtime errors
+var failing = map[string]bool{
+	"readInt": true,
+	"substr":  true,
+}
+
+func cType(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "long long"
+	case types.Float:
+		return "double"
+	case types.String:
+		return "const char *"
+	}
+	return "int"
+}
+
+func zero(t types.Type) string {
+	switch t {
+	case types.Float:
+		return "0.0"
+	case types.String:
+		return "\"\""
+	}
+	return "0"
+}
+
+func printer(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "int"
+	case types.Float:
+		return "float"
+	case types.Boolean:
+		return "bool"
+	}
+	return "str"
+}
@@ -0,0 +1,170 @@
+package cgen_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/cgen"
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// compiler returns the C compiler in $PATH, the tests are skipped without
+// one
+func compiler(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{"cc", "gcc", "clang"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	t.Skip("no C compiler in $PATH")
+	return ""
+}
+
+// compare runs a program with the interpreter and as a C binary, before and
+// after optimizing. The output must match, and so must the exit status and
+// the position of a runtime error.
+func compare(t *testing.T, program *loader.Program, input string) {
+	t.Helper()
+	cc := compiler(t)
+	var want bytes.Buffer
+	wantErr := interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(input), &want).Run(context.Background(), program.Root)
+
+	for _, optimize := range []bool{false, true} {
+		fn, err := ir.Lower(program.Root, program.Info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if optimize {
+			fn.Optimize(nil)
+			fn.FromSSA()
+		}
+		src, err := cgen.Generate(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, cgen.RuntimeHeader), cgen.Runtime, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.c"), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		bin := filepath.Join(dir, "main")
+		if out, err := exec.Command(cc, "-std=c99", "-o", bin, filepath.Join(dir, "main.c"), "-lm").CombinedOutput(); err != nil {
+			t.Fatalf("optimized %t: %v\n%s\n%s", optimize, err, out, src)
+		}
+
+		var got, stderr bytes.Buffer
+		cmd := exec.Command(bin)
+		cmd.Stdin = strings.NewReader(input)
+		cmd.Stdout, cmd.Stderr = &got, &stderr
+		err = cmd.Run()
+		if got.String() != want.String() {
+			t.Errorf("optimized %t: the binary prints %q, the interpreter %q", optimize, got.String(), want.String())
+		}
+
+		status := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		var exit *stdlib.ExitError
+		var runtimeErr *interpreter.RuntimeError
+		switch {
+		case errors.As(wantErr, &exit):
+			{
+				if status != exit.Code {
+					t.Errorf("optimized %t: the binary exits with status %d, the interpreter with %d", optimize, status, exit.Code)
+				}
+			}
+		case errors.As(wantErr, &runtimeErr):
+			{
+				if msg := runtimeErr.Pos.String() + ": runtime error: " + runtimeErr.Msg + "\n"; status != 1 || stderr.String() != msg {
+					t.Errorf("optimized %t: the binary exits with status %d and %q, the interpreter fails at %s with %q", optimize, status, stderr.String(), runtimeErr.Pos, runtimeErr.Msg)
+				}
+			}
+		case wantErr != nil:
+			t.Fatal(wantErr)
+		case status != 0:
+			t.Errorf("optimized %t: the binary exits with status %d and %q", optimize, status, stderr.String())
+		}
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "tests", "*.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if filepath.Base(file) == "while-do.alpha" {
+				t.Skip("loops forever")
+			}
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(file)
+			if err != nil {
+				t.Skip(err)
+			}
+			compare(t, program, "")
+		})
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	tests := []string{
+		`println(1.0 / 0.0, 0.0 - 1.0 / 0.0, 0.0 / 0.0, 1.5, 100000000.0, 0.000001)`,
+		`begin println(1, 2); println(3, 1 / 0) end`,
+		`let var x : Integer in begin x = 9223372036854775807; println(x + 1, x * 2, 0 - x - 2) end`,
+		`let var x : Integer in begin x = 0 - 9223372036854775807 - 1; println(x / (0 - 1), x / 2) end`,
+		`let var s : String in begin if 1 < 2 then s = "a" else begin end; println(s, s == "a") end`,
+		`let var s : String in println(length(s))`,
+		`println(substr("hola", 1, 9223372036854775807))`,
+		`begin println(concat("ho", "la"), length("ñandú"), toInt(2.7), toFloat(3), abs(0.0 - 2.5)); exit(3) end`,
+		`println(true == false, "a" == "a", sqrt(2.0), pow(2.0, 0.5))`,
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, program, "")
+		})
+	}
+}
+
+// TestFailures checks the messages the builtins fail with against the ones
+// of the interpreter
+func TestFailures(t *testing.T) {
+	tests := []struct {
+		src   string
+		input string
+	}{
+		{src: `println(readInt())`, input: "12 x\n"},
+		{src: `println(readInt())`, input: "a\"b\\\t\x01\x7f\u00e9 x\r\n"},
+		{src: `println(substr("hola", 3, 2))`},
+		{src: `println(substr("hola", 0 - 1, 2))`},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, program, test.input)
+		})
+	}
+}
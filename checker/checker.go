@@ -0,0 +1,316 @@
+package checker
+
+import (
+	"errors"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// Info holds everything the checker learned about a program
+type Info struct {
+	// Types maps expressions to their type
+	Types map[ast.Expression]types.Type
+	// Uses maps identifiers and type denoters to the symbol they refer to
+	Uses map[ast.Node]*types.Symbol
+	// Defs maps single declarations to the symbol they declare
+	Defs map[ast.Declaration]*types.Symbol
+}
+
+func NewInfo() *Info {
+	return &Info{
+		Types: map[ast.Expression]types.Type{},
+		Uses:  map[ast.Node]*types.Symbol{},
+		Defs:  map[ast.Declaration]*types.Symbol{},
+	}
+}
+
+// The Checker structure resolves the names used in a program and verifies
+// that every construct is used with the right types
+type Checker struct {
+	info   *Info
+	scope  *types.Scope
+	errors []error
+}
+
+// NewChecker returns a checker that records its findings in info and
+// resolves free names in scope.
+func NewChecker(info *Info, scope *types.Scope) *Checker {
+	return &Checker{
+		info:  info,
+		scope: scope,
+	}
+}
+
+// Check checks a whole program against the given scope
+func Check(root *ast.Program, scope *types.Scope) (*Info, error) {
+	info := NewInfo()
+	err := NewChecker(info, scope).Program(root)
+	return info, err
+}
+
+func (c *Checker) errorf(pos ast.Position, format string, args ...any) *diag.Diagnostic {
+	d := diag.Errorf(pos, format, args...)
+	c.errors = append(c.errors, d)
+	return d
+}
+
+func (c *Checker) openScope() {
+	c.scope = types.NewScope(c.scope)
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.Parent()
+}
+
+// Program checks a program or a module, module declarations are added to
+// the checker's scope so the caller can collect the exported ones. Imports
+// are expected to be resolved by the caller as well.
+//
+//	program ::= (import String)* (singleCommand | module)
+func (c *Checker) Program(node *ast.Program) error {
+	if node.Module != nil {
+		c.Declaration(node.Module.Decls)
+	} else {
+		c.SingleCommand(node.Body)
+	}
+	return errors.Join(c.errors...)
+}
+
+// SingleCommand checks the single command construct
+func (c *Checker) SingleCommand(node ast.Command) {
+	switch node := node.(type) {
+	case *ast.SkipCommand:
+		return
+	case *ast.BlockCommand:
+		{
+			for _, command := range node.Commands {
+				c.SingleCommand(command)
+			}
+		}
+	case *ast.IfCommand:
+		{
+			c.condition(node.Cond)
+			c.SingleCommand(node.Then)
+			c.SingleCommand(node.Else)
+		}
+	case *ast.WhileCommand:
+		{
+			c.condition(node.Cond)
+			c.SingleCommand(node.Body)
+		}
+	case *ast.LetCommand:
+		{
+			c.openScope()
+			c.Declaration(node.Decls)
+			c.SingleCommand(node.Body)
+			c.closeScope()
+		}
+	case *ast.AssignCommand:
+		c.assignment(node.Name, node.Value)
+	case *ast.CallCommand:
+		c.call(node.Name, node.Args)
+	}
+}
+
+func (c *Checker) condition(node ast.Expression) {
+	t := c.Expression(node)
+	if t != types.Invalid && t != types.Boolean {
+		c.errorf(node.Position(), "condition must be of type %s, got %s", types.Boolean, t)
+	}
+}
+
+func (c *Checker) assignment(name *ast.Ident, value ast.Expression) {
+	t := c.Expression(value)
+	sym := c.resolve(name, name.Name)
+	if sym == nil {
+		return
+	}
+	c.info.Uses[name] = sym
+	if sym.Kind != types.Var {
+		c.errorf(name.Pos, "cannot assign to %s %s", types.SymbolKindNames[sym.Kind], sym.Name)
+		return
+	}
+	if t != types.Invalid && !t.AssignableTo(sym.Type) {
+		c.errorf(value.Position(), "cannot assign a value of type %s to %s of type %s", t, sym.Name, sym.Type)
+	}
+}
+
+// call checks a call to a function, returning its result type
+func (c *Checker) call(name *ast.Ident, args []ast.Expression) types.Type {
+	argTypes := make([]types.Type, len(args))
+	for i, arg := range args {
+		argTypes[i] = c.Expression(arg)
+	}
+
+	sym := c.resolve(name, name.Name)
+	if sym == nil {
+		return types.Invalid
+	}
+	c.info.Uses[name] = sym
+	if sym.Kind != types.Func {
+		c.errorf(name.Pos, "cannot call %s %s", types.SymbolKindNames[sym.Kind], sym.Name)
+		return types.Invalid
+	}
+
+	sig := sym.Sig
+	// the last parameter of a variadic function may be left out
+	if sig.Variadic {
+		if len(args) < len(sig.Params)-1 {
+			c.errorf(name.Pos, "wrong number of arguments in call to %s, expected at least %d got %d", sym.Name, len(sig.Params)-1, len(args))
+			return sig.Result
+		}
+	} else if len(args) != len(sig.Params) {
+		c.errorf(name.Pos, "wrong number of arguments in call to %s, expected %d got %d", sym.Name, len(sig.Params), len(args))
+		return sig.Result
+	}
+	for i, t := range argTypes {
+		param := sig.Params[min(i, len(sig.Params)-1)]
+		if t != types.Invalid && !t.AssignableTo(param) {
+			c.errorf(args[i].Position(), "cannot use a value of type %s as argument %d of %s, expected %s", t, i+1, sym.Name, param)
+		}
+	}
+	return sig.Result
+}
+
+// resolve looks up the name used by node
+func (c *Checker) resolve(node ast.Node, name string) *types.Symbol {
+	sym := c.scope.Lookup(name)
+	if sym == nil {
+		d := c.errorf(node.Position(), "undefined: %s", name)
+		d.Primary.Len = len(name)
+		if closest := diag.Closest(name, c.scope.Names()); closest != "" {
+			d.WithSuggestion(d.Primary, closest, "did you mean %s?", closest)
+		}
+	}
+	return sym
+}
+
+// Declaration checks every single declaration and adds it to the current
+// scope
+func (c *Checker) Declaration(decls []ast.Declaration) {
+	for _, decl := range decls {
+		export, exported := decl.(*ast.ExportDecl)
+		if exported {
+			decl = export.Decl
+		}
+		c.SingleDeclaration(decl)
+		if sym := c.info.Defs[decl]; sym != nil {
+			sym.Exported = exported
+		}
+	}
+}
+
+// SingleDeclaration checks the single declaration construct
+func (c *Checker) SingleDeclaration(node ast.Declaration) {
+	var sym *types.Symbol
+	switch node := node.(type) {
+	case *ast.ConstDecl:
+		sym = &types.Symbol{Name: node.Name.Name, Pos: node.Name.Pos, Kind: types.Const, Type: c.Expression(node.Value)}
+	case *ast.VarDecl:
+		sym = &types.Symbol{Name: node.Name.Name, Pos: node.Name.Pos, Kind: types.Var, Type: c.TypeDenoter(node.Type)}
+	default:
+		return
+	}
+
+	if prev := c.scope.Insert(sym); prev != nil {
+		c.errorf(sym.Pos, "%s redeclared in this block, previous declaration at %s", sym.Name, prev.Pos).
+			WithSecondary(prev.Pos, "previous declaration")
+		return
+	}
+	c.info.Defs[node] = sym
+}
+
+// TypeDenoter resolves the type named by the type denoter construct
+func (c *Checker) TypeDenoter(node *ast.TypeDenoter) types.Type {
+	sym := c.resolve(node, node.Name)
+	if sym == nil {
+		return types.Invalid
+	}
+	c.info.Uses[node] = sym
+	if sym.Kind != types.TypeName {
+		c.errorf(node.Pos, "%s is not a type", sym.Name)
+		return types.Invalid
+	}
+	return sym.Type
+}
+
+// Expression checks the expression constructs, operators are applied from
+// left to right without any precedence.
+func (c *Checker) Expression(node ast.Expression) types.Type {
+	t := types.Invalid
+	switch node := node.(type) {
+	case *ast.IntegerLit:
+		t = types.Integer
+	case *ast.FloatLit:
+		t = types.Float
+	case *ast.StringLit:
+		t = types.String
+	case *ast.ParenExpr:
+		t = c.Expression(node.X)
+	case *ast.BinaryExpr:
+		{
+			lhs := c.Expression(node.X)
+			rhs := c.Expression(node.Y)
+			t = c.binary(node, lhs, rhs)
+		}
+	case *ast.CallExpr:
+		{
+			t = c.call(node.Name, node.Args)
+			if t == types.Void {
+				c.errorf(node.Pos, "%s doesn't return a value", node.Name.Name)
+				t = types.Invalid
+			}
+		}
+	case *ast.Ident:
+		{
+			sym := c.resolve(node, node.Name)
+			if sym == nil {
+				break
+			}
+			c.info.Uses[node] = sym
+			if sym.Kind != types.Const && sym.Kind != types.Var {
+				c.errorf(node.Pos, "%s %s is not a value", types.SymbolKindNames[sym.Kind], sym.Name)
+				break
+			}
+			t = sym.Type
+		}
+	}
+	c.info.Types[node] = t
+	return t
+}
+
+func (c *Checker) binary(node *ast.BinaryExpr, lhs, rhs types.Type) types.Type {
+	if lhs == types.Invalid || rhs == types.Invalid {
+		return types.Invalid
+	}
+
+	switch node.Op {
+	case ast.Add, ast.Sub, ast.Mul, ast.Div:
+		{
+			if !lhs.IsNumeric() || !rhs.IsNumeric() {
+				break
+			}
+			if lhs == types.Float || rhs == types.Float {
+				return types.Float
+			}
+			return types.Integer
+		}
+	case ast.Less, ast.Greater, ast.LessEqual, ast.GreaterEqual:
+		{
+			if lhs.IsNumeric() && rhs.IsNumeric() {
+				return types.Boolean
+			}
+		}
+	case ast.Equals, ast.Comparison:
+		{
+			if lhs == rhs || (lhs.IsNumeric() && rhs.IsNumeric()) {
+				return types.Boolean
+			}
+		}
+	}
+
+	c.errorf(node.OpPos, "invalid operation: operator %s not defined on %s and %s", node.Op, lhs, rhs)
+	return types.Invalid
+}
@@ -0,0 +1,50 @@
+package checker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+func check(t *testing.T, src string) error {
+	t.Helper()
+	lib := stdlib.Prelude()
+	if err := lib.RegisterFunc("sum", func(first int, rest ...int) int { return first }); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loader.NewLoader(nil, lib.Scope(types.Universe())).LoadSource("test.alpha", src)
+	return err
+}
+
+func TestCallArity(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{src: "println()"},
+		{src: "print()"},
+		{src: "println(1, 2.5, \"three\")"},
+		{src: "sum(1)"},
+		{src: "sum(1, 2, 3)"},
+		{src: "sum()", err: "wrong number of arguments in call to sum, expected at least 1 got 0"},
+		{src: "exit()", err: "wrong number of arguments in call to exit, expected 1 got 0"},
+		{src: "exit(1, 2)", err: "wrong number of arguments in call to exit, expected 1 got 2"},
+		{src: "x = concat(\"a\")", err: "wrong number of arguments in call to concat, expected 2 got 1"},
+	}
+	for _, test := range tests {
+		src := test.src
+		if strings.HasPrefix(src, "x =") {
+			src = "let var x : String in " + src
+		}
+		err := check(t, src)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: %v", test.src, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%q: got %v, want %q", test.src, err, test.err)
+		}
+	}
+}
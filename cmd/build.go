@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zSnails/alpha/gogen"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// build translates a program to Go and compiles it into a native binary
+func build(flags *flag.FlagSet, args []string) error {
+	searchPath := includeFlag(flags)
+	out := flags.String("o", "", "output file, defaults to the program name without its extension")
+	source := flags.Bool("go", false, "write the generated Go source instead of building a binary")
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+
+	program, err := loader.NewLoader(searchPath(), stdlib.Prelude().Scope(types.Universe())).Load(name)
+	if err != nil {
+		return err
+	}
+
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		return err
+	}
+
+	src, err := gogen.Generate(fn)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		if *source {
+			*out += ".go"
+		}
+	}
+
+	if *source {
+		return os.WriteFile(*out, src, 0o644)
+	}
+	return gogen.Build(src, *out)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/cgen"
+	"github.com/zSnails/alpha/gogen"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/llgen"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+	"github.com/zSnails/alpha/watgen"
+)
+
+// target translates the intermediate representation of a program into the
+// source of another language, files the generated code needs are written
+// next to out. Optimized programs are taken out of SSA form for the targets
+// that don't read it.
+type target struct {
+	extension string
+	generate  func(fn *ir.Func, out string) ([]byte, error)
+	ssa       bool
+}
+
+var targets = map[string]target{
+	"go": {
+		extension: ".go",
+		generate: func(fn *ir.Func, out string) ([]byte, error) {
+			return gogen.Generate(fn)
+		},
+	},
+	"c": {
+		extension: ".c",
+		generate: func(fn *ir.Func, out string) ([]byte, error) {
+			header := filepath.Join(filepath.Dir(out), cgen.RuntimeHeader)
+			if err := os.WriteFile(header, cgen.Runtime, 0o644); err != nil {
+				return nil, err
+			}
+			return cgen.Generate(fn)
+		},
+	},
+	"llvm": {
+		extension: ".ll",
+		generate: func(fn *ir.Func, out string) ([]byte, error) {
+			return llgen.Generate(fn)
+		},
+		ssa: true,
+	},
+	"wat": {
+		extension: ".wat",
+		generate: func(fn *ir.Func, out string) ([]byte, error) {
+			return watgen.Generate(fn)
+		},
+	},
+}
+
+func targetNames() string {
+	names := []string{}
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// compile translates a program into the source of one of the targets
+func compile(flags *flag.FlagSet, args []string) error {
+	searchPath := includeFlag(flags)
+	out := flags.String("o", "", "output file, defaults to the program name with the extension of the target")
+	targetName := flags.String("target", "c", "language to translate the program to, one of "+targetNames())
+	optimize := flags.Bool("O", false, "optimize the program")
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+
+	target, ok := targets[*targetName]
+	if !ok {
+		return fmt.Errorf("error: unknown target %q, expected one of %s", *targetName, targetNames())
+	}
+
+	program, err := loader.NewLoader(searchPath(), stdlib.Prelude().Scope(types.Universe())).Load(name)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)) + target.extension
+	}
+
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		return err
+	}
+	if *optimize {
+		fn.Optimize(nil)
+		if !target.ssa {
+			fn.FromSSA()
+		}
+	}
+
+	src, err := target.generate(fn, *out)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
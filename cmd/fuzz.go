@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zSnails/alpha/fuzz"
+)
+
+// fuzzParser checks the tokenizer, the parser and the compilation over
+// generated programs, the seeds of the fuzz package and the files given, and
+// over random mutations of each. The failing sources are printed and the
+// seed to generate them again as well.
+func fuzzParser(flags *flag.FlagSet, args []string) error {
+	n := flags.Int("n", 1000, "number of programs to generate")
+	seed := flags.Int64("seed", 0, "seed of the generator, defaults to the current time")
+	depth := flags.Int("depth", 4, "how deep commands and expressions of generated programs nest")
+	mutations := flags.Int("mutations", 10, "number of mutations of each program")
+	show := flags.Bool("print", false, "print the generated programs instead of checking them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	gen := fuzz.NewGenerator(*seed)
+	gen.MaxDepth = *depth
+	if *show {
+		for i := 0; i < *n; i++ {
+			fmt.Printf("// program %d\n%s\n", i, gen.Program())
+		}
+		return nil
+	}
+
+	sources := append([]string{}, fuzz.Seeds...)
+	for _, name := range flags.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, string(data))
+	}
+	for i := 0; i < *n; i++ {
+		sources = append(sources, gen.Program())
+	}
+
+	failures := 0
+	fail := func(src string, err error) {
+		failures++
+		fmt.Printf("failure %d on %q\n%s\n\n", failures, src, err)
+	}
+	check := func(src string) {
+		if err := fuzz.Tokens(src); err != nil {
+			fail(src, err)
+		}
+		if err := fuzz.Parse(src); err != nil {
+			fail(src, err)
+		}
+		if err := fuzz.Compile(src); err != nil {
+			fail(src, err)
+		}
+	}
+	for _, src := range sources {
+		check(src)
+		for i := 0; i < *mutations; i++ {
+			edit := gen.Mutate(src)
+			edited, _ := edit.Apply(src)
+			check(edited)
+			if err := fuzz.Edit(src, edit); err != nil {
+				fail(src, fmt.Errorf("edit %+v: %w", edit, err))
+			}
+		}
+	}
+
+	fmt.Printf("%d sources, %d mutations each, seed %d\n", len(sources), *mutations, *seed)
+	if failures > 0 {
+		return fmt.Errorf("error: %d failures", failures)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// dumpIR prints the intermediate representation of a program
+func dumpIR(flags *flag.FlagSet, args []string) error {
+	searchPath := includeFlag(flags)
+	ssa := flags.Bool("ssa", false, "convert the program to SSA form")
+	optimize := flags.Bool("O", false, "optimize the program, implies -ssa")
+	trace := flags.Bool("trace", false, "print the program before optimizing and after every pass changing it, implies -O")
+	format := flags.String("format", "text", "format of the program, text or dot for a Graphviz graph of its blocks")
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "dot" {
+		return fmt.Errorf("error: unknown format %q, expected text or dot", *format)
+	}
+
+	program, err := loader.NewLoader(searchPath(), stdlib.Prelude().Scope(types.Universe())).Load(name)
+	if err != nil {
+		return err
+	}
+
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *trace:
+		fn.Optimize(os.Stdout)
+		return nil
+	case *optimize:
+		fn.Optimize(nil)
+	case *ssa:
+		fn.ToSSA()
+	}
+	if *format == "dot" {
+		fmt.Print(fn.Dot())
+		return nil
+	}
+	fmt.Print(fn)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/lint"
+)
+
+// lintFile runs the lint rules over a file, the configuration is read from
+// the project file closest to it unless -config names one
+func lintFile(flags *flag.FlagSet, args []string) error {
+	configFile := flags.String("config", "", "project file to read the configuration from, defaults to the closest "+lint.ConfigFile)
+	list := flags.Bool("rules", false, "list the rules and their default severity")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-24s %-8s %s\n", rule.ID, rule.Severity, rule.Doc)
+		}
+		return nil
+	}
+
+	if flags.NArg() < 1 {
+		return errors.New("error: missing filename")
+	}
+	name := flags.Arg(0)
+
+	var err error
+	if *configFile == "" {
+		*configFile, err = lint.FindConfig(filepath.Dir(name))
+		if err != nil {
+			return err
+		}
+	}
+	var config *lint.Config
+	if *configFile != "" {
+		config, err = lint.LoadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	findings, err := lint.NewLinter(config).File(name)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	diags := make([]*diag.Diagnostic, len(findings))
+	for i, finding := range findings {
+		diags[i] = finding.Diagnostic()
+		if finding.Severity == lint.Error {
+			failed++
+		}
+	}
+	if err := printDiagnostics(os.Stdout, diags, args); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("problems with severity error: %d", failed)
+	}
+	return nil
+}
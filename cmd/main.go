@@ -1,38 +1,154 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
 
-	"github.com/zSnails/alpha/parser"
-	"github.com/zSnails/alpha/tokenizer"
+	"github.com/zSnails/alpha"
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
 )
 
+type command func(flags *flag.FlagSet, args []string) error
+
+var commands = map[string]command{
+	"build":   build,
+	"compile": compile,
+	"fuzz":    fuzzParser,
+	"ir":      dumpIR,
+	"lint":    lintFile,
+	"parse":   parse,
+	"run":     run,
+	"tokens":  tokens,
+	"vet":     vetProgram,
+}
+
 func main() {
+	name, args := "parse", os.Args[1:]
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&diagnosticFormat, "diagnostics", "text", "format of errors and warnings, text or json for a JSON object per line")
+	err := commands[name](flags, args)
 
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "error: missing filename")
+	var exit *stdlib.ExitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.Code)
+	}
+	if err != nil {
+		report(os.Stderr, err, args)
+		os.Exit(1)
+	}
+}
+
+// diagnosticFormat is set by the -diagnostics flag every command has
+var diagnosticFormat string
+
+// report prints the diagnostics of an error, errors without a position are
+// printed as they are
+func report(w *os.File, err error, args []string) {
+	diags := diag.FromError(err)
+	if diagnosticFormat == "json" {
+		diag.WriteJSON(w, diags)
 		return
 	}
 
-	tok, err := tokenizer.FromFile(os.Args[1])
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+	positioned := false
+	for _, d := range diags {
+		positioned = positioned || d.Primary.Pos != (ast.Position{})
+	}
+	if !positioned {
+		fmt.Fprintf(w, "%s\n", err)
 		return
 	}
+	printDiagnostics(w, diags, args)
+}
+
+// printDiagnostics renders diagnostics with the lines of source they point
+// at, colored when w is a terminal. Positions only name the base of their
+// file, so the source is looked up next to the files of the command line,
+// in the current directory and in the search path of the environment.
+func printDiagnostics(w *os.File, diags []*diag.Diagnostic, args []string) error {
+	if diagnosticFormat == "json" {
+		return diag.WriteJSON(w, diags)
+	}
 
-	parser, err := parser.NewParser(tok)
+	dirs := []string{}
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+			dirs = append(dirs, filepath.Dir(arg))
+		}
+	}
+	dirs = append(dirs, ".")
+	dirs = append(dirs, loader.SearchPathFromEnv()...)
+
+	renderer := diag.NewRenderer(func(file string) ([]byte, error) {
+		for _, dir := range dirs {
+			if src, err := os.ReadFile(filepath.Join(dir, file)); err == nil {
+				return src, nil
+			}
+		}
+		return nil, os.ErrNotExist
+	})
+	renderer.Color = diag.IsTerminal(w)
+	return renderer.RenderAll(w, diags)
+}
+
+// includeFlag defines the flag listing the directories imports are looked up
+// in, the returned function gives the whole search path once flags are parsed
+func includeFlag(flags *flag.FlagSet) func() []string {
+	includes := flags.String("I", "", "list of directories to search for imported modules, separated by '"+string(filepath.ListSeparator)+"'")
+	return func() []string {
+		return loader.SearchPath(*includes)
+	}
+}
+
+// filename parses the command line flags and returns the file to work on
+func filename(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	if flags.NArg() < 1 {
+		return "", errors.New("error: missing filename")
+	}
+	return flags.Arg(0), nil
+}
+
+// run executes a program
+func run(flags *flag.FlagSet, args []string) error {
+	engine := alpha.NewEngine()
+	searchPath := includeFlag(flags)
+	flags.IntVar(&engine.Limits.MaxSteps, "max-steps", 0, "maximum number of steps the program can take, 0 means no limit")
+	flags.IntVar(&engine.Limits.MaxCallDepth, "max-depth", 0, "maximum nesting of calls, commands and expressions, 0 means no limit")
+	flags.IntVar(&engine.Limits.MaxMemory, "max-memory", 0, "approximate number of bytes the program values can use, 0 means no limit")
+	timeout := flags.Duration("timeout", 0, "stop the program after this long, 0 means no timeout")
+	name, err := filename(flags, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
-		return
+		return err
 	}
 
-	node, err := parser.Program()
-	if err != nil && !errors.Is(err, io.EOF) {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
-		return
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	engine.SearchPath = searchPath()
+	program, err := engine.CompileFile(name)
+	if err != nil {
+		return err
 	}
-	fmt.Printf("%s\n", node)
+	return engine.Run(ctx, program, os.Stdin, os.Stdout)
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/parser"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// formats print the tree of a program
+var formats = map[string]func(root ast.Node) ([]byte, error){
+	"text": func(root ast.Node) ([]byte, error) {
+		return []byte(ast.Sprint(root) + "\n"), nil
+	},
+	"json": func(root ast.Node) ([]byte, error) {
+		data, err := ast.Marshal(root)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteString("\n")
+		return out.Bytes(), nil
+	},
+	"dot": func(root ast.Node) ([]byte, error) {
+		return []byte(ast.Dot(root)), nil
+	},
+	"source": func(root ast.Node) ([]byte, error) {
+		return []byte(strings.TrimSuffix(ast.Format(root), "\n") + "\n"), nil
+	},
+}
+
+func formatNames() string {
+	names := []string{}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parse prints the syntax tree of a file, imports included. The file is
+// only parsed so trees of modules and of programs that don't check can be
+// looked at as well.
+func parse(flags *flag.FlagSet, args []string) error {
+	formatName := flags.String("format", "text", "format of the tree, one of "+formatNames())
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+
+	format, ok := formats[*formatName]
+	if !ok {
+		return fmt.Errorf("error: unknown format %q, expected one of %s", *formatName, formatNames())
+	}
+
+	lexer, err := tokenizer.FromFile(name)
+	if err != nil {
+		return err
+	}
+	p, err := parser.NewParser(lexer)
+	if err != nil {
+		return err
+	}
+	program, err := p.Program()
+	if err != nil {
+		return err
+	}
+
+	out, err := format(program)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
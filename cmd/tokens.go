@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// The tokenRecord structure is a token as the tokens command prints it
+type tokenRecord struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Row   int    `json:"row"`
+	Col   int    `json:"col"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+func newTokenRecord(token *tokenizer.Token) *tokenRecord {
+	row, col := token.GetPosition()
+	start, end := token.GetSpan()
+	return &tokenRecord{
+		Type:  tokenizer.TokenNames[token.Type],
+		Value: token.Value,
+		Row:   row,
+		Col:   col,
+		Start: start,
+		End:   end,
+	}
+}
+
+// tokenWriter prints the token stream of a file, flush is called once the
+// last token has been written
+type tokenWriter struct {
+	write func(token *tokenizer.Token) error
+	flush func() error
+}
+
+var tokenFormats = map[string]func(w io.Writer) tokenWriter{
+	"text": func(w io.Writer) tokenWriter {
+		return tokenWriter{
+			write: func(token *tokenizer.Token) error {
+				start, end := token.GetSpan()
+				_, err := fmt.Fprintf(w, "%d-%d\t%s\n", start, end, token)
+				return err
+			},
+			flush: func() error { return nil },
+		}
+	},
+	"jsonl": func(w io.Writer) tokenWriter {
+		encoder := json.NewEncoder(w)
+		return tokenWriter{
+			write: func(token *tokenizer.Token) error {
+				return encoder.Encode(newTokenRecord(token))
+			},
+			flush: func() error { return nil },
+		}
+	},
+	"csv": func(w io.Writer) tokenWriter {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"type", "value", "row", "col", "start", "end"})
+		return tokenWriter{
+			write: func(token *tokenizer.Token) error {
+				record := newTokenRecord(token)
+				return writer.Write([]string{
+					record.Type,
+					record.Value,
+					strconv.Itoa(record.Row),
+					strconv.Itoa(record.Col),
+					strconv.Itoa(record.Start),
+					strconv.Itoa(record.End),
+				})
+			},
+			flush: func() error {
+				writer.Flush()
+				return writer.Error()
+			},
+		}
+	},
+}
+
+// tokens prints the token stream of a file, whitespace, comments and new
+// lines included. The lexing errors are reported once every token has been
+// printed.
+func tokens(flags *flag.FlagSet, args []string) error {
+	format := flags.String("format", "text", "format of the tokens, text, jsonl for JSON lines or csv")
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+
+	newWriter, ok := tokenFormats[*format]
+	if !ok {
+		return fmt.Errorf("error: unknown format %q, expected text, jsonl or csv", *format)
+	}
+
+	lexer, err := tokenizer.FromFile(name)
+	if err != nil {
+		return err
+	}
+	return writeTokens(newWriter(os.Stdout), lexer)
+}
+
+// writeTokens writes every token of lexer, trivia included, and then returns
+// the lexing errors
+func writeTokens(writer tokenWriter, lexer *tokenizer.Tokenizer) error {
+	lexer.KeepTrivia()
+	var errs tokenizer.ErrorList
+	for {
+		token, err := lexer.GetNextToken()
+		if token != nil {
+			if err := writer.write(token); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			errs.Add(err)
+		}
+	}
+	if err := writer.flush(); err != nil {
+		return err
+	}
+	return errs.Err()
+}
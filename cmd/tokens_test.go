@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestTokens compares the tokens of testdata/tokens.alpha in every format
+// with the golden files named after the format, go test -update rewrites
+// them
+func TestTokens(t *testing.T) {
+	for format, newWriter := range tokenFormats {
+		t.Run(format, func(t *testing.T) {
+			lexer, err := tokenizer.FromFile(filepath.Join("testdata", "tokens.alpha"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			err = writeTokens(newWriter(&got), lexer)
+			if want := "tokens.alpha:2:19: syntax error: unexpected token '@'"; err == nil || err.Error() != want {
+				t.Errorf("got error %v, want %s", err, want)
+			}
+
+			golden := filepath.Join("testdata", "tokens."+format)
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("the tokens differ from %s\n%s", golden, got.String())
+			}
+		})
+	}
+}
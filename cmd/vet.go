@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+	"github.com/zSnails/alpha/vet"
+)
+
+// vetProgram reports the reads of unassigned variables, unused declarations
+// and assignments that are never read
+func vetProgram(flags *flag.FlagSet, args []string) error {
+	searchPath := includeFlag(flags)
+	name, err := filename(flags, args)
+	if err != nil {
+		return err
+	}
+
+	program, err := loader.NewLoader(searchPath(), stdlib.Prelude().Scope(types.Universe())).Load(name)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := vet.Vet(program.Root, program.Info)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, len(warnings))
+	for i, warning := range warnings {
+		errs[i] = warning
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+type Severity int8
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+var SeverityNames = map[Severity]string{
+	Error:   "error",
+	Warning: "warning",
+	Info:    "info",
+}
+
+func (s Severity) String() string {
+	return SeverityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range SeverityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// The Span structure is a piece of a line of source, Len is its length in
+// bytes and a zero Len marks a single character
+type Span struct {
+	Pos   ast.Position `json:"pos"`
+	Len   int          `json:"len,omitempty"`
+	Label string       `json:"label,omitempty"`
+}
+
+// The Suggestion structure proposes a fix, Replacement is the text that
+// should take the place of Span
+type Suggestion struct {
+	Msg         string `json:"message"`
+	Span        Span   `json:"span"`
+	Replacement string `json:"replacement"`
+}
+
+// The Diagnostic structure describes a problem found in a program. Primary
+// points at the problem and Secondary at related code, like a previous
+// declaration. Code identifies the kind of problem when it has one.
+type Diagnostic struct {
+	Severity    Severity      `json:"severity"`
+	Code        string        `json:"code,omitempty"`
+	Msg         string        `json:"message"`
+	Primary     Span          `json:"primary"`
+	Secondary   []Span        `json:"secondary,omitempty"`
+	Notes       []string      `json:"notes,omitempty"`
+	Suggestions []*Suggestion `json:"suggestions,omitempty"`
+}
+
+// Errorf returns an error diagnostic pointing at pos
+func Errorf(pos ast.Position, format string, args ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: Error,
+		Msg:      fmt.Sprintf(format, args...),
+		Primary:  Span{Pos: pos},
+	}
+}
+
+// Error returns the diagnostic on a single line, without its spans, notes
+// and suggestions
+func (d *Diagnostic) Error() string {
+	var sb strings.Builder
+	if d.Primary.Pos != (ast.Position{}) {
+		sb.WriteString(d.Primary.Pos.String() + ": ")
+	}
+	if d.Severity != Error || d.Code != "" {
+		sb.WriteString(d.Severity.String())
+		if d.Code != "" {
+			sb.WriteString(" " + d.Code)
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Msg)
+	return sb.String()
+}
+
+// WithSecondary adds a span pointing at related code
+func (d *Diagnostic) WithSecondary(pos ast.Position, label string) *Diagnostic {
+	d.Secondary = append(d.Secondary, Span{Pos: pos, Label: label})
+	return d
+}
+
+// WithNote adds a note
+func (d *Diagnostic) WithNote(format string, args ...any) *Diagnostic {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, args...))
+	return d
+}
+
+// WithSuggestion proposes to replace a span with some text
+func (d *Diagnostic) WithSuggestion(span Span, replacement, format string, args ...any) *Diagnostic {
+	d.Suggestions = append(d.Suggestions, &Suggestion{
+		Msg:         fmt.Sprintf(format, args...),
+		Span:        span,
+		Replacement: replacement,
+	})
+	return d
+}
+
+// Diagnoser is implemented by errors that can describe themselves as a
+// diagnostic
+type Diagnoser interface {
+	Diagnostic() *Diagnostic
+}
+
+// FromError returns the diagnostics an error holds. Errors joined with
+// errors.Join give one diagnostic each, errors that are neither a
+// *Diagnostic nor a Diagnoser become an error diagnostic without a span.
+func FromError(err error) []*Diagnostic {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		out := []*Diagnostic{}
+		for _, err := range joined.Unwrap() {
+			out = append(out, FromError(err)...)
+		}
+		return out
+	}
+
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return []*Diagnostic{d}
+	}
+	var diagnoser Diagnoser
+	if errors.As(err, &diagnoser) {
+		return []*Diagnostic{diagnoser.Diagnostic()}
+	}
+	return []*Diagnostic{{Severity: Error, Msg: err.Error()}}
+}
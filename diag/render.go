@@ -0,0 +1,234 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escapes used when the renderer writes colors
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	red    = "\x1b[1;31m"
+	yellow = "\x1b[1;33m"
+	cyan   = "\x1b[1;36m"
+	blue   = "\x1b[1;34m"
+	green  = "\x1b[1;32m"
+)
+
+var severityColors = map[Severity]string{
+	Error:   red,
+	Warning: yellow,
+	Info:    cyan,
+}
+
+// The Renderer structure prints diagnostics for humans, with the lines of
+// source they point at and a caret under each span
+type Renderer struct {
+	// Color enables ANSI colors
+	Color bool
+	// Source returns the contents of a file as positions name it, spans
+	// are printed without their line of source when it fails or is nil
+	Source func(file string) ([]byte, error)
+	lines  map[string][]string
+}
+
+// NewRenderer returns a renderer reading the sources it shows with source,
+// which may be nil
+func NewRenderer(source func(file string) ([]byte, error)) *Renderer {
+	return &Renderer{
+		Source: source,
+		lines:  map[string][]string{},
+	}
+}
+
+// IsTerminal reports whether f is a terminal colors can be written to, NO_COLOR
+// disables them as usual
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) paint(color, s string) string {
+	if !r.Color || s == "" {
+		return s
+	}
+	return color + s + reset
+}
+
+// line returns a line of a file, ok is false when it can't be read
+func (r *Renderer) line(file string, row int) (string, bool) {
+	if r.Source == nil {
+		return "", false
+	}
+	lines, ok := r.lines[file]
+	if !ok {
+		src, err := r.Source(file)
+		if err == nil {
+			lines = strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+		}
+		r.lines[file] = lines
+	}
+	if row < 1 || row > len(lines) {
+		return "", false
+	}
+	return lines[row-1], true
+}
+
+// width returns how many columns text takes once printed, tabs are
+// expanded to four spaces
+func width(text string) int {
+	return utf8.RuneCountInString(text) + 3*strings.Count(text, "\t")
+}
+
+// underline returns the marker line of a span of line, drawn with mark
+func underline(line string, span Span, mark string) string {
+	col := span.Pos.Col - 1
+	col = max(0, min(col, len(line)))
+	end := col + max(span.Len, 1)
+	end = min(end, len(line))
+	n := max(width(line[col:end]), 1)
+	return strings.Repeat(" ", width(line[:col])) + strings.Repeat(mark, n)
+}
+
+// Render prints a diagnostic
+//
+//	error[code]: message
+//	 --> file:row:col
+//	  |
+//	3 | the line of source
+//	  |     ^^^ label
+//	  = note: note
+//	  = help: suggestion
+func (r *Renderer) Render(w io.Writer, d *Diagnostic) error {
+	var sb strings.Builder
+	color := severityColors[d.Severity]
+	title := d.Severity.String()
+	if d.Code != "" {
+		title += "[" + d.Code + "]"
+	}
+	sb.WriteString(r.paint(color, title) + r.paint(bold, ": "+d.Msg) + "\n")
+
+	type marked struct {
+		span    Span
+		primary bool
+	}
+	spans := []marked{{span: d.Primary, primary: true}}
+	for _, span := range d.Secondary {
+		spans = append(spans, marked{span: span})
+	}
+
+	// the gutter is as wide as the largest row shown
+	gutter := 1
+	for _, m := range spans {
+		gutter = max(gutter, len(strconv.Itoa(m.span.Pos.Row)))
+	}
+	for _, s := range d.Suggestions {
+		gutter = max(gutter, len(strconv.Itoa(s.Span.Pos.Row)))
+	}
+	pad := strings.Repeat(" ", gutter)
+	bar := r.paint(blue, "|")
+
+	if d.Primary.Pos.Row == 0 {
+		spans = spans[1:]
+	} else {
+		fmt.Fprintf(&sb, "%s%s %s\n", pad, r.paint(blue, "-->"), d.Primary.Pos)
+	}
+
+	// spans are printed in the order of their rows, grouped by file with
+	// the file of the primary span first
+	sort.SliceStable(spans, func(i, j int) bool {
+		a, b := spans[i].span.Pos, spans[j].span.Pos
+		if a.File != b.File {
+			return a.File == d.Primary.Pos.File
+		}
+		return a.Row < b.Row
+	})
+	file := d.Primary.Pos.File
+	for i, m := range spans {
+		pos := m.span.Pos
+		if pos.File != file || (i == 0 && d.Primary.Pos.Row == 0) {
+			file = pos.File
+			fmt.Fprintf(&sb, "%s%s %s\n", pad, r.paint(blue, ":::"), pos)
+		}
+		mark, markColor := "-", blue
+		if m.primary {
+			mark, markColor = "^", color
+		}
+
+		line, ok := r.line(pos.File, pos.Row)
+		if !ok {
+			label := pos.String()
+			if m.span.Label != "" {
+				label += ": " + m.span.Label
+			}
+			fmt.Fprintf(&sb, "%s %s %s\n", pad, bar, label)
+			continue
+		}
+		if i == 0 || spans[i-1].span.Pos.Row != pos.Row || spans[i-1].span.Pos.File != pos.File {
+			fmt.Fprintf(&sb, "%s %s\n", pad, bar)
+			fmt.Fprintf(&sb, "%s %s %s\n", r.paint(blue, fmt.Sprintf("%*d", gutter, pos.Row)), bar, strings.ReplaceAll(line, "\t", "    "))
+		}
+		marker := r.paint(markColor, underline(line, m.span, mark))
+		if m.span.Label != "" {
+			marker += " " + r.paint(markColor, m.span.Label)
+		}
+		fmt.Fprintf(&sb, "%s %s %s\n", pad, bar, marker)
+	}
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(&sb, "%s %s %s %s\n", pad, r.paint(blue, "="), r.paint(bold, "note:"), note)
+	}
+	for _, s := range d.Suggestions {
+		fmt.Fprintf(&sb, "%s %s %s %s\n", pad, r.paint(blue, "="), r.paint(bold, "help:"), s.Msg)
+		line, ok := r.line(s.Span.Pos.File, s.Span.Pos.Row)
+		if !ok {
+			continue
+		}
+		col := max(0, min(s.Span.Pos.Col-1, len(line)))
+		end := min(col+s.Span.Len, len(line))
+		fixed := line[:col] + s.Replacement + line[end:]
+		fmt.Fprintf(&sb, "%s %s\n", pad, bar)
+		fmt.Fprintf(&sb, "%s %s %s\n", r.paint(blue, fmt.Sprintf("%*d", gutter, s.Span.Pos.Row)), bar, strings.ReplaceAll(fixed, "\t", "    "))
+		marker := underline(fixed, Span{Pos: s.Span.Pos, Len: len(s.Replacement)}, "+")
+		fmt.Fprintf(&sb, "%s %s %s\n", pad, bar, r.paint(green, marker))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// RenderAll prints diagnostics separated by blank lines
+func (r *Renderer) RenderAll(w io.Writer, diags []*Diagnostic) error {
+	for i, d := range diags {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := r.Render(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON prints diagnostics for tools, one JSON object per line
+func WriteJSON(w io.Writer, diags []*Diagnostic) error {
+	encoder := json.NewEncoder(w)
+	for _, d := range diags {
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
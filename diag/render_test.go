@@ -0,0 +1,140 @@
+package diag_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+var sources = map[string]string{
+	"main.alpha": "let var count : Integer in begin\n\tcount = 1;\n\tprintn(count, \"año\")\nend\n",
+	"lib.alpha":  "export var count : Integer\n",
+}
+
+// source reads the files of sources, the rest don't exist
+func source(file string) ([]byte, error) {
+	src, ok := sources[file]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(src), nil
+}
+
+func pos(file string, row, col int) ast.Position {
+	return ast.Position{File: file, Row: row, Col: col}
+}
+
+// diagnostics covers what the renderer draws: codes, spans with labels on
+// the same line and in other files, tabs and wide characters, notes,
+// suggestions, files it can't read and diagnostics without a position
+var diagnostics = []*diag.Diagnostic{
+	diag.Errorf(pos("main.alpha", 3, 2), "undefined: printn").
+		WithSuggestion(diag.Span{Pos: pos("main.alpha", 3, 2), Len: 6}, "println", "did you mean println?"),
+	{
+		Severity:  diag.Warning,
+		Code:      "W002",
+		Msg:       "variable count is declared but never read",
+		Primary:   diag.Span{Pos: pos("main.alpha", 1, 9), Len: 5, Label: "declared here"},
+		Secondary: []diag.Span{{Pos: pos("main.alpha", 1, 17), Len: 7, Label: "of this type"}, {Pos: pos("lib.alpha", 1, 12), Label: "shadows this one"}},
+		Notes:     []string{"remove it or read it"},
+	},
+	diag.Errorf(pos("main.alpha", 3, 16), "unexpected string").
+		WithSecondary(pos("main.alpha", 3, 9), "in this call"),
+	diag.Errorf(pos("missing.alpha", 12, 3), "can't read the source").
+		WithSecondary(pos("main.alpha", 2, 2), "imported here"),
+	{Severity: diag.Info, Msg: "no position", Notes: []string{"like errors of the command line"}},
+}
+
+// golden compares got with a file of testdata, go test -update rewrites it
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(file, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("the output differs from %s\n%s", file, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		t.Run(fmt.Sprint("color ", color), func(t *testing.T) {
+			r := diag.NewRenderer(source)
+			r.Color = color
+			var got bytes.Buffer
+			if err := r.RenderAll(&got, diagnostics); err != nil {
+				t.Fatal(err)
+			}
+			name := "render.golden"
+			if color {
+				name = "render-color.golden"
+			}
+			golden(t, name, got.Bytes())
+		})
+	}
+}
+
+// TestRenderWithoutSource renders spans as positions when no source is
+// given
+func TestRenderWithoutSource(t *testing.T) {
+	var got bytes.Buffer
+	if err := diag.NewRenderer(nil).Render(&got, diagnostics[1]); err != nil {
+		t.Fatal(err)
+	}
+	want := `warning[W002]: variable count is declared but never read
+ --> main.alpha:1:9
+  | main.alpha:1:9: declared here
+  | main.alpha:1:17: of this type
+ ::: lib.alpha:1:12
+  | lib.alpha:1:12: shadows this one
+  = note: remove it or read it
+`
+	if got.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want)
+	}
+}
+
+// TestWriteJSON compares the JSON lines of the diagnostics with their golden
+// file and decodes them back
+func TestWriteJSON(t *testing.T) {
+	var got bytes.Buffer
+	if err := diag.WriteJSON(&got, diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "diagnostics.jsonl", got.Bytes())
+
+	decoder := json.NewDecoder(&got)
+	for i, want := range diagnostics {
+		var d diag.Diagnostic
+		if err := decoder.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&d, want) {
+			t.Errorf("diagnostic %d decodes to %+v, want %+v", i, &d, want)
+		}
+	}
+	var d diag.Diagnostic
+	if err := decoder.Decode(&d); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v after the last diagnostic, want io.EOF", err)
+	}
+}
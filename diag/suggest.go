@@ -0,0 +1,48 @@
+package diag
+
+// distance returns the number of single character insertions, deletions,
+// substitutions and swaps of adjacent characters turning a into b
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// Closest returns the candidate closest to a misspelled word, or an empty
+// string when none is close enough to be what was meant. Short words allow
+// a single typo and longer ones two.
+func Closest(word string, candidates []string) string {
+	limit := 1
+	if len([]rune(word)) > 4 {
+		limit = 2
+	}
+	best, bestDistance := "", limit+1
+	for _, candidate := range candidates {
+		if candidate == word {
+			continue
+		}
+		if d := distance(word, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
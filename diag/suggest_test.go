@@ -0,0 +1,40 @@
+package diag_test
+
+import (
+	"testing"
+
+	"github.com/zSnails/alpha/diag"
+)
+
+func TestClosest(t *testing.T) {
+	tests := []struct {
+		word       string
+		candidates []string
+		want       string
+	}{
+		{word: "whle", candidates: []string{"while", "begin"}, want: "while"},
+		{word: "fi", candidates: []string{"if", "in"}, want: "if"},
+		{word: "lenght", candidates: []string{"length"}, want: "length"},
+		{word: "añoo", candidates: []string{"año"}, want: "año"},
+		// ties go to the first candidate
+		{word: "tan", candidates: []string{"ten", "tin"}, want: "ten"},
+		{word: "tan", candidates: []string{"tin", "ten"}, want: "tin"},
+		{word: "tan", candidates: []string{"tn", "tin"}, want: "tn"},
+		// short words allow a single typo, longer ones two
+		{word: "wle", candidates: []string{"while"}},
+		{word: "rdIt", candidates: []string{"readInt"}},
+		{word: "rdInt", candidates: []string{"readInt"}, want: "readInt"},
+		{word: "rdIntt", candidates: []string{"readInt"}},
+		{word: "substring", candidates: []string{"substr"}},
+		// the word itself is never suggested
+		{word: "print", candidates: []string{"print"}},
+		{word: "print", candidates: []string{"print", "println"}, want: "println"},
+		{word: "x", candidates: []string{}},
+		{word: "x"},
+	}
+	for _, test := range tests {
+		if got := diag.Closest(test.word, test.candidates); got != test.want {
+			t.Errorf("Closest(%q, %q) = %q, want %q", test.word, test.candidates, got, test.want)
+		}
+	}
+}
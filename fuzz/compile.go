@@ -0,0 +1,225 @@
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zSnails/alpha/cgen"
+	"github.com/zSnails/alpha/gogen"
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/llgen"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+	"github.com/zSnails/alpha/watgen"
+	"github.com/zSnails/alpha/watgen/wat"
+)
+
+// limits bounds the runs of the interpreter, programs going past them are
+// only compiled. The function and the module of a program get more room
+// since they take several instructions for a step of the interpreter.
+var limits = interpreter.Limits{
+	MaxSteps:     10_000,
+	MaxCallDepth: 1_000,
+	MaxMemory:    1 << 20,
+}
+
+// The outcome structure is what a run prints and how it ends, the status
+// is only set when the program calls exit
+type outcome struct {
+	out    string
+	err    string
+	exited bool
+	status int
+}
+
+// The target structure is a target of alpha compile generated from the
+// function as it is, optimized tells whether it takes the optimized one and
+// ssa whether it takes it in SSA form
+type target struct {
+	name      string
+	generate  func(fn *ir.Func) ([]byte, error)
+	optimized bool
+	ssa       bool
+}
+
+var targets = []target{
+	{name: "c", generate: cgen.Generate},
+	{name: "go", generate: gogen.Generate},
+	{name: "llvm", generate: llgen.Generate},
+	{name: "c", generate: cgen.Generate, optimized: true},
+	{name: "go", generate: gogen.Generate, optimized: true},
+	{name: "llvm", generate: llgen.Generate, optimized: true, ssa: true},
+}
+
+// Compile loads and checks src, lowers it and generates every target from
+// the lowered and the optimized function. Programs the checker rejects are
+// left alone. The lowered, SSA, optimized and out of SSA functions must run
+// like the interpreter, printing the same output and stopping with the same
+// error, and so must the WebAssembly modules of the lowered and optimized
+// functions, which are run with the wat package.
+func Compile(src string) error {
+	return safely(func() error {
+		program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource(file, src)
+		if err != nil {
+			return nil
+		}
+		lower := func() (*ir.Func, error) {
+			fn, err := ir.Lower(program.Root, program.Info)
+			if err != nil {
+				return nil, fmt.Errorf("lowering: %w", err)
+			}
+			return fn, nil
+		}
+
+		fn, err := lower()
+		if err != nil {
+			return err
+		}
+		optimized, err := lower()
+		if err != nil {
+			return err
+		}
+		optimized.Optimize(nil)
+		plain, err := lower()
+		if err != nil {
+			return err
+		}
+		plain.Optimize(nil)
+		plain.FromSSA()
+		for _, target := range targets {
+			from := fn
+			switch {
+			case target.optimized && target.ssa:
+				from = optimized
+			case target.optimized:
+				from = plain
+			}
+			if _, err := target.generate(from); err != nil {
+				return fmt.Errorf("%s, optimized %t: %w\n%s", target.name, target.optimized, err, from)
+			}
+		}
+		// modules can't read input
+		modules := [][]byte{}
+		if !reads(fn) {
+			for _, from := range []*ir.Func{fn, plain} {
+				module, err := watgen.Generate(from)
+				if err != nil {
+					return fmt.Errorf("wat, optimized %t: %w\n%s", from == plain, err, from)
+				}
+				modules = append(modules, module)
+			}
+		}
+
+		want, ok := interpret(program)
+		if !ok {
+			return nil
+		}
+		stages := []struct {
+			name      string
+			transform func(fn *ir.Func)
+		}{
+			{name: "lowered", transform: func(fn *ir.Func) {}},
+			{name: "ssa", transform: func(fn *ir.Func) { fn.ToSSA() }},
+			{name: "optimized", transform: func(fn *ir.Func) { fn.Optimize(nil) }},
+			{name: "out of SSA", transform: func(fn *ir.Func) { fn.Optimize(nil); fn.FromSSA() }},
+		}
+		for _, stage := range stages {
+			fn, err := lower()
+			if err != nil {
+				return err
+			}
+			stage.transform(fn)
+			if got := evaluate(fn); got != want {
+				return fmt.Errorf("the %s function prints %q and stops with %q, the interpreter prints %q and stops with %q\n%s", stage.name, got.out, got.err, want.out, want.err, fn)
+			}
+		}
+
+		for _, module := range modules {
+			var out bytes.Buffer
+			err = run(module, &out)
+			var exit *watgen.ExitError
+			switch {
+			case err != nil && !errors.As(err, new(*wat.Trap)) && !errors.As(err, &exit):
+				return fmt.Errorf("the module doesn't run: %w\n%s", err, module)
+			case out.String() != want.out:
+				return fmt.Errorf("the module prints %q, the interpreter %q\n%s", out.String(), want.out, module)
+			case (err == nil) != (want.err == ""):
+				return fmt.Errorf("the module stops with %v, the interpreter with %q\n%s", err, want.err, module)
+			case (exit != nil) != want.exited || exit != nil && exit.Code != int32(want.status):
+				return fmt.Errorf("the module stops with %v, the interpreter with %q", err, want.err)
+			}
+		}
+		return nil
+	})
+}
+
+// reads reports whether fn reads input, WebAssembly modules can't
+func reads(fn *ir.Func) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Op == ir.Call && (instr.Callee.Name == "readLine" || instr.Callee.Name == "readInt") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interpret runs a program with the interpreter on an empty input, it
+// reports false when the program goes past the limits
+func interpret(program *loader.Program) (outcome, bool) {
+	var out bytes.Buffer
+	in := interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(""), &out)
+	in.SetLimits(limits)
+	err := in.Run(context.Background(), program.Root)
+	var limitErr *interpreter.LimitError
+	var runtimeErr *interpreter.RuntimeError
+	var exit *stdlib.ExitError
+	switch {
+	case errors.As(err, &limitErr):
+		return outcome{}, false
+	case errors.As(err, &exit):
+		return outcome{out: out.String(), err: err.Error(), exited: true, status: exit.Code}, true
+	case errors.As(err, &runtimeErr):
+		return outcome{out: out.String(), err: (&ir.RuntimeError{Pos: runtimeErr.Pos, Msg: runtimeErr.Msg}).Error()}, true
+	case err != nil:
+		return outcome{out: out.String(), err: err.Error()}, true
+	}
+	return outcome{out: out.String()}, true
+}
+
+// evaluate runs a function with the machine on an empty input
+func evaluate(fn *ir.Func) outcome {
+	var out bytes.Buffer
+	m := ir.NewMachine(stdlib.Prelude(), stdlib.NewIO(strings.NewReader(""), &out))
+	m.MaxSteps = 100 * limits.MaxSteps
+	err := m.Run(fn)
+	var exit *stdlib.ExitError
+	switch {
+	case errors.As(err, &exit):
+		return outcome{out: out.String(), err: err.Error(), exited: true, status: exit.Code}
+	case err != nil:
+		return outcome{out: out.String(), err: err.Error()}
+	}
+	return outcome{out: out.String()}
+}
+
+// run runs the main function of a module printing to out
+func run(src []byte, out *bytes.Buffer) error {
+	module, err := wat.Parse(string(src))
+	if err != nil {
+		return err
+	}
+	instance, err := module.Instantiate(watgen.Host(out))
+	if err != nil {
+		return err
+	}
+	instance.MaxSteps = 1_000 * limits.MaxSteps
+	_, err = instance.Call("main")
+	return err
+}
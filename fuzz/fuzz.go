@@ -0,0 +1,216 @@
+// Package fuzz checks the compiler against random input, from the tokenizer
+// and the parser to the checker, the lowering and the backends. The checks
+// take any source and return an error when an invariant doesn't hold, a
+// panic included, so they fit as the body of a native Go fuzz target:
+//
+//	f.Fuzz(func(t *testing.T, src string) {
+//		if err := fuzz.Compile(src); err != nil {
+//			t.Fatal(err)
+//		}
+//	})
+//
+// The Generator writes the programs to start from, the fuzz command of the
+// CLI runs the checks over them and over random mutations of them.
+package fuzz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/debug"
+	"unicode/utf8"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// file is the name the checked sources are given
+const file = "fuzz.alpha"
+
+// Seeds are sources hitting the corners of the parser and of the
+// compilation, meant to start a corpus with
+var Seeds = []string{
+	"",
+	"begin end",
+	"begin ; end",
+	// calls without arguments
+	"f()",
+	"print()",
+	"x = f()",
+	// declarations can't be empty
+	"let in x = 1",
+	"let ; var x : Integer in begin end",
+	"export ; const x ~ 1",
+	"if x <= 1 then else",
+	"x = y >= 1 == (2 < = 3)",
+	"x = 1.5 + 2 * (3 - y)",
+	"x = 'it\"s' == \"it's\"",
+	"import \"a.alpha\" import 'b.alpha' const a ~ 1",
+	"x = 99999999999999999999",
+	"x = \"open",
+	"while true do // comment",
+	// programs the checker accepts, they reach the lowering and the backends
+	"let var x : Integer in begin x = 9223372036854775807; println(x + 1, (x + 1) / (0 - 1), x / 0) end",
+	"let var x : Integer; var s : String in begin if x < 1 then s = 'a' else begin end; println(s, x) end",
+	"let var a : Integer in begin a = 1; let var a : Float in begin a = 2.5; println(a) end; println(a) end",
+	"let const n ~ 3; var i : Integer in while i < n do begin println(substr('hola', i, 2), 1.0 / 0.0); i = i + 1 end",
+	"begin print(readInt()); exit(3) end",
+}
+
+// safely runs check and turns a panic into an error
+func safely(check func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return check()
+}
+
+// Tokens lexes src with the trivia and checks the spans of the tokens: they
+// follow each other without gaps or overlaps, hold the value of their token
+// and end where the source does. The skipped characters of lexing errors are
+// the only gaps allowed, and the errors must point at them.
+func Tokens(src string) error {
+	return safely(func() error {
+		lexer := tokenizer.FromString(file, src)
+		lexer.KeepTrivia()
+		offset, gap := 0, false
+		for {
+			token, err := lexer.GetNextToken()
+			var lexErr *tokenizer.LexError
+			switch {
+			case errors.As(err, &lexErr):
+				{
+					if lexErr.Offset < offset || lexErr.Offset > len(src) {
+						return fmt.Errorf("%s: the error is at offset %d, after the token ending at %d in a source of %d bytes", lexErr, lexErr.Offset, offset, len(src))
+					}
+					offset, gap = lexErr.Offset, true
+					continue
+				}
+			case err != nil && !errors.Is(err, io.EOF):
+				return err
+			}
+
+			start, end := token.GetSpan()
+			if start < offset || !gap && start != offset || end < start || end > len(src) {
+				return fmt.Errorf("%s: span %d-%d after offset %d in a source of %d bytes", token, start, end, offset, len(src))
+			}
+			value := src[start:end]
+			if token.Type == tokenizer.String {
+				value = value[:len(value)-1]
+			}
+			if token.Value != value {
+				return fmt.Errorf("%s: the span %d-%d holds %q", token, start, end, value)
+			}
+			offset, gap = end, false
+
+			if errors.Is(err, io.EOF) {
+				if token.Type != tokenizer.EOF || end != len(src) {
+					return fmt.Errorf("%s: the last token ends at %d in a source of %d bytes", token, end, len(src))
+				}
+				return nil
+			}
+		}
+	})
+}
+
+// Parse parses src and checks the result. Errors must point at the source.
+// A tree must survive being printed with ast.Format and parsed again, being
+// copied and being encoded to JSON and decoded, when src is valid UTF-8.
+func Parse(src string) error {
+	return safely(func() error {
+		root, err := parse(src)
+		if err != nil {
+			for _, d := range diag.FromError(err) {
+				if d.Primary.Pos.Row == 0 {
+					return fmt.Errorf("error without a position: %w", err)
+				}
+			}
+			return nil
+		}
+		tree := ast.Sprint(root)
+
+		text := ast.Format(root)
+		again, err := parse(text)
+		if err != nil {
+			return fmt.Errorf("the formatted source doesn't parse: %w\n%s", err, text)
+		}
+		if got := ast.Sprint(again); got != tree {
+			return fmt.Errorf("the formatted source gives another tree\n%s\n%s\n%s", text, tree, got)
+		}
+		if got := ast.Format(again); got != text {
+			return fmt.Errorf("formatting again gives another source\n%s\n%s", text, got)
+		}
+
+		if copied := ast.Copy(root); !reflect.DeepEqual(copied, ast.Node(root)) {
+			return fmt.Errorf("the copy of the tree is another tree\n%s", ast.Sprint(copied))
+		}
+
+		// JSON strings hold text, invalid UTF-8 in a literal comes back
+		// as replacement characters
+		if !utf8.ValidString(src) {
+			return nil
+		}
+		data, err := ast.Marshal(root)
+		if err != nil {
+			return err
+		}
+		decoded, err := ast.Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("the JSON of the tree doesn't decode: %w\n%s", err, data)
+		}
+		if !reflect.DeepEqual(decoded, ast.Node(root)) {
+			return fmt.Errorf("the JSON of the tree decodes to another tree\n%s", data)
+		}
+		if encoded, err := ast.Marshal(decoded); err != nil || !bytes.Equal(encoded, data) {
+			return fmt.Errorf("encoding the decoded tree gives another JSON: %v\n%s\n%s", err, data, encoded)
+		}
+
+		return nil
+	})
+}
+
+// Edit applies edit to src and checks that parsing the edited source again
+// from the tree of src gives the tree, or the error, of a full parse. Sources
+// that don't parse before the edit are left alone.
+func Edit(src string, edit tokenizer.Edit) error {
+	return safely(func() error {
+		tree, err := parser.ParseTree(file, src)
+		if err != nil {
+			return nil
+		}
+		edited, err := edit.Apply(src)
+		if err != nil {
+			return nil
+		}
+
+		before := ast.Sprint(tree.Root)
+		got, err := tree.Edit(edit)
+		want, fullErr := parser.ParseTree(file, edited)
+		if ast.Sprint(tree.Root) != before {
+			return errors.New("the edit changed the previous tree")
+		}
+		switch {
+		case err != nil || fullErr != nil:
+			if fmt.Sprint(err) != fmt.Sprint(fullErr) {
+				return fmt.Errorf("reparsing fails with %v, a full parse with %v", err, fullErr)
+			}
+		case !reflect.DeepEqual(got.Root, want.Root):
+			return fmt.Errorf("reparsing gives another tree than a full parse\n%s\n%s", ast.Sprint(got.Root), ast.Sprint(want.Root))
+		}
+		return nil
+	})
+}
+
+func parse(src string) (*ast.Program, error) {
+	p, err := parser.NewParser(tokenizer.FromString(file, src))
+	if err != nil {
+		return nil, err
+	}
+	return p.Program()
+}
@@ -0,0 +1,83 @@
+package fuzz_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zSnails/alpha/fuzz"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// seed adds the seeds of the package, the examples of the repository and
+// generated programs to the corpus of f
+func seed(f *testing.F) []string {
+	f.Helper()
+	sources := append([]string{}, fuzz.Seeds...)
+	files, err := filepath.Glob(filepath.Join("..", "tests", "*.alpha"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		// loops forever
+		if filepath.Base(file) == "while-do.alpha" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		sources = append(sources, string(data))
+	}
+	gen := fuzz.NewGenerator(1)
+	for i := 0; i < 50; i++ {
+		sources = append(sources, gen.Program())
+	}
+	return sources
+}
+
+func FuzzTokens(f *testing.F) {
+	for _, src := range seed(f) {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		if err := fuzz.Tokens(src); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, src := range seed(f) {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		if err := fuzz.Parse(src); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzEdit(f *testing.F) {
+	gen := fuzz.NewGenerator(1)
+	for _, src := range seed(f) {
+		edit := gen.Mutate(src)
+		f.Add(src, edit.Start, edit.End, edit.Text)
+	}
+	f.Fuzz(func(t *testing.T, src string, start, end int, text string) {
+		if err := fuzz.Edit(src, tokenizer.Edit{Start: start, End: end, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, src := range seed(f) {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		if err := fuzz.Compile(src); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
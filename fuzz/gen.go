@@ -0,0 +1,309 @@
+package fuzz
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// names are the identifiers generated programs use, true and false are
+// identifiers as well
+var names = []string{"x", "y", "total", "_tmp", "n2", "true", "false", "print", "putint", "Integer"}
+
+// words fill generated string literals
+var words = []string{"hola", "mundo", "a b", "it's", "", "ñandú", "// no comment"}
+
+// operators are the operators the tokenizer can read
+var operators = []string{"+", "-", "*", "/", "<", ">", "<=", ">=", "=", "=="}
+
+// separators go between tokens, comments included
+var separators = []string{" ", " ", " ", "\n", "\t", "\n    ", " // note\n", "\r\n"}
+
+// The Generator structure writes random programs following the grammar in
+// the comments of the parser. Each production picks one of its alternatives
+// at random, the ones that nest are left out past MaxDepth so every program
+// ends.
+type Generator struct {
+	Rand *rand.Rand
+	// MaxDepth bounds the nesting of commands and expressions
+	MaxDepth int
+	depth    int
+	sb       strings.Builder
+	// last is the last token written, a separator is needed before the next
+	// one when both are words
+	last string
+}
+
+// NewGenerator returns a generator of programs nested up to four levels,
+// seeded with seed
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		Rand:     rand.New(rand.NewSource(seed)),
+		MaxDepth: 4,
+	}
+}
+
+// token writes a token, separated from the previous one by whitespace or a
+// comment when it has to or when the generator feels like it
+func (g *Generator) token(text string) {
+	if g.last != "" && (isWord(g.last[len(g.last)-1]) && isWord(text[0]) || g.Rand.Intn(3) > 0) {
+		g.sb.WriteString(separators[g.Rand.Intn(len(separators))])
+	}
+	g.sb.WriteString(text)
+	g.last = text
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// deep reports whether the nesting is past MaxDepth, the nested
+// alternatives aren't picked then
+func (g *Generator) deep() bool {
+	return g.depth >= g.MaxDepth
+}
+
+// Program returns a new program
+//
+//	program ::= (import String)* (singleCommand | module)
+func (g *Generator) Program() string {
+	g.sb.Reset()
+	g.last = ""
+	g.depth = 0
+	for i := g.Rand.Intn(4) - 2; i > 0; i-- {
+		g.token("import")
+		g.token(strconv.Quote(names[g.Rand.Intn(len(names))] + ".alpha"))
+	}
+	if g.Rand.Intn(4) == 0 {
+		g.module()
+	} else {
+		g.singleCommand()
+	}
+	if g.Rand.Intn(2) == 0 {
+		g.sb.WriteString("\n")
+	}
+	return g.sb.String()
+}
+
+// module writes a module
+//
+//	module ::= [export] singleDeclaration (; [export] singleDeclaration)*
+func (g *Generator) module() {
+	for i := 0; i == 0 || g.Rand.Intn(2) == 0; i++ {
+		if i > 0 {
+			g.token(";")
+		}
+		if g.Rand.Intn(2) == 0 {
+			g.token("export")
+		}
+		g.singleDeclaration()
+	}
+}
+
+// singleCommand writes a single command
+//
+//	singleCommand ::=
+//	         Identifier (= expression | arguments)
+//	        | if expression then singleCommand
+//	        | while expression do singleCommand
+//	        | let declaration in singleCommand
+//	        | begin command end
+//	        | ε
+func (g *Generator) singleCommand() {
+	g.depth++
+	defer func() { g.depth-- }()
+
+	choice := g.Rand.Intn(8)
+	if g.deep() {
+		choice = g.Rand.Intn(3)
+	}
+	switch choice {
+	case 0:
+		return
+	case 1:
+		{
+			g.identifier()
+			g.token("=")
+			g.expression()
+		}
+	case 2:
+		{
+			g.identifier()
+			g.arguments()
+		}
+	case 3:
+		{
+			g.token("if")
+			g.expression()
+			g.token("then")
+			g.singleCommand()
+			g.token("else")
+			g.singleCommand()
+		}
+	case 4:
+		{
+			g.token("while")
+			g.expression()
+			g.token("do")
+			g.singleCommand()
+		}
+	case 5:
+		{
+			g.token("let")
+			g.declaration()
+			g.token("in")
+			g.singleCommand()
+		}
+	default:
+		{
+			g.token("begin")
+			g.command()
+			g.token("end")
+		}
+	}
+}
+
+// command writes the commands of a block
+//
+//	command ::= singleCommand (; singleCommand)*
+func (g *Generator) command() {
+	g.singleCommand()
+	for g.Rand.Intn(2) == 0 {
+		g.token(";")
+		g.singleCommand()
+	}
+}
+
+// declaration writes the declarations of a let
+//
+//	declaration ::= singleDeclaration (; singleDeclaration)*
+func (g *Generator) declaration() {
+	g.singleDeclaration()
+	for g.Rand.Intn(3) == 0 {
+		g.token(";")
+		g.singleDeclaration()
+	}
+}
+
+// singleDeclaration writes a declaration
+//
+//	singleDeclaration ::=
+//	         const Identifier ~ expression
+//	       | var identifier : typeDenoter
+func (g *Generator) singleDeclaration() {
+	switch g.Rand.Intn(5) {
+	case 0, 1:
+		{
+			g.token("const")
+			g.identifier()
+			g.token("~")
+			g.expression()
+		}
+	default:
+		{
+			g.token("var")
+			g.identifier()
+			g.token(":")
+			g.identifier()
+		}
+	}
+}
+
+// expression writes an expression
+//
+//	expression ::= primaryExpression (operator primaryExpression)*
+func (g *Generator) expression() {
+	g.depth++
+	defer func() { g.depth-- }()
+
+	g.primaryExpression()
+	for g.Rand.Intn(3) == 0 {
+		g.token(operators[g.Rand.Intn(len(operators))])
+		g.primaryExpression()
+	}
+}
+
+// primaryExpression writes an operand
+//
+//	primaryExpression ::= Literal | Identifier [arguments] | ( expression )
+func (g *Generator) primaryExpression() {
+	choice := g.Rand.Intn(7)
+	if g.deep() {
+		choice = g.Rand.Intn(5)
+	}
+	switch choice {
+	case 0:
+		g.token(strconv.Itoa(g.Rand.Intn(1000)))
+	case 1:
+		g.token(strconv.Itoa(g.Rand.Intn(100)) + "." + strconv.Itoa(g.Rand.Intn(100)))
+	case 2:
+		{
+			word := words[g.Rand.Intn(len(words))]
+			quote := `"`
+			if strings.Contains(word, `"`) || g.Rand.Intn(4) == 0 && !strings.Contains(word, "'") {
+				quote = "'"
+			}
+			g.token(quote + word + quote)
+		}
+	case 3, 4:
+		g.identifier()
+	case 5:
+		{
+			g.identifier()
+			g.arguments()
+		}
+	default:
+		{
+			g.token("(")
+			g.expression()
+			g.token(")")
+		}
+	}
+}
+
+// arguments writes the arguments of a call
+//
+//	arguments ::= ( [expression (, expression)*] )
+func (g *Generator) arguments() {
+	g.token("(")
+	if g.Rand.Intn(4) > 0 {
+		g.expression()
+		for g.Rand.Intn(3) == 0 {
+			g.token(",")
+			g.expression()
+		}
+	}
+	g.token(")")
+}
+
+func (g *Generator) identifier() {
+	g.token(names[g.Rand.Intn(len(names))])
+}
+
+// Mutate returns a random edit of src: a few bytes of it replaced by a
+// token, by random bytes or by nothing. Most mutations break the program.
+func (g *Generator) Mutate(src string) tokenizer.Edit {
+	start := g.Rand.Intn(len(src) + 1)
+	end := min(len(src), start+g.Rand.Intn(6))
+	var text string
+	switch g.Rand.Intn(4) {
+	case 0:
+		text = ""
+	case 1:
+		{
+			b := make([]byte, 1+g.Rand.Intn(3))
+			for i := range b {
+				b[i] = byte(g.Rand.Intn(128))
+			}
+			text = string(b)
+		}
+	default:
+		{
+			tokens := append([]string{"begin", "end", "if", "let", ";", "(", ")", "'", `"`, "//", "1.", "~", ":"}, operators...)
+			text = tokens[g.Rand.Intn(len(tokens))]
+		}
+	}
+	return tokenizer.Edit{Start: start, End: end, Text: text}
+}
@@ -0,0 +1,39 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Build compiles the source of a generated program into a native binary
+// using the Go toolchain found in $PATH.
+func Build(src []byte, out string) error {
+	out, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "alpha-build-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module alphaprogram\n\ngo 1.22\n"), 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o644); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gogen: go build: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// The Generator structure translates functions of the intermediate
+// representation into the source of a Go main package. Every variable is
+// declared at the top of main and every basic block becomes a label jumped
+// to with goto.
+type Generator struct {
+	fn     *ir.Func
+	out    bytes.Buffer
+	labels map[*ir.Block]bool
+	errors []error
+}
+
+func NewGenerator(fn *ir.Func) *Generator {
+	return &Generator{
+		fn:     fn,
+		labels: map[*ir.Block]bool{},
+	}
+}
+
+// Generate translates fn, which must not be in SSA form. Every statement is
+// preceded by a line directive so panics point back to the alpha source.
+func Generate(fn *ir.Func) ([]byte, error) {
+	return NewGenerator(fn).Program()
+}
+
+func (g *Generator) errorf(pos ast.Position, format string, args ...any) {
+	g.errors = append(g.errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+func (g *Generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.out, format, args...)
+}
+
+// line starts a new statement pointing it back to the instruction's
+// position in the source, the /*line*/ form survives the indentation done
+// by gofmt.
+func (g *Generator) line(pos ast.Position, format string, args ...any) {
+	g.printf("\n")
+	if pos.File != "" {
+		g.printf("/*line %s:%d:%d*/", pos.File, pos.Row, pos.Col)
+	}
+	g.printf(format, args...)
+}
+
+// next returns the block laid out after b, control falls through to it
+// without a goto
+func (g *Generator) next(b *ir.Block) *ir.Block {
+	if b.Index+1 < len(g.fn.Blocks) {
+		return g.fn.Blocks[b.Index+1]
+	}
+	return nil
+}
+
+// Program translates the function into main, only the blocks some goto
+// jumps to get a label since Go rejects unused ones
+func (g *Generator) Program() ([]byte, error) {
+	if g.fn.SSA {
+		return nil, fmt.Errorf("gogen: %s is in SSA form", g.fn.Name)
+	}
+	for _, b := range g.fn.Blocks {
+		for i, succ := range b.Succs {
+			if succ != g.next(b) || b.Terminator().Op == ir.Branch && i == 0 {
+				g.labels[succ] = true
+			}
+		}
+	}
+
+	g.printf("// Code generated by alpha build. DO NOT EDIT.\n\n")
+	g.printf("package main\n\n%s\nfunc main() {", imports)
+	// alpha allows unused declarations, every variable is marked as used
+	for _, v := range g.fn.Vars() {
+		g.printf("\nvar %s %s\n_ = %s", v.Ident(), goType(v.T), v.Ident())
+	}
+	for _, b := range g.fn.Blocks {
+		g.Block(b)
+	}
+	g.printf("\n}\n\n//line alpha_runtime.go:1\n%s%s", runtime, failures())
+
+	if len(g.errors) > 0 {
+		return nil, g.errors[0]
+	}
+
+	src, err := format.Source(g.out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gogen: formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// Block translates the instructions of a basic block
+func (g *Generator) Block(b *ir.Block) {
+	if g.labels[b] {
+		g.printf("\n%s:", b)
+	}
+	for _, instr := range b.Instrs {
+		switch {
+		case instr.Op == ir.Copy || instr.Op == ir.Declare:
+			g.line(instr.Pos, "%s = %s", instr.Dst.Ident(), g.value(instr.Args[0]))
+		case instr.Op == ir.Convert:
+			g.line(instr.Pos, "%s = float64(%s)", instr.Dst.Ident(), g.value(instr.Args[0]))
+		case instr.Op.IsBinary():
+			g.binary(instr)
+		case instr.Op == ir.Call:
+			g.call(instr)
+		case instr.Op == ir.Assert:
+			{
+				g.line(instr.Pos, "if !%s {", g.value(instr.Args[0]))
+				g.line(instr.Pos, "panic(%s)", g.value(instr.Args[1]))
+				g.printf("\n}")
+			}
+		case instr.Op == ir.Jump:
+			{
+				if b.Succs[0] != g.next(b) {
+					g.line(instr.Pos, "goto %s", b.Succs[0])
+				}
+			}
+		case instr.Op == ir.Branch:
+			{
+				g.line(instr.Pos, "if %s {\ngoto %s\n}", g.value(instr.Args[0]), b.Succs[0])
+				if b.Succs[1] != g.next(b) {
+					g.line(instr.Pos, "goto %s", b.Succs[1])
+				}
+			}
+		case instr.Op == ir.Return:
+			g.line(instr.Pos, "return")
+		default:
+			g.errorf(instr.Pos, "%s can't be translated to Go", instr.Op)
+		}
+	}
+}
+
+// binary translates an operation. Go evaluates operations on constants at
+// compile time, where overflows and divisions by zero are errors, so one of
+// the constants goes through the destination first. Integer divisions go
+// through the runtime, which panics with the message of the interpreter.
+func (g *Generator) binary(instr *ir.Instr) {
+	dst := instr.Dst.Ident()
+	lhs, rhs := g.value(instr.Args[0]), g.value(instr.Args[1])
+	if instr.Args[0].Type() == types.Integer && instr.Op == ir.Div {
+		g.line(instr.Pos, "%s = alpha_div(%s, %s)", dst, lhs, rhs)
+		return
+	}
+	_, lconst := instr.Args[0].(*ir.Const)
+	_, rconst := instr.Args[1].(*ir.Const)
+	if lconst && rconst && !instr.Op.IsComparison() {
+		g.line(instr.Pos, "%s = %s", dst, lhs)
+		lhs = dst
+	}
+	g.line(instr.Pos, "%s = %s %s %s", dst, lhs, operators[instr.Op], rhs)
+}
+
+func (g *Generator) call(instr *ir.Instr) {
+	sym := instr.Callee
+	if !builtins[sym.Name] {
+		g.errorf(instr.Pos, "%s can't be translated to Go, only the prelude builtins can", sym.Name)
+		return
+	}
+
+	values := make([]string, len(instr.Args))
+	for i, arg := range instr.Args {
+		values[i] = g.value(arg)
+	}
+	call := fmt.Sprintf("alpha_%s(%s)", sym.Name, strings.Join(values, ", "))
+	if instr.Dst == nil {
+		g.line(instr.Pos, "%s", call)
+		return
+	}
+	g.line(instr.Pos, "%s = %s", instr.Dst.Ident(), call)
+}
+
+// value returns the Go expression for an operand
+func (g *Generator) value(value ir.Value) string {
+	if v, ok := value.(*ir.Var); ok {
+		return v.Ident()
+	}
+	switch c := value.(*ir.Const).Value.(type) {
+	case int:
+		return strconv.Itoa(c)
+	case float64:
+		return formatFloat(c)
+	case string:
+		return strconv.Quote(c)
+	case bool:
+		return strconv.FormatBool(c)
+	}
+	return zero(value.Type())
+}
+
+// formatFloat writes a float literal Go won't mistake for an integer
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	case math.IsNaN(f):
+		return "math.NaN()"
+	}
+	value := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(value, ".e") {
+		value += ".0"
+	}
+	return "float64(" + value + ")"
+}
+
+// operators maps the operations to the Go operators, they apply to every
+// type the checker allows them on
+var operators = map[ir.Op]string{
+	ir.Add:          "+",
+	ir.Sub:          "-",
+	ir.Mul:          "*",
+	ir.Div:          "/",
+	ir.Less:         "<",
+	ir.Greater:      ">",
+	ir.LessEqual:    "<=",
+	ir.GreaterEqual: ">=",
+	ir.Equal:        "==",
+}
+
+func goType(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "int"
+	case types.Float:
+		return "float64"
+	case types.String:
+		return "string"
+	case types.Boolean:
+		return "bool"
+	}
+	return "any"
+}
+
+func zero(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "0"
+	case types.Float:
+		return "float64(0)"
+	case types.String:
+		return `""`
+	case types.Boolean:
+		return "false"
+	}
+	return "nil"
+}
@@ -0,0 +1,54 @@
+package gogen_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/gogen"
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// TestShadowedNames builds a program declaring a variable named like the
+// one shadowing another and compares its output with the interpreter's
+func TestShadowedNames(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command isn't in $PATH")
+	}
+	program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(filepath.Join("..", "tests", "shadowed-names.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	err = interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(""), &want).Run(context.Background(), program.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := gogen.Generate(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(t.TempDir(), "shadowed-names")
+	if err := gogen.Build(src, bin); err != nil {
+		t.Fatal(err)
+	}
+	got, err := exec.Command(bin).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want.String() {
+		t.Errorf("the binary prints %q, the interpreter %q", got, want.String())
+	}
+}
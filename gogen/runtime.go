@@ -0,0 +1,149 @@
+package gogen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/stdlib"
+)
+
+// builtins lists the prelude builtins the runtime implements
+var builtins = map[string]bool{
+	"print":    true,
+	"println":  true,
+	"readLine": true,
+	"readInt":  true,
+	"length":   true,
+	"concat":   true,
+	"substr":   true,
+	"toInt":    true,
+	"toFloat":  true,
+	"abs":      true,
+	"sqrt":     true,
+	"pow":      true,
+	"exit":     true,
+}
+
+// imports lists the packages used by the runtime
+const imports = `import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+`
+
+// runtime is added to every generated program, it must behave like the
+// builtins in the stdlib package
+const runtime = `var (
+	alpha_stdin  = bufio.NewReader(os.Stdin)
+	alpha_stdout = bufio.NewWriter(os.Stdout)
+)
+
+func alpha_format(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return fmt.Sprintf("%.6g", v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+func alpha_print(args ...any) {
+	for _, arg := range args {
+		alpha_stdout.WriteString(alpha_format(arg))
+	}
+	alpha_stdout.Flush()
+}
+
+func alpha_println(args ...any) {
+	alpha_print(append(args, "\n")...)
+}
+
+func alpha_div(a, b int) int {
+	if b == 0 {
+		panic("integer division by zero")
+	}
+	return a / b
+}
+
+func alpha_readLine() string {
+	line, _ := alpha_stdin.ReadString('\n')
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+func alpha_readInt() int {
+	line := alpha_readLine()
+	value, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		panic(fmt.Sprintf(alpha_readInt_failure, line))
+	}
+	return value
+}
+
+func alpha_length(s string) int {
+	return len(s)
+}
+
+func alpha_concat(a, b string) string {
+	return a + b
+}
+
+func alpha_substr(s string, start, n int) string {
+	if start < 0 || n < 0 || start > len(s) || n > len(s)-start {
+		panic(fmt.Sprintf(alpha_substr_failure, n, start, len(s)))
+	}
+	return s[start : start+n]
+}
+
+func alpha_toInt(f float64) int {
+	return int(f)
+}
+
+func alpha_toFloat(i int) float64 {
+	return float64(i)
+}
+
+func alpha_abs(f float64) float64 {
+	return math.Abs(f)
+}
+
+func alpha_sqrt(f float64) float64 {
+	return math.Sqrt(f)
+}
+
+func alpha_pow(x, y float64) float64 {
+	return math.Pow(x, y)
+}
+
+func alpha_exit(code int) {
+	alpha_stdout.Flush()
+	os.Exit(code)
+}
+`
+
+// failures declares the messages the builtins of the runtime fail with,
+// taken from stdlib.Failures
+func failures() string {
+	names := []string{}
+	for name := range stdlib.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("\nconst (\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\talpha_%s_failure = %q\n", name, stdlib.Failures[name])
+	}
+	sb.WriteString(")\n")
+	return sb.String()
+}
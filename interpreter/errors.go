@@ -0,0 +1,95 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+// Frame is an entry in the call stack of a running program, Pos is where
+// the frame was executing.
+type Frame struct {
+	Name string
+	Pos  ast.Position
+}
+
+// RuntimeError is returned when a program fails while running
+type RuntimeError struct {
+	// Pos is the position of the failing construct
+	Pos ast.Position
+	Msg string
+	// Stack is the call stack at the time of the failure, innermost first
+	Stack []Frame
+	// Err is the underlying error, like the one returned by a builtin
+	Err error
+}
+
+func (e *RuntimeError) Error() string {
+	return describe(e.Pos, e.Msg, e.Stack)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic describes the error with the call stack as notes
+func (e *RuntimeError) Diagnostic() *diag.Diagnostic {
+	return diagnose(e.Pos, e.Msg, e.Stack)
+}
+
+// describe writes a runtime error followed by its call stack
+func describe(pos ast.Position, msg string, stack []Frame) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: runtime error: %s", pos, msg)
+	for _, frame := range stack {
+		fmt.Fprintf(&sb, "\n\tat %s (%s)", frame.Name, frame.Pos)
+	}
+	return sb.String()
+}
+
+// diagnose describes a runtime error with its call stack as notes
+func diagnose(pos ast.Position, msg string, stack []Frame) *diag.Diagnostic {
+	d := diag.Errorf(pos, "runtime error: %s", msg)
+	for _, frame := range stack {
+		d.WithNote("at %s (%s)", frame.Name, frame.Pos)
+	}
+	return d
+}
+
+type LimitKind int8
+
+const (
+	StepLimit LimitKind = iota
+	CallDepthLimit
+	MemoryLimit
+)
+
+var LimitNames = map[LimitKind]string{
+	StepLimit:      "step",
+	CallDepthLimit: "call depth",
+	MemoryLimit:    "memory",
+}
+
+// LimitError is returned when a program exceeds one of its limits
+type LimitError struct {
+	Kind  LimitKind
+	Limit int
+	// Pos is the position of the construct going past the limit
+	Pos ast.Position
+	// Stack is the call stack at the time, innermost first
+	Stack []Frame
+}
+
+func (e *LimitError) Error() string {
+	return describe(e.Pos, e.msg(), e.Stack)
+}
+
+func (e *LimitError) Diagnostic() *diag.Diagnostic {
+	return diagnose(e.Pos, e.msg(), e.Stack)
+}
+
+func (e *LimitError) msg() string {
+	return fmt.Sprintf("%s limit of %d exceeded", LimitNames[e.Kind], e.Limit)
+}
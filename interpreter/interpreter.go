@@ -0,0 +1,447 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/zSnails/alpha/checker"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// Limits bounds the resources a program can use, zero means no limit
+type Limits struct {
+	// MaxSteps is the number of commands and expressions a program can
+	// evaluate
+	MaxSteps int
+	// MaxCallDepth bounds how deeply calls, commands and expressions can
+	// nest while being evaluated
+	MaxCallDepth int
+	// MaxMemory is an estimate, in bytes, of the memory the values of a
+	// program can take up
+	MaxMemory int
+}
+
+// contextCheckInterval is how many steps are taken between checks for the
+// cancellation of the running program
+const contextCheckInterval = 1024
+
+// The Interpreter structure runs checked programs by walking their tree,
+// values are represented with int, float64, string and bool.
+type Interpreter struct {
+	info    *checker.Info
+	library *stdlib.Library
+	io      *stdlib.IO
+	values  map[*types.Symbol]any
+	limits  Limits
+	ctx     context.Context
+	frames  []Frame
+	steps   int
+	depth   int
+	memory  int
+}
+
+// NewInterpreter returns an interpreter for programs checked into info,
+// builtins are looked up in library.
+func NewInterpreter(info *checker.Info, library *stdlib.Library, stdin io.Reader, stdout io.Writer) *Interpreter {
+	return &Interpreter{
+		info:    info,
+		library: library,
+		io:      stdlib.NewIO(stdin, stdout),
+		values:  map[*types.Symbol]any{},
+	}
+}
+
+// SetLimits bounds the resources the programs run from now on can use
+func (in *Interpreter) SetLimits(limits Limits) {
+	in.limits = limits
+}
+
+// SetValue assigns a value to a variable before the program runs, used for
+// variables declared outside of the program.
+func (in *Interpreter) SetValue(sym *types.Symbol, value any) {
+	value = convert(value, sym.Type)
+	in.memory += sizeOf(value) - sizeOf(in.values[sym])
+	in.values[sym] = value
+}
+
+// Run executes the single command at the root of a program, the program is
+// stopped as soon as ctx is done with a *RuntimeError wrapping ctx.Err().
+func (in *Interpreter) Run(ctx context.Context, root ast.Command) error {
+	in.ctx = ctx
+	in.steps = 0
+	in.depth = 0
+	in.frames = []Frame{{Name: "main", Pos: root.Position()}}
+	if err := ctx.Err(); err != nil {
+		return in.runtimeError(root.Position(), err, "%v", err)
+	}
+	return in.SingleCommand(root)
+}
+
+// stack returns a copy of the call stack, innermost frame first
+func (in *Interpreter) stack() []Frame {
+	stack := make([]Frame, len(in.frames))
+	for i, frame := range in.frames {
+		stack[len(in.frames)-1-i] = frame
+	}
+	return stack
+}
+
+// enter accounts for the evaluation of node, each successful call must be
+// paired with a call to leave once node is done.
+func (in *Interpreter) enter(node ast.Node) error {
+	in.steps++
+	if in.limits.MaxSteps > 0 && in.steps > in.limits.MaxSteps {
+		return in.limitError(node, StepLimit, in.limits.MaxSteps)
+	}
+	if in.limits.MaxCallDepth > 0 && in.depth >= in.limits.MaxCallDepth {
+		return in.limitError(node, CallDepthLimit, in.limits.MaxCallDepth)
+	}
+	if in.steps%contextCheckInterval == 0 {
+		if err := in.ctx.Err(); err != nil {
+			return in.runtimeError(node.Position(), err, "%v", err)
+		}
+	}
+	in.depth++
+	in.frames[len(in.frames)-1].Pos = node.Position()
+	return nil
+}
+
+func (in *Interpreter) leave() {
+	in.depth--
+}
+
+// sizeOf estimates the memory taken up by a value
+func sizeOf(value any) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 16 + len(v)
+	}
+	return 8
+}
+
+// reserve makes sure a new value fits in the memory limit
+func (in *Interpreter) reserve(node ast.Node, value any) error {
+	if in.limits.MaxMemory > 0 && in.memory+sizeOf(value) > in.limits.MaxMemory {
+		return in.limitError(node, MemoryLimit, in.limits.MaxMemory)
+	}
+	return nil
+}
+
+// store assigns value to the symbol
+func (in *Interpreter) store(node ast.Node, sym *types.Symbol, value any) error {
+	old := in.values[sym]
+	in.memory -= sizeOf(old)
+	if err := in.reserve(node, value); err != nil {
+		in.memory += sizeOf(old)
+		return err
+	}
+	in.memory += sizeOf(value)
+	in.values[sym] = value
+	return nil
+}
+
+// forget leaves the symbol without a value
+func (in *Interpreter) forget(sym *types.Symbol) {
+	in.memory -= sizeOf(in.values[sym])
+	delete(in.values, sym)
+}
+
+// runtimeError reports a failure evaluating node along with the current
+// call stack
+func (in *Interpreter) runtimeError(pos ast.Position, cause error, format string, args ...any) error {
+	in.frames[len(in.frames)-1].Pos = pos
+	return &RuntimeError{
+		Pos:   pos,
+		Msg:   fmt.Sprintf(format, args...),
+		Stack: in.stack(),
+		Err:   cause,
+	}
+}
+
+// limitError reports node going past a limit along with the current call
+// stack
+func (in *Interpreter) limitError(node ast.Node, kind LimitKind, limit int) error {
+	in.frames[len(in.frames)-1].Pos = node.Position()
+	return &LimitError{
+		Kind:  kind,
+		Limit: limit,
+		Pos:   node.Position(),
+		Stack: in.stack(),
+	}
+}
+
+// SingleCommand executes the single command constructs
+func (in *Interpreter) SingleCommand(node ast.Command) error {
+	if err := in.enter(node); err != nil {
+		return err
+	}
+	defer in.leave()
+
+	switch node := node.(type) {
+	case *ast.SkipCommand:
+		return nil
+	case *ast.BlockCommand:
+		{
+			for _, command := range node.Commands {
+				if err := in.SingleCommand(command); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case *ast.IfCommand:
+		{
+			cond, err := in.Expression(node.Cond)
+			if err != nil {
+				return err
+			}
+			if cond.(bool) {
+				return in.SingleCommand(node.Then)
+			}
+			return in.SingleCommand(node.Else)
+		}
+	case *ast.WhileCommand:
+		{
+			for {
+				cond, err := in.Expression(node.Cond)
+				if err != nil {
+					return err
+				}
+				if !cond.(bool) {
+					return nil
+				}
+				if err := in.SingleCommand(node.Body); err != nil {
+					return err
+				}
+			}
+		}
+	case *ast.LetCommand:
+		{
+			if err := in.Declaration(node.Decls); err != nil {
+				return err
+			}
+			return in.SingleCommand(node.Body)
+		}
+	case *ast.AssignCommand:
+		{
+			value, err := in.Expression(node.Value)
+			if err != nil {
+				return err
+			}
+			sym := in.info.Uses[node.Name]
+			return in.store(node, sym, convert(value, sym.Type))
+		}
+	case *ast.CallCommand:
+		{
+			_, err := in.call(node.Name, node.Args)
+			return err
+		}
+	}
+	return in.runtimeError(node.Position(), nil, "unknown command")
+}
+
+// Declaration elaborates every single declaration, variables start out
+// without a value every time their block is entered.
+func (in *Interpreter) Declaration(decls []ast.Declaration) error {
+	for _, decl := range decls {
+		sym := in.info.Defs[decl]
+		c, ok := decl.(*ast.ConstDecl)
+		if !ok {
+			in.forget(sym)
+			continue
+		}
+
+		value, err := in.Expression(c.Value)
+		if err != nil {
+			return err
+		}
+		if err := in.store(decl, sym, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *Interpreter) call(name *ast.Ident, args []ast.Expression) (any, error) {
+	if err := in.enter(name); err != nil {
+		return nil, err
+	}
+	defer in.leave()
+
+	sym := in.info.Uses[name]
+	fn := in.library.Lookup(sym.Name)
+	if fn == nil {
+		return nil, in.runtimeError(name.Pos, nil, "%s is not implemented", sym.Name)
+	}
+
+	values := make([]any, len(args))
+	for i, arg := range args {
+		value, err := in.Expression(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = convert(value, fn.Sig.Params[min(i, len(fn.Sig.Params)-1)])
+	}
+
+	in.frames[len(in.frames)-1].Pos = name.Pos
+	in.frames = append(in.frames, Frame{Name: fn.Name, Pos: name.Pos})
+	result, err := invoke(fn, in.io, values)
+	if err != nil {
+		if _, ok := err.(*stdlib.ExitError); ok {
+			return nil, err
+		}
+		return nil, in.runtimeError(name.Pos, err, "%s", err)
+	}
+	in.frames = in.frames[:len(in.frames)-1]
+	if err := in.reserve(name, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// invoke calls a builtin, a panicking builtin fails like one returning an
+// error instead of taking the whole process down
+func invoke(fn *stdlib.Function, io *stdlib.IO, args []any) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", fn.Name, r)
+		}
+	}()
+	return fn.Impl(io, args)
+}
+
+// Expression evaluates the expression constructs, operands are evaluated
+// from left to right
+func (in *Interpreter) Expression(node ast.Expression) (any, error) {
+	switch node := node.(type) {
+	case *ast.IntegerLit:
+		return node.Value, nil
+	case *ast.FloatLit:
+		return node.Value, nil
+	case *ast.StringLit:
+		return node.Value, nil
+	case *ast.Ident:
+		{
+			sym := in.info.Uses[node]
+			if sym.Value != nil {
+				return sym.Value, nil
+			}
+			value, ok := in.values[sym]
+			if !ok {
+				return nil, in.runtimeError(node.Pos, nil, "variable %s used before being assigned", sym.Name)
+			}
+			return value, nil
+		}
+	case *ast.CallExpr:
+		return in.call(node.Name, node.Args)
+	}
+
+	if err := in.enter(node); err != nil {
+		return nil, err
+	}
+	defer in.leave()
+
+	switch node := node.(type) {
+	case *ast.ParenExpr:
+		return in.Expression(node.X)
+	case *ast.BinaryExpr:
+		{
+			lhs, err := in.Expression(node.X)
+			if err != nil {
+				return nil, err
+			}
+			rhs, err := in.Expression(node.Y)
+			if err != nil {
+				return nil, err
+			}
+			return in.binary(node, lhs, rhs)
+		}
+	}
+	return nil, in.runtimeError(node.Position(), nil, "unknown expression")
+}
+
+// convert promotes integers stored in locations of type Float
+func convert(value any, t types.Type) any {
+	if i, ok := value.(int); ok && t == types.Float {
+		return float64(i)
+	}
+	return value
+}
+
+func (in *Interpreter) binary(node *ast.BinaryExpr, lhs, rhs any) (any, error) {
+	l, lok := lhs.(int)
+	r, rok := rhs.(int)
+	if lok && rok {
+		switch node.Op {
+		case ast.Add:
+			return l + r, nil
+		case ast.Sub:
+			return l - r, nil
+		case ast.Mul:
+			return l * r, nil
+		case ast.Div:
+			{
+				if r == 0 {
+					return nil, in.runtimeError(node.OpPos, nil, "integer division by zero")
+				}
+				return l / r, nil
+			}
+		case ast.Less:
+			return l < r, nil
+		case ast.Greater:
+			return l > r, nil
+		case ast.LessEqual:
+			return l <= r, nil
+		case ast.GreaterEqual:
+			return l >= r, nil
+		case ast.Equals, ast.Comparison:
+			return l == r, nil
+		}
+	}
+
+	lf, lok := toFloat(lhs)
+	rf, rok := toFloat(rhs)
+	if lok && rok {
+		switch node.Op {
+		case ast.Add:
+			return lf + rf, nil
+		case ast.Sub:
+			return lf - rf, nil
+		case ast.Mul:
+			return lf * rf, nil
+		case ast.Div:
+			return lf / rf, nil
+		case ast.Less:
+			return lf < rf, nil
+		case ast.Greater:
+			return lf > rf, nil
+		case ast.LessEqual:
+			return lf <= rf, nil
+		case ast.GreaterEqual:
+			return lf >= rf, nil
+		case ast.Equals, ast.Comparison:
+			return lf == rf, nil
+		}
+	}
+
+	switch node.Op {
+	case ast.Equals, ast.Comparison:
+		return lhs == rhs, nil
+	}
+	return nil, in.runtimeError(node.OpPos, nil, "operator %s not defined on %v and %v", node.Op, lhs, rhs)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,174 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+func run(t *testing.T, lib *stdlib.Library, src string) (string, error) {
+	t.Helper()
+	program, err := loader.NewLoader(nil, lib.Scope(types.Universe())).LoadSource("test.alpha", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	in := interpreter.NewInterpreter(program.Info, lib, strings.NewReader(""), &out)
+	err = in.Run(context.Background(), program.Root)
+	return out.String(), err
+}
+
+func TestSubstrOutOfRange(t *testing.T) {
+	_, err := run(t, stdlib.Prelude(), "println(substr(\"hola\", 1, 9223372036854775807))")
+	var runtimeErr *interpreter.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	if runtimeErr.Pos.Row != 1 || runtimeErr.Pos.Col != 9 {
+		t.Errorf("error at %s, want 1:9", runtimeErr.Pos)
+	}
+}
+
+func TestPanickingBuiltin(t *testing.T) {
+	lib := stdlib.Prelude()
+	lib.Define(&stdlib.Function{
+		Name: "boom",
+		Sig:  types.Signature{Result: types.Void},
+		Impl: func(io *stdlib.IO, args []any) (any, error) {
+			panic("kaboom")
+		},
+	})
+	out, err := run(t, lib, "begin print(1); boom(); print(2) end")
+	var runtimeErr *interpreter.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	if !strings.Contains(runtimeErr.Msg, "boom panicked: kaboom") {
+		t.Errorf("got %q", runtimeErr.Msg)
+	}
+	if out != "1" {
+		t.Errorf("printed %q, want %q", out, "1")
+	}
+}
+
+func TestPrintWithoutArguments(t *testing.T) {
+	out, err := run(t, stdlib.Prelude(), "begin print(); println() end")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "\n" {
+		t.Errorf("printed %q, want a newline", out)
+	}
+}
+
+// limited runs src with limits until ctx is done
+func limited(t *testing.T, ctx context.Context, limits interpreter.Limits, src string) error {
+	t.Helper()
+	lib := stdlib.Prelude()
+	program, err := loader.NewLoader(nil, lib.Scope(types.Universe())).LoadSource("test.alpha", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := interpreter.NewInterpreter(program.Info, lib, strings.NewReader(""), io.Discard)
+	in.SetLimits(limits)
+	return in.Run(ctx, program.Root)
+}
+
+// checkStack checks that a stopped program reports where it stopped, the
+// innermost frame is where the error is and the outermost is main
+func checkStack(t *testing.T, err error, pos ast.Position, stack []interpreter.Frame) {
+	t.Helper()
+	if pos.Row == 0 || len(stack) == 0 || stack[0].Pos != pos || stack[len(stack)-1].Name != "main" {
+		t.Errorf("%v: stopped at %s with the stack %v", err, pos, stack)
+	}
+	if want := pos.String() + ": runtime error: "; !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), "\n\tat main (") {
+		t.Errorf("got %q, want it to start with %q and hold the stack", err, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits interpreter.Limits
+		src    string
+		kind   interpreter.LimitKind
+		msg    string
+	}{
+		{
+			name:   "steps",
+			limits: interpreter.Limits{MaxSteps: 100},
+			src:    `let var i : Integer in begin i = 0; while true do i = i + 1 end`,
+			kind:   interpreter.StepLimit,
+			msg:    "step limit of 100 exceeded",
+		},
+		{
+			name:   "call depth",
+			limits: interpreter.Limits{MaxCallDepth: 5},
+			src:    `println(abs(abs(abs(abs(abs(1))))))`,
+			kind:   interpreter.CallDepthLimit,
+			msg:    "call depth limit of 5 exceeded",
+		},
+		{
+			name:   "memory",
+			limits: interpreter.Limits{MaxMemory: 1000},
+			src:    `let var s : String in begin s = "ab"; while true do s = concat(s, s) end`,
+			kind:   interpreter.MemoryLimit,
+			msg:    "memory limit of 1000 exceeded",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := limited(t, context.Background(), test.limits, test.src)
+			var limitErr *interpreter.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("got %v, want a limit error", err)
+			}
+			if limitErr.Kind != test.kind || !strings.Contains(err.Error(), test.msg) {
+				t.Errorf("got %q, want the %s limit", err, interpreter.LimitNames[test.kind])
+			}
+			checkStack(t, err, limitErr.Pos, limitErr.Stack)
+		})
+	}
+
+	// without limits the same programs run on
+	err := limited(t, context.Background(), interpreter.Limits{}, `println(abs(abs(abs(abs(abs(1))))))`)
+	if err != nil {
+		t.Errorf("got %v without limits", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{name: "canceled before", ctx: canceled, want: context.Canceled},
+		{name: "deadline while running", ctx: expired, want: context.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := limited(t, test.ctx, interpreter.Limits{}, `let var i : Integer in begin i = 0; while true do i = i + 1 end`)
+			var runtimeErr *interpreter.RuntimeError
+			if !errors.As(err, &runtimeErr) || !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want a runtime error wrapping %v", err, test.want)
+			}
+			checkStack(t, err, runtimeErr.Pos, runtimeErr.Stack)
+		})
+	}
+}
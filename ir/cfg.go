@@ -0,0 +1,157 @@
+package ir
+
+// ReversePostorder returns the blocks reachable from the entry so every
+// block comes before its successors, back edges aside
+func (f *Func) ReversePostorder() []*Block {
+	visited := map[*Block]bool{}
+	order := []*Block{}
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		visited[b] = true
+		for _, succ := range b.Succs {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		order = append(order, b)
+	}
+	visit(f.Blocks[0])
+
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// Dominators sets the immediate dominator of every reachable block, the
+// entry is its own. It uses the iterative algorithm of Cooper, Harvey and
+// Kennedy.
+func (f *Func) Dominators() {
+	order := f.ReversePostorder()
+	index := map[*Block]int{}
+	for i, b := range order {
+		index[b] = i
+	}
+	for _, b := range f.Blocks {
+		b.Idom = nil
+	}
+
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for index[a] > index[b] {
+				a = a.Idom
+			}
+			for index[b] > index[a] {
+				b = b.Idom
+			}
+		}
+		return a
+	}
+
+	entry := order[0]
+	entry.Idom = entry
+	for changed := true; changed; {
+		changed = false
+		for _, b := range order[1:] {
+			var idom *Block
+			for _, pred := range b.Preds {
+				if pred.Idom == nil {
+					continue
+				}
+				if idom == nil {
+					idom = pred
+				} else {
+					idom = intersect(pred, idom)
+				}
+			}
+			if b.Idom != idom {
+				b.Idom = idom
+				changed = true
+			}
+		}
+	}
+}
+
+// Dominates reports whether every path from the entry to b goes through a,
+// Dominators must have been computed
+func (a *Block) Dominates(b *Block) bool {
+	for {
+		if a == b {
+			return true
+		}
+		if b.Idom == b || b.Idom == nil {
+			return false
+		}
+		b = b.Idom
+	}
+}
+
+// DominanceFrontiers returns the blocks where the dominance of every block
+// ends, Dominators must have been computed
+func (f *Func) DominanceFrontiers() map[*Block][]*Block {
+	frontiers := map[*Block][]*Block{}
+	for _, b := range f.Blocks {
+		if len(b.Preds) < 2 || b.Idom == nil {
+			continue
+		}
+		for _, pred := range b.Preds {
+			for runner := pred; runner != b.Idom && runner.Idom != nil; runner = runner.Idom {
+				if !contains(frontiers[runner], b) {
+					frontiers[runner] = append(frontiers[runner], b)
+				}
+				if runner.Idom == runner {
+					break
+				}
+			}
+		}
+	}
+	return frontiers
+}
+
+// RemoveUnreachable drops the blocks control can't reach from the entry and
+// renumbers the remaining ones
+func (f *Func) RemoveUnreachable() {
+	reachable := map[*Block]bool{}
+	for _, b := range f.ReversePostorder() {
+		reachable[b] = true
+	}
+
+	blocks := []*Block{}
+	for _, b := range f.Blocks {
+		if !reachable[b] {
+			for _, succ := range b.Succs {
+				succ.removePred(b)
+			}
+			continue
+		}
+		b.Index = len(blocks)
+		blocks = append(blocks, b)
+	}
+	f.Blocks = blocks
+}
+
+// removePred removes an edge coming from pred along with the arguments of
+// the phis for it
+func (b *Block) removePred(pred *Block) {
+	for i, p := range b.Preds {
+		if p != pred {
+			continue
+		}
+		b.Preds = append(b.Preds[:i:i], b.Preds[i+1:]...)
+		for _, instr := range b.Instrs {
+			if instr.Op == Phi {
+				instr.Args = append(instr.Args[:i:i], instr.Args[i+1:]...)
+			}
+		}
+		return
+	}
+}
+
+func contains(blocks []*Block, b *Block) bool {
+	for _, block := range blocks {
+		if block == b {
+			return true
+		}
+	}
+	return false
+}
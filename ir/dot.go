@@ -0,0 +1,41 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dot returns the control-flow graph of the function as a Graphviz graph,
+// every block is labelled with its instructions and the edges leaving a
+// branch with the value of its condition
+func (f *Func) Dot() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n", dotQuote(f.Name))
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	for _, b := range f.Blocks {
+		lines := []string{b.String() + ":"}
+		for _, instr := range b.Instrs {
+			lines = append(lines, "  "+b.line(instr))
+		}
+		fmt.Fprintf(&sb, "  %s [label=%s];\n", b, dotQuote(strings.Join(lines, "\n")+"\n"))
+	}
+	for _, b := range f.Blocks {
+		branch := b.Terminator() != nil && b.Terminator().Op == Branch
+		for i, succ := range b.Succs {
+			if branch {
+				fmt.Fprintf(&sb, "  %s -> %s [label=\"%t\"];\n", b, succ, i == 0)
+				continue
+			}
+			fmt.Fprintf(&sb, "  %s -> %s;\n", b, succ)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotQuote returns s as a quoted Graphviz string, every line ends with \l so
+// the lines are left justified
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\l`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,180 @@
+package ir
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// ErrTooManySteps stops a run executing more than MaxSteps instructions
+var ErrTooManySteps = errors.New("too many steps")
+
+// The RuntimeError structure is a failure running a function, it carries
+// the position and the message the interpreter reports for the same failure
+type RuntimeError struct {
+	Pos ast.Position
+	Msg string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s: runtime error: %s", e.Pos, e.Msg)
+}
+
+// The Machine structure runs functions, in SSA form or not, calling the
+// builtins of a library. It lets the lowering and the passes be checked
+// against the interpreter.
+type Machine struct {
+	Library *stdlib.Library
+	IO      *stdlib.IO
+	// MaxSteps bounds the instructions a run executes, 0 means no limit
+	MaxSteps int
+}
+
+func NewMachine(library *stdlib.Library, io *stdlib.IO) *Machine {
+	return &Machine{
+		Library: library,
+		IO:      io,
+	}
+}
+
+// Run runs f from its entry block until it returns or fails, the exit
+// builtin stops it with a *stdlib.ExitError
+func (m *Machine) Run(f *Func) error {
+	values := map[*Var]any{}
+	value := func(v Value) any {
+		switch v := v.(type) {
+		case *Var:
+			return values[v]
+		case *Const:
+			{
+				if v.Value == nil {
+					return Zero(v.T).Value
+				}
+				return v.Value
+			}
+		}
+		return nil
+	}
+
+	steps := 0
+	var pred *Block
+	for b := f.Blocks[0]; ; {
+		// the phis of a block read the values of the predecessor at once
+		phis := map[*Var]any{}
+		for _, instr := range b.Instrs {
+			if instr.Op != Phi {
+				break
+			}
+			phis[instr.Dst] = value(instr.Args[b.predIndex(pred)])
+		}
+		for v, phi := range phis {
+			values[v] = phi
+		}
+
+		var next *Block
+		for _, instr := range b.Instrs {
+			if steps++; m.MaxSteps > 0 && steps > m.MaxSteps {
+				return ErrTooManySteps
+			}
+			switch {
+			case instr.Op == Phi:
+				continue
+			case instr.Op == Copy || instr.Op == Declare:
+				values[instr.Dst] = value(instr.Args[0])
+			case instr.Op == Convert:
+				values[instr.Dst] = float64(value(instr.Args[0]).(int))
+			case instr.Op.IsBinary():
+				{
+					result, err := binary(instr, value(instr.Args[0]), value(instr.Args[1]))
+					if err != nil {
+						return err
+					}
+					values[instr.Dst] = result
+				}
+			case instr.Op == Call:
+				{
+					args := make([]any, len(instr.Args))
+					for i, arg := range instr.Args {
+						args[i] = value(arg)
+					}
+					result, err := m.call(instr, args)
+					if err != nil {
+						return err
+					}
+					if instr.Dst != nil {
+						values[instr.Dst] = result
+					}
+				}
+			case instr.Op == Assert:
+				{
+					if !value(instr.Args[0]).(bool) {
+						return &RuntimeError{Pos: instr.Pos, Msg: value(instr.Args[1]).(string)}
+					}
+				}
+			case instr.Op == Jump:
+				next = b.Succs[0]
+			case instr.Op == Branch:
+				{
+					next = b.Succs[1]
+					if value(instr.Args[0]).(bool) {
+						next = b.Succs[0]
+					}
+				}
+			case instr.Op == Return:
+				return nil
+			}
+		}
+		if next == nil {
+			return fmt.Errorf("%s: %s doesn't end with a terminator", f.Name, b)
+		}
+		pred, b = b, next
+	}
+}
+
+// predIndex returns the position of pred among the predecessors of b
+func (b *Block) predIndex(pred *Block) int {
+	for i, p := range b.Preds {
+		if p == pred {
+			return i
+		}
+	}
+	return -1
+}
+
+// call calls a builtin of the library, a failing or panicking builtin fails
+// as it does in the interpreter
+func (m *Machine) call(instr *Instr, args []any) (result any, err error) {
+	fn := m.Library.Lookup(instr.Callee.Name)
+	if fn == nil {
+		return nil, &RuntimeError{Pos: instr.Pos, Msg: fmt.Sprintf("%s is not implemented", instr.Callee.Name)}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &RuntimeError{Pos: instr.Pos, Msg: fmt.Sprintf("%s panicked: %v", fn.Name, r)}
+		}
+	}()
+	result, err = fn.Impl(m.IO, args)
+	var exit *stdlib.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		err = &RuntimeError{Pos: instr.Pos, Msg: err.Error()}
+	}
+	return result, err
+}
+
+// binary computes an operation, an integer division by zero fails
+func binary(instr *Instr, lhs, rhs any) (any, error) {
+	if instr.Op == Equal {
+		return lhs == rhs, nil
+	}
+	if instr.Op == Div && instr.Args[0].Type() == types.Integer && rhs.(int) == 0 {
+		return nil, &RuntimeError{Pos: instr.Pos, Msg: "integer division by zero"}
+	}
+	value, ok := evaluate(instr, []any{lhs, rhs})
+	if !ok {
+		return nil, fmt.Errorf("%s: can't evaluate %s", instr.Pos, instr)
+	}
+	return value, nil
+}
@@ -0,0 +1,175 @@
+package ir_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// programs returns the examples of the repository and of testdata, but the
+// ones that loop forever
+func programs(t *testing.T) []string {
+	t.Helper()
+	files := []string{}
+	for _, pattern := range []string{filepath.Join("..", "tests", "*.alpha"), filepath.Join("testdata", "*.alpha")} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	kept := files[:0]
+	for _, file := range files {
+		if filepath.Base(file) != "while-do.alpha" {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// result is what a run prints and how it ends
+type result struct {
+	out string
+	err string
+}
+
+// interpret runs a program with the interpreter, runtime errors are
+// described by their position and message
+func interpret(program *loader.Program) result {
+	var out bytes.Buffer
+	err := interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(""), &out).Run(context.Background(), program.Root)
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		return result{out: out.String(), err: (&ir.RuntimeError{Pos: runtimeErr.Pos, Msg: runtimeErr.Msg}).Error()}
+	case err != nil:
+		return result{out: out.String(), err: err.Error()}
+	}
+	return result{out: out.String()}
+}
+
+// evaluate runs a function with the machine
+func evaluate(fn *ir.Func) result {
+	var out bytes.Buffer
+	m := ir.NewMachine(stdlib.Prelude(), stdlib.NewIO(strings.NewReader(""), &out))
+	m.MaxSteps = 1_000_000
+	if err := m.Run(fn); err != nil {
+		return result{out: out.String(), err: err.Error()}
+	}
+	return result{out: out.String()}
+}
+
+// compare runs a program with the interpreter and its function before and
+// after the conversion to SSA form, optimizing and taking it out of SSA form
+func compare(t *testing.T, program *loader.Program) {
+	t.Helper()
+	want := interpret(program)
+	stages := []struct {
+		name      string
+		transform func(fn *ir.Func)
+	}{
+		{name: "lowered", transform: func(fn *ir.Func) {}},
+		{name: "ssa", transform: func(fn *ir.Func) { fn.ToSSA() }},
+		{name: "optimized", transform: func(fn *ir.Func) { fn.Optimize(nil) }},
+		{name: "out of SSA", transform: func(fn *ir.Func) { fn.Optimize(nil); fn.FromSSA() }},
+	}
+	for _, stage := range stages {
+		fn, err := ir.Lower(program.Root, program.Info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stage.transform(fn)
+		if got := evaluate(fn); got != want {
+			t.Errorf("%s: got %q and error %q, the interpreter %q and error %q\n%s", stage.name, got.out, got.err, want.out, want.err, fn)
+		}
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	for _, file := range programs(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(file)
+			if err != nil {
+				t.Skip(err)
+			}
+			compare(t, program)
+		})
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	tests := []string{
+		`let var x : Integer in println(x)`,
+		`let var x : Integer in begin if 1 < 2 then x = 1 else begin end; println(x) end`,
+		`let var x : Integer in begin x = 9223372036854775807; println(x + 1, x * 2, (0 - x - 1) / (0 - 1)) end`,
+		`begin println(1, 2); println(3, 1 / 0) end`,
+		`println(substr("hola", 1, 9223372036854775807))`,
+		`begin println(1.0 / 0.0, (0.0 / 0.0) == (0.0 / 0.0)); exit(3); println(1) end`,
+		`let var a : Integer; var b : Integer; var t : Integer; var i : Integer in begin a = 1; b = 2; i = 0; while i < 3 do begin t = a; a = b; b = t; i = i + 1; println(a, b) end end`,
+		`let var s : String; var i : Integer in begin i = 0; while i < 3 do let var t : String in begin if i == 2 then s = "x" else t = "y"; println(t); i = i + 1 end; println(s) end`,
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, program)
+		})
+	}
+}
+
+// TestGolden compares the lowered, SSA and optimized functions of the
+// programs in testdata with their golden files, go test -update rewrites
+// them
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			fn, err := ir.Lower(program.Root, program.Info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.WriteString("; lowered\n" + fn.String())
+			fn.ToSSA()
+			got.WriteString("\n; ssa\n" + fn.String())
+			fn.Optimize(nil)
+			got.WriteString("\n; optimized\n" + fn.String())
+
+			golden := strings.TrimSuffix(file, ".alpha") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("the functions differ from %s\n%s", golden, got.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ir
+
+// pruneGuards removes the asserts reading a flag that is true on every path
+// reaching them, then the assignments of the flags no assert reads anymore.
+// A forward analysis finds the flags that may be false at the start of
+// every block.
+func (f *Func) pruneGuards(flags map[*Var]*Var) {
+	isFlag := map[*Var]bool{}
+	for _, flag := range flags {
+		isFlag[flag] = true
+	}
+
+	// transfer runs the block over the flags that may be false at its
+	// start, visit sees every instruction with the flags before it
+	transfer := func(b *Block, in map[*Var]bool, visit func(instr *Instr, unset map[*Var]bool)) map[*Var]bool {
+		unset := map[*Var]bool{}
+		for flag := range in {
+			unset[flag] = true
+		}
+		for _, instr := range b.Instrs {
+			if visit != nil {
+				visit(instr, unset)
+			}
+			if instr.Op != Copy || !isFlag[instr.Dst] {
+				continue
+			}
+			if instr.Args[0].(*Const).Value == true {
+				delete(unset, instr.Dst)
+			} else {
+				unset[instr.Dst] = true
+			}
+		}
+		return unset
+	}
+
+	order := f.ReversePostorder()
+	in := map[*Block]map[*Var]bool{}
+	out := map[*Block]map[*Var]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range order {
+			unset := map[*Var]bool{}
+			for _, pred := range b.Preds {
+				for flag := range out[pred] {
+					unset[flag] = true
+				}
+			}
+			in[b] = unset
+			// the sets only grow, a new size means a new set
+			if next := transfer(b, unset, nil); len(next) != len(out[b]) || out[b] == nil {
+				out[b], changed = next, true
+			}
+		}
+	}
+
+	read := map[*Var]bool{}
+	for _, b := range f.Blocks {
+		kept := []*Instr{}
+		transfer(b, in[b], func(instr *Instr, unset map[*Var]bool) {
+			if instr.Op == Assert && !unset[instr.Args[0].(*Var)] {
+				return
+			}
+			if instr.Op == Assert {
+				read[instr.Args[0].(*Var)] = true
+			}
+			kept = append(kept, instr)
+		})
+		b.Instrs = kept
+	}
+
+	for _, b := range f.Blocks {
+		kept := b.Instrs[:0]
+		for _, instr := range b.Instrs {
+			if instr.Op == Copy && isFlag[instr.Dst] && !read[instr.Dst] {
+				continue
+			}
+			kept = append(kept, instr)
+		}
+		b.Instrs = kept
+	}
+}
@@ -0,0 +1,310 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+type Op int
+
+const (
+	// Copy sets Dst to Args[0]
+	Copy Op = iota
+	// Declare sets the variable Dst to the zero value Args[0] when its let
+	// is entered, analyses tell it apart from assignments
+	Declare
+	// Convert sets Dst to the Integer Args[0] converted to Float
+	Convert
+	Add
+	Sub
+	Mul
+	Div
+	Less
+	Greater
+	LessEqual
+	GreaterEqual
+	Equal
+	// Call calls Callee with Args, Dst is nil for builtins returning nothing
+	Call
+	// Assert stops the program with a runtime error when the Boolean Args[0]
+	// is false, the String constant Args[1] is its message
+	Assert
+	// Phi sets Dst to the argument of the predecessor control came from,
+	// Args follow the order of the predecessors of the block
+	Phi
+	// Jump continues with the only successor of the block
+	Jump
+	// Branch continues with the first successor when Args[0] is true and
+	// with the second one otherwise
+	Branch
+	// Return ends the function
+	Return
+)
+
+var OpNames = map[Op]string{
+	Copy:         "copy",
+	Declare:      "declare",
+	Convert:      "float",
+	Add:          "+",
+	Sub:          "-",
+	Mul:          "*",
+	Div:          "/",
+	Less:         "<",
+	Greater:      ">",
+	LessEqual:    "<=",
+	GreaterEqual: ">=",
+	Equal:        "==",
+	Call:         "call",
+	Assert:       "assert",
+	Phi:          "phi",
+	Jump:         "jump",
+	Branch:       "branch",
+	Return:       "return",
+}
+
+func (o Op) String() string {
+	return OpNames[o]
+}
+
+// IsBinary reports whether the operation combines its two arguments
+func (o Op) IsBinary() bool {
+	return o >= Add && o <= Equal
+}
+
+// IsComparison reports whether the operation results in a Boolean
+func (o Op) IsComparison() bool {
+	return o >= Less && o <= Equal
+}
+
+// IsTerminator reports whether the operation ends a block
+func (o Op) IsTerminator() bool {
+	return o >= Jump
+}
+
+// Value is an operand of an instruction, either a *Const or a *Var
+type Value interface {
+	Type() types.Type
+	String() string
+}
+
+// The Const structure is a constant operand, Value holds an int, float64,
+// string or bool. A nil Value is undefined, it is used for variables read
+// before any assignment reached them.
+type Const struct {
+	T     types.Type
+	Value any
+}
+
+func (c *Const) Type() types.Type {
+	return c.T
+}
+
+func (c *Const) String() string {
+	switch v := c.Value.(type) {
+	case nil:
+		return "undef"
+	case float64:
+		{
+			value := strconv.FormatFloat(v, 'g', -1, 64)
+			if !strings.ContainsAny(value, ".eIN") {
+				value += ".0"
+			}
+			return value
+		}
+	case string:
+		return strconv.Quote(v)
+	}
+	return fmt.Sprint(c.Value)
+}
+
+// The Var structure is a location instructions write to. Temporaries have no
+// Sym and are only assigned once. Variables of the source are assigned any
+// number of times until the function is converted to SSA form, from then on
+// every assignment defines a new Version.
+type Var struct {
+	Name    string
+	T       types.Type
+	Sym     *types.Symbol
+	Version int
+}
+
+func (v *Var) Type() types.Type {
+	return v.T
+}
+
+func (v *Var) String() string {
+	if v.Version > 0 {
+		return v.Name + "." + strconv.Itoa(v.Version)
+	}
+	return v.Name
+}
+
+// Ident returns the name of the variable in generated code. Temporaries keep
+// their name, the variables of the source are written v_x and the shadowing
+// ones vN_x, so they can't clash with each other, with temporaries or with
+// the keywords of the target language. The flags telling whether they were
+// assigned are written f_x and fN_x. Versions are separated by a dot, which
+// only LLVM reads in a name, the other targets take functions that aren't in
+// SSA form.
+func (v *Var) Ident() string {
+	if v.Sym == nil {
+		return v.String()
+	}
+	prefix, name := "v", v.Name
+	if flag, ok := strings.CutSuffix(name, "?"); ok {
+		prefix, name = "f", flag
+	}
+	name, count, _ := strings.Cut(name, "#")
+	ident := prefix + count + "_" + name
+	if v.Version > 0 {
+		ident += "." + strconv.Itoa(v.Version)
+	}
+	return ident
+}
+
+// The Instr structure is a three-address instruction
+type Instr struct {
+	Op     Op
+	Dst    *Var
+	Args   []Value
+	Callee *types.Symbol
+	Pos    ast.Position
+	// ArgPos holds the position in the source of every argument, it is only
+	// kept until the function is optimized
+	ArgPos []ast.Position
+}
+
+func (i *Instr) String() string {
+	args := make([]string, len(i.Args))
+	for j, arg := range i.Args {
+		args[j] = arg.String()
+	}
+
+	var value string
+	switch {
+	case i.Op == Copy:
+		value = args[0]
+	case i.Op.IsBinary():
+		value = fmt.Sprintf("%s %s %s", args[0], i.Op, args[1])
+	case i.Op == Call:
+		value = fmt.Sprintf("call %s(%s)", i.Callee.Name, strings.Join(args, ", "))
+	default:
+		value = strings.TrimSpace(i.Op.String() + " " + strings.Join(args, ", "))
+	}
+	if i.Dst == nil {
+		return value
+	}
+	return i.Dst.String() + " = " + value
+}
+
+// The Block structure is a basic block, its last instruction is its only
+// terminator
+type Block struct {
+	Index  int
+	Instrs []*Instr
+	Preds  []*Block
+	Succs  []*Block
+	// Idom is the immediate dominator of the block, see Func.Dominators
+	Idom *Block
+}
+
+func (b *Block) String() string {
+	return "b" + strconv.Itoa(b.Index)
+}
+
+// Terminator returns the last instruction of the block
+func (b *Block) Terminator() *Instr {
+	if len(b.Instrs) == 0 {
+		return nil
+	}
+	return b.Instrs[len(b.Instrs)-1]
+}
+
+// The Func structure is the control-flow graph of a function, Blocks[0] is
+// its entry and never has predecessors.
+type Func struct {
+	Name   string
+	Blocks []*Block
+	// SSA is set once the function is in SSA form
+	SSA bool
+}
+
+func (f *Func) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "func %s:\n", f.Name)
+	for _, b := range f.Blocks {
+		fmt.Fprintf(&sb, "%s:", b)
+		if len(b.Preds) > 0 {
+			preds := make([]string, len(b.Preds))
+			for i, pred := range b.Preds {
+				preds[i] = pred.String()
+			}
+			fmt.Fprintf(&sb, " ; preds %s", strings.Join(preds, ", "))
+		}
+		sb.WriteString("\n")
+		for _, instr := range b.Instrs {
+			sb.WriteString("  " + b.line(instr) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// line returns an instruction of the block as it is listed, phi arguments
+// name the predecessor they come from and terminators their successors
+func (b *Block) line(instr *Instr) string {
+	if instr.Op == Phi {
+		args := make([]string, len(instr.Args))
+		for i, arg := range instr.Args {
+			args[i] = fmt.Sprintf("%s: %s", b.Preds[i], arg)
+		}
+		return fmt.Sprintf("%s = phi [%s]", instr.Dst, strings.Join(args, ", "))
+	}
+	line := instr.String()
+	if instr.Op.IsTerminator() {
+		for _, succ := range b.Succs {
+			line += " " + succ.String()
+		}
+	}
+	return line
+}
+
+// Vars returns every variable the function reads or writes, in the order
+// they first appear
+func (f *Func) Vars() []*Var {
+	seen := map[*Var]bool{}
+	vars := []*Var{}
+	add := func(value Value) {
+		if v, ok := value.(*Var); ok && !seen[v] {
+			seen[v] = true
+			vars = append(vars, v)
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			for _, arg := range instr.Args {
+				add(arg)
+			}
+			if instr.Dst != nil {
+				add(instr.Dst)
+			}
+		}
+	}
+	return vars
+}
+
+// newBlock appends an empty block to the function
+func (f *Func) newBlock() *Block {
+	b := &Block{Index: len(f.Blocks)}
+	f.Blocks = append(f.Blocks, b)
+	return b
+}
+
+// addEdge records that control can flow from one block to another
+func addEdge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
@@ -0,0 +1,336 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/zSnails/alpha/checker"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// The Builder structure lowers checked programs into a function of basic
+// blocks, variables of the source are kept as assignable Vars.
+type Builder struct {
+	// Guard makes reads of variables that may not be assigned yet stop the
+	// program with a runtime error, as they do in the interpreter
+	Guard  bool
+	info   *checker.Info
+	fn     *Func
+	block  *Block
+	vars   map[*types.Symbol]*Var
+	flags  map[*Var]*Var
+	counts map[string]int
+	temps  int
+	errors []error
+}
+
+func NewBuilder(info *checker.Info) *Builder {
+	return &Builder{
+		info:   info,
+		vars:   map[*types.Symbol]*Var{},
+		flags:  map[*Var]*Var{},
+		counts: map[string]int{},
+	}
+}
+
+// Lower lowers the program rooted at root into the main function, with the
+// reads of variables guarded
+func Lower(root ast.Command, info *checker.Info) (*Func, error) {
+	b := NewBuilder(info)
+	b.Guard = true
+	return b.Program(root)
+}
+
+func (b *Builder) errorf(pos ast.Position, format string, args ...any) {
+	b.errors = append(b.errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+// emit appends an instruction to the current block
+func (b *Builder) emit(instr *Instr) *Instr {
+	b.block.Instrs = append(b.block.Instrs, instr)
+	return instr
+}
+
+// temp returns a new temporary of type t
+func (b *Builder) temp(t types.Type) *Var {
+	b.temps++
+	return &Var{Name: "t" + strconv.Itoa(b.temps), T: t}
+}
+
+// variable returns the Var of a symbol, shadowed names are numbered after a
+// #, which can't appear in an identifier
+func (b *Builder) variable(sym *types.Symbol) *Var {
+	if v, ok := b.vars[sym]; ok {
+		return v
+	}
+	b.counts[sym.Name]++
+	name := sym.Name
+	if count := b.counts[sym.Name]; count > 1 {
+		name += "#" + strconv.Itoa(count)
+	}
+	v := &Var{Name: name, T: sym.Type, Sym: sym}
+	b.vars[sym] = v
+	return v
+}
+
+// flag returns the Boolean variable telling whether v was assigned since it
+// was declared, it is named after v with a trailing ?
+func (b *Builder) flag(v *Var) *Var {
+	if flag, ok := b.flags[v]; ok {
+		return flag
+	}
+	sym := &types.Symbol{Name: v.Sym.Name + "?", Kind: types.Var, Type: types.Boolean, Pos: v.Sym.Pos}
+	flag := &Var{Name: v.Name + "?", T: types.Boolean, Sym: sym}
+	b.flags[v] = flag
+	return flag
+}
+
+// jump ends the current block with a jump to target
+func (b *Builder) jump(target *Block, pos ast.Position) {
+	b.emit(&Instr{Op: Jump, Pos: pos})
+	addEdge(b.block, target)
+}
+
+// Program lowers the root single command into the main function
+func (b *Builder) Program(root ast.Command) (*Func, error) {
+	b.fn = &Func{Name: "main"}
+	b.block = b.fn.newBlock()
+	b.SingleCommand(root)
+	b.emit(&Instr{Op: Return, Pos: root.Position()})
+
+	if len(b.errors) > 0 {
+		return nil, b.errors[0]
+	}
+	if b.Guard {
+		b.fn.pruneGuards(b.flags)
+	}
+	return b.fn, nil
+}
+
+// SingleCommand lowers the single command constructs
+func (b *Builder) SingleCommand(node ast.Command) {
+	switch node := node.(type) {
+	case *ast.SkipCommand:
+		return
+	case *ast.BlockCommand:
+		{
+			for _, command := range node.Commands {
+				b.SingleCommand(command)
+			}
+		}
+	case *ast.IfCommand:
+		{
+			cond := b.Expression(node.Cond)
+			then, otherwise, end := b.fn.newBlock(), b.fn.newBlock(), b.fn.newBlock()
+			b.emit(&Instr{Op: Branch, Args: []Value{cond}, ArgPos: positions(node.Cond), Pos: node.Pos})
+			addEdge(b.block, then)
+			addEdge(b.block, otherwise)
+
+			b.block = then
+			b.SingleCommand(node.Then)
+			b.jump(end, node.Pos)
+
+			b.block = otherwise
+			b.SingleCommand(node.Else)
+			b.jump(end, node.Pos)
+			b.block = end
+		}
+	case *ast.WhileCommand:
+		{
+			header, body, end := b.fn.newBlock(), b.fn.newBlock(), b.fn.newBlock()
+			b.jump(header, node.Pos)
+
+			b.block = header
+			cond := b.Expression(node.Cond)
+			b.emit(&Instr{Op: Branch, Args: []Value{cond}, ArgPos: positions(node.Cond), Pos: node.Pos})
+			addEdge(b.block, body)
+			addEdge(b.block, end)
+
+			b.block = body
+			b.SingleCommand(node.Body)
+			b.jump(header, node.Pos)
+			b.block = end
+		}
+	case *ast.LetCommand:
+		{
+			b.Declaration(node.Decls)
+			b.SingleCommand(node.Body)
+		}
+	case *ast.AssignCommand:
+		{
+			sym := b.info.Uses[node.Name]
+			value := b.convert(node.Value, sym.Type)
+			v := b.variable(sym)
+			b.emit(&Instr{Op: Copy, Dst: v, Args: []Value{value}, ArgPos: positions(node.Value), Pos: node.Pos})
+			if flag, ok := b.flags[v]; ok {
+				b.emit(&Instr{Op: Copy, Dst: flag, Args: []Value{&Const{T: types.Boolean, Value: true}}, Pos: node.Pos})
+			}
+		}
+	case *ast.CallCommand:
+		b.call(node.Name, node.Args)
+	}
+}
+
+// Declaration assigns every single declaration, variables are declared
+// with the zero value of their type every time the let is entered. When
+// guarding, their flag is cleared as well.
+func (b *Builder) Declaration(decls []ast.Declaration) {
+	for _, decl := range decls {
+		sym := b.info.Defs[decl]
+		c, ok := decl.(*ast.ConstDecl)
+		if !ok {
+			v := b.variable(sym)
+			b.emit(&Instr{Op: Declare, Dst: v, Args: []Value{Zero(sym.Type)}, ArgPos: positions(decl), Pos: decl.Position()})
+			if b.Guard {
+				b.emit(&Instr{Op: Copy, Dst: b.flag(v), Args: []Value{&Const{T: types.Boolean, Value: false}}, Pos: decl.Position()})
+			}
+			continue
+		}
+		value := b.Expression(c.Value)
+		b.emit(&Instr{Op: Copy, Dst: b.variable(sym), Args: []Value{value}, ArgPos: positions(c.Value), Pos: c.Pos})
+	}
+}
+
+// call lowers a call, the result is nil for builtins returning nothing
+func (b *Builder) call(name *ast.Ident, args []ast.Expression) Value {
+	sym := b.info.Uses[name]
+	values := make([]Value, len(args))
+	nodes := make([]ast.Node, len(args))
+	for i, arg := range args {
+		values[i] = b.convert(arg, sym.Sig.Params[min(i, len(sym.Sig.Params)-1)])
+		nodes[i] = arg
+	}
+
+	instr := b.emit(&Instr{Op: Call, Args: values, Callee: sym, ArgPos: positions(nodes...), Pos: name.Pos})
+	if sym.Sig.Result == types.Void {
+		return nil
+	}
+	instr.Dst = b.temp(sym.Sig.Result)
+	return instr.Dst
+}
+
+// convert lowers an expression promoting integers stored in locations of
+// type Float
+func (b *Builder) convert(node ast.Expression, t types.Type) Value {
+	value := b.Expression(node)
+	if t == types.Float && value.Type() == types.Integer {
+		return b.promote(value, node.Position())
+	}
+	return value
+}
+
+func (b *Builder) promote(value Value, pos ast.Position) Value {
+	dst := b.temp(types.Float)
+	b.emit(&Instr{Op: Convert, Dst: dst, Args: []Value{value}, ArgPos: []ast.Position{pos}, Pos: pos})
+	return dst
+}
+
+// Expression lowers the expression constructs, operands are evaluated from
+// left to right and every operation gets a temporary.
+func (b *Builder) Expression(node ast.Expression) Value {
+	switch node := node.(type) {
+	case *ast.IntegerLit:
+		return &Const{T: types.Integer, Value: node.Value}
+	case *ast.FloatLit:
+		return &Const{T: types.Float, Value: node.Value}
+	case *ast.StringLit:
+		return &Const{T: types.String, Value: node.Value}
+	case *ast.ParenExpr:
+		return b.Expression(node.X)
+	case *ast.BinaryExpr:
+		return b.binary(node)
+	case *ast.CallExpr:
+		{
+			value := b.call(node.Name, node.Args)
+			if value == nil {
+				// the checker rejects programs using these calls as values
+				return &Const{T: types.Invalid}
+			}
+			return value
+		}
+	case *ast.Ident:
+		{
+			sym := b.info.Uses[node]
+			if sym.Value != nil {
+				return &Const{T: sym.Type, Value: sym.Value}
+			}
+			v := b.variable(sym)
+			if flag, ok := b.flags[v]; ok {
+				msg := &Const{T: types.String, Value: fmt.Sprintf("variable %s used before being assigned", sym.Name)}
+				b.emit(&Instr{Op: Assert, Args: []Value{flag, msg}, ArgPos: positions(node), Pos: node.Pos})
+			}
+			return v
+		}
+	}
+	b.errorf(node.Position(), "unknown expression")
+	return &Const{T: types.Invalid}
+}
+
+// binary lowers an operation, an integer operand is promoted when the other
+// one is a float
+func (b *Builder) binary(node *ast.BinaryExpr) Value {
+	value, pos := b.Expression(node.X), operandPos(node.X)
+	rhs, rhsPos := b.Expression(node.Y), operandPos(node.Y)
+
+	t, rt := value.Type(), rhs.Type()
+	if t != rt && t.IsNumeric() && rt.IsNumeric() {
+		if t == types.Integer {
+			value, t = b.promote(value, pos), types.Float
+		} else {
+			rhs = b.promote(rhs, rhsPos)
+		}
+	}
+
+	op := operators[node.Op]
+	if op.IsComparison() {
+		t = types.Boolean
+	}
+	dst := b.temp(t)
+	b.emit(&Instr{Op: op, Dst: dst, Args: []Value{value, rhs}, ArgPos: []ast.Position{pos, rhsPos}, Pos: node.OpPos})
+	return dst
+}
+
+// operandPos returns the position of the value of an operand, the result of
+// an operation is at its operator
+func operandPos(node ast.Expression) ast.Position {
+	if binary, ok := node.(*ast.BinaryExpr); ok {
+		return binary.OpPos
+	}
+	return node.Position()
+}
+
+// positions returns the positions of the nodes
+func positions(nodes ...ast.Node) []ast.Position {
+	pos := make([]ast.Position, len(nodes))
+	for i, node := range nodes {
+		pos[i] = node.Position()
+	}
+	return pos
+}
+
+// Zero returns the zero value of a type
+func Zero(t types.Type) *Const {
+	switch t {
+	case types.Integer:
+		return &Const{T: t, Value: 0}
+	case types.Float:
+		return &Const{T: t, Value: 0.0}
+	case types.String:
+		return &Const{T: t, Value: ""}
+	}
+	return &Const{T: t, Value: false}
+}
+
+var operators = map[ast.Operator]Op{
+	ast.Add:          Add,
+	ast.Sub:          Sub,
+	ast.Mul:          Mul,
+	ast.Div:          Div,
+	ast.Less:         Less,
+	ast.Greater:      Greater,
+	ast.LessEqual:    LessEqual,
+	ast.GreaterEqual: GreaterEqual,
+	ast.Equals:       Equal,
+	ast.Comparison:   Equal,
+}
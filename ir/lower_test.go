@@ -0,0 +1,83 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+func lower(t *testing.T, src string) *ir.Func {
+	t.Helper()
+	program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestShadowedNames(t *testing.T) {
+	fn := lower(t, `let var a : Integer; var a_2 : Integer; var v2_a : Integer in begin
+	a = 1; a_2 = 2; v2_a = 3;
+	let var a : Integer in a = 4
+end`)
+	want := []string{"v_a", "v_a_2", "v_v2_a", "v2_a"}
+	vars := fn.Vars()
+	if len(vars) != len(want) {
+		t.Fatalf("got %d variables, want %d\n%s", len(vars), len(want), fn)
+	}
+	for i, v := range vars {
+		if v.Ident() != want[i] {
+			t.Errorf("variable %s is written %s, want %s", v, v.Ident(), want[i])
+		}
+	}
+
+	fn.ToSSA()
+	seen := map[string]*ir.Var{}
+	for _, v := range fn.Vars() {
+		if other, ok := seen[v.Ident()]; ok && other != v {
+			t.Errorf("%s and %s are both written %s", other, v, v.Ident())
+		}
+		seen[v.Ident()] = v
+	}
+}
+
+func TestGuards(t *testing.T) {
+	tests := []struct {
+		src     string
+		asserts int
+	}{
+		{src: `let var x : Integer in println(x)`, asserts: 1},
+		{src: `let var x : Integer in begin x = 1; println(x) end`, asserts: 0},
+		{src: `let var x : Integer in begin if true then x = 1 else begin end; println(x) end`, asserts: 1},
+		{src: `let var x : Integer in begin if true then x = 1 else x = 2; println(x) end`, asserts: 0},
+		{src: `let var x : Integer in while true do begin println(x); x = 1 end`, asserts: 1},
+		{src: `let var x : Integer in while true do let var y : Integer in begin x = 1; y = x; println(y) end`, asserts: 0},
+	}
+	for _, test := range tests {
+		fn := lower(t, test.src)
+		asserts := 0
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if instr.Op == ir.Assert {
+					asserts++
+				}
+			}
+		}
+		if asserts != test.asserts {
+			t.Errorf("%s: %d asserts, want %d\n%s", test.src, asserts, test.asserts, fn)
+		}
+		for _, v := range fn.Vars() {
+			if asserts == 0 && strings.HasSuffix(v.Name, "?") {
+				t.Errorf("%s: flag %s is left without asserts\n%s", test.src, v, fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// The Pass structure is an optimization of functions in SSA form, Run
+// reports whether it changed the function
+type Pass struct {
+	Name string
+	Run  func(f *Func) bool
+}
+
+// Passes lists the optimizations run by Optimize in order
+var Passes = []Pass{
+	{Name: "fold", Run: Fold},
+	{Name: "propagate", Run: Propagate},
+	{Name: "branches", Run: SimplifyBranches},
+	{Name: "dead code", Run: EliminateDeadCode},
+	{Name: "merge", Run: MergeBlocks},
+}
+
+// Optimize converts f to SSA form and runs the passes until none of them
+// changes it. When trace isn't nil the function is written to it before
+// optimizing and after every pass that changed it.
+func (f *Func) Optimize(trace io.Writer) {
+	f.ToSSA()
+	if trace != nil {
+		fmt.Fprintf(trace, "; before optimizing\n%s", f)
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, pass := range Passes {
+			if !pass.Run(f) {
+				continue
+			}
+			changed = true
+			if trace != nil {
+				fmt.Fprintf(trace, "; after %s\n%s", pass.Name, f)
+			}
+		}
+	}
+}
+
+// pure lists the builtins without side effects that can't fail, calls to
+// them are evaluated when their arguments are constant
+var pure = map[string]bool{
+	"length":  true,
+	"concat":  true,
+	"toInt":   true,
+	"toFloat": true,
+	"abs":     true,
+	"sqrt":    true,
+	"pow":     true,
+}
+
+var prelude = stdlib.Prelude()
+
+// isPure reports whether removing the instruction when its result is unused
+// keeps the behaviour of the program
+func isPure(instr *Instr) bool {
+	switch {
+	case instr.Op == Call:
+		return instr.Callee.Kind == types.Func && pure[instr.Callee.Name] && prelude.Lookup(instr.Callee.Name) != nil
+	case instr.Op == Assert:
+		{
+			c, ok := instr.Args[0].(*Const)
+			return ok && c.Value == true
+		}
+	case instr.Op == Div:
+		{
+			// integer divisions fail on a zero divisor
+			c, ok := instr.Args[1].(*Const)
+			return instr.Args[1].Type() == types.Float || ok && c.Value != nil && c.Value != 0
+		}
+	}
+	return !instr.Op.IsTerminator()
+}
+
+// Fold replaces operations on constants by their result, integer divisions
+// by zero are kept so they fail at run time
+func Fold(f *Func) bool {
+	changed := false
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Dst == nil || instr.Op == Copy || instr.Op == Declare || instr.Op == Phi {
+				continue
+			}
+			args := make([]any, len(instr.Args))
+			constant := true
+			for i, arg := range instr.Args {
+				c, ok := arg.(*Const)
+				if !ok || c.Value == nil {
+					constant = false
+					break
+				}
+				args[i] = c.Value
+			}
+			if !constant {
+				continue
+			}
+
+			value, ok := evaluate(instr, args)
+			if !ok {
+				continue
+			}
+			instr.Op, instr.Args, instr.ArgPos, instr.Callee = Copy, []Value{&Const{T: instr.Dst.T, Value: value}}, nil, nil
+			changed = true
+		}
+	}
+	return changed
+}
+
+// evaluate computes the result of an instruction from constant arguments
+func evaluate(instr *Instr, args []any) (any, bool) {
+	switch {
+	case instr.Op == Convert:
+		return float64(args[0].(int)), true
+	case instr.Op == Call:
+		{
+			if !isPure(instr) {
+				return nil, false
+			}
+			value, err := prelude.Lookup(instr.Callee.Name).Impl(nil, args)
+			return value, err == nil
+		}
+	case instr.Op == Equal:
+		return args[0] == args[1], true
+	}
+
+	switch a := args[0].(type) {
+	case int:
+		{
+			b := args[1].(int)
+			switch instr.Op {
+			case Add:
+				return a + b, true
+			case Sub:
+				return a - b, true
+			case Mul:
+				return a * b, true
+			case Div:
+				{
+					if b == 0 {
+						return nil, false
+					}
+					return a / b, true
+				}
+			case Less:
+				return a < b, true
+			case Greater:
+				return a > b, true
+			case LessEqual:
+				return a <= b, true
+			case GreaterEqual:
+				return a >= b, true
+			}
+		}
+	case float64:
+		{
+			b := args[1].(float64)
+			switch instr.Op {
+			case Add:
+				return a + b, true
+			case Sub:
+				return a - b, true
+			case Mul:
+				return a * b, true
+			case Div:
+				return a / b, true
+			case Less:
+				return a < b, true
+			case Greater:
+				return a > b, true
+			case LessEqual:
+				return a <= b, true
+			case GreaterEqual:
+				return a >= b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Propagate replaces the uses of copies by their source, including phis
+// merging a single value, and removes them
+func Propagate(f *Func) bool {
+	replace := map[*Var]Value{}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr.Op {
+			case Copy, Declare:
+				replace[instr.Dst] = instr.Args[0]
+			case Phi:
+				if value := uniqueArg(instr); value != nil {
+					replace[instr.Dst] = value
+				}
+			}
+		}
+	}
+	if len(replace) == 0 {
+		return false
+	}
+
+	var resolve func(value Value) Value
+	resolve = func(value Value) Value {
+		if v, ok := value.(*Var); ok {
+			if src, ok := replace[v]; ok {
+				return resolve(src)
+			}
+		}
+		return value
+	}
+
+	for _, b := range f.Blocks {
+		instrs := b.Instrs[:0]
+		for _, instr := range b.Instrs {
+			if instr.Dst != nil {
+				if _, ok := replace[instr.Dst]; ok {
+					continue
+				}
+			}
+			for i, arg := range instr.Args {
+				instr.Args[i] = resolve(arg)
+			}
+			instrs = append(instrs, instr)
+		}
+		b.Instrs = instrs
+	}
+	return true
+}
+
+// uniqueArg returns the only value a phi merges besides itself, or nil
+func uniqueArg(phi *Instr) Value {
+	var unique Value
+	for _, arg := range phi.Args {
+		if arg == phi.Dst || unique != nil && sameValue(arg, unique) {
+			continue
+		}
+		if unique != nil {
+			return nil
+		}
+		unique = arg
+	}
+	return unique
+}
+
+func sameValue(a, b Value) bool {
+	if a == b {
+		return true
+	}
+	ca, ok := a.(*Const)
+	cb, ok2 := b.(*Const)
+	return ok && ok2 && ca.T == cb.T && ca.Value != nil && ca.Value == cb.Value
+}
+
+// SimplifyBranches turns branches on constants into jumps and removes the
+// blocks that become unreachable, like the bodies of if false and while
+// false
+func SimplifyBranches(f *Func) bool {
+	changed := false
+	for _, b := range f.Blocks {
+		term := b.Terminator()
+		if term == nil || term.Op != Branch {
+			continue
+		}
+		c, ok := term.Args[0].(*Const)
+		if !ok || c.Value == nil {
+			continue
+		}
+
+		taken, dropped := b.Succs[0], b.Succs[1]
+		if !c.Value.(bool) {
+			taken, dropped = dropped, taken
+		}
+		dropped.removePred(b)
+		term.Op, term.Args = Jump, nil
+		b.Succs = []*Block{taken}
+		changed = true
+	}
+	if changed {
+		f.RemoveUnreachable()
+		f.Dominators()
+	}
+	return changed
+}
+
+// EliminateDeadCode removes the instructions whose result never reaches a
+// side effect, assignments of values that are never read included
+func EliminateDeadCode(f *Func) bool {
+	defs := map[*Var]*Instr{}
+	live := map[*Instr]bool{}
+	work := []*Instr{}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Dst != nil {
+				defs[instr.Dst] = instr
+			}
+			if !isPure(instr) {
+				live[instr] = true
+				work = append(work, instr)
+			}
+		}
+	}
+	for len(work) > 0 {
+		instr := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, arg := range instr.Args {
+			v, ok := arg.(*Var)
+			if !ok {
+				continue
+			}
+			if def, ok := defs[v]; ok && !live[def] {
+				live[def] = true
+				work = append(work, def)
+			}
+		}
+	}
+
+	changed := false
+	for _, b := range f.Blocks {
+		instrs := b.Instrs[:0]
+		for _, instr := range b.Instrs {
+			if !live[instr] {
+				changed = true
+				continue
+			}
+			instrs = append(instrs, instr)
+		}
+		b.Instrs = instrs
+	}
+	return changed
+}
+
+// MergeBlocks appends every block to its predecessor when it is the only
+// successor of a predecessor it only has
+func MergeBlocks(f *Func) bool {
+	changed := false
+	for _, b := range f.Blocks {
+		for {
+			term := b.Terminator()
+			if term == nil || term.Op != Jump {
+				break
+			}
+			s := b.Succs[0]
+			if len(s.Preds) != 1 || s == b || s == f.Blocks[0] {
+				break
+			}
+
+			b.Instrs = b.Instrs[:len(b.Instrs)-1]
+			for _, instr := range s.Instrs {
+				if instr.Op == Phi {
+					instr.Op = Copy
+				}
+				b.Instrs = append(b.Instrs, instr)
+			}
+			b.Succs = s.Succs
+			for _, succ := range s.Succs {
+				for i, pred := range succ.Preds {
+					if pred == s {
+						succ.Preds[i] = b
+					}
+				}
+			}
+			s.Instrs, s.Preds, s.Succs = nil, nil, nil
+			changed = true
+		}
+	}
+	if changed {
+		f.RemoveUnreachable()
+		f.Dominators()
+	}
+	return changed
+}
@@ -0,0 +1,249 @@
+package ir
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Liveness returns the variables of the source whose value may be read
+// after the start and the end of every block. It must run before the
+// function is converted to SSA form.
+func (f *Func) Liveness() (in, out map[*Block]map[*Var]bool) {
+	uses := map[*Block]map[*Var]bool{}
+	defs := map[*Block]map[*Var]bool{}
+	in = map[*Block]map[*Var]bool{}
+	out = map[*Block]map[*Var]bool{}
+	for _, b := range f.Blocks {
+		uses[b], defs[b] = map[*Var]bool{}, map[*Var]bool{}
+		in[b], out[b] = map[*Var]bool{}, map[*Var]bool{}
+		for _, instr := range b.Instrs {
+			for _, arg := range instr.Args {
+				if v, ok := arg.(*Var); ok && v.Sym != nil && !defs[b][v] {
+					uses[b][v] = true
+				}
+			}
+			if instr.Dst != nil && instr.Dst.Sym != nil {
+				defs[b][instr.Dst] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.Blocks) - 1; i >= 0; i-- {
+			b := f.Blocks[i]
+			for _, succ := range b.Succs {
+				for v := range in[succ] {
+					out[b][v] = true
+				}
+			}
+			for v := range out[b] {
+				if !defs[b][v] && !in[b][v] {
+					in[b][v], changed = true, true
+				}
+			}
+			for v := range uses[b] {
+				if !in[b][v] {
+					in[b][v], changed = true, true
+				}
+			}
+		}
+	}
+	return in, out
+}
+
+// ToSSA converts the function to SSA form, phis are only placed where the
+// variable is live so their number stays close to the minimum. Reads no
+// assignment reaches become undefined constants.
+func (f *Func) ToSSA() {
+	if f.SSA {
+		return
+	}
+	f.RemoveUnreachable()
+	f.Dominators()
+	frontiers := f.DominanceFrontiers()
+	liveIn, _ := f.Liveness()
+
+	defBlocks := map[*Var][]*Block{}
+	vars := []*Var{}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			v := instr.Dst
+			if v == nil || v.Sym == nil {
+				continue
+			}
+			if _, ok := defBlocks[v]; !ok {
+				vars = append(vars, v)
+			}
+			if !contains(defBlocks[v], b) {
+				defBlocks[v] = append(defBlocks[v], b)
+			}
+		}
+	}
+
+	for _, v := range vars {
+		placed := map[*Block]bool{}
+		work := append([]*Block{}, defBlocks[v]...)
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, d := range frontiers[b] {
+				if placed[d] || !liveIn[d][v] {
+					continue
+				}
+				placed[d] = true
+				phi := &Instr{Op: Phi, Dst: v, Args: make([]Value, len(d.Preds))}
+				d.Instrs = append([]*Instr{phi}, d.Instrs...)
+				work = append(work, d)
+			}
+		}
+	}
+
+	children := map[*Block][]*Block{}
+	for _, b := range f.Blocks[1:] {
+		children[b.Idom] = append(children[b.Idom], b)
+	}
+	r := renamer{
+		stacks:   map[*Var][]Value{},
+		versions: map[*Var]int{},
+		origins:  map[*Instr]*Var{},
+		children: children,
+	}
+	r.rename(f.Blocks[0])
+	f.SSA = true
+}
+
+type renamer struct {
+	stacks   map[*Var][]Value
+	versions map[*Var]int
+	// origins maps every phi to the variable it was placed for
+	origins  map[*Instr]*Var
+	children map[*Block][]*Block
+}
+
+func (r *renamer) current(v *Var) Value {
+	stack := r.stacks[v]
+	if len(stack) == 0 {
+		return &Const{T: v.T}
+	}
+	return stack[len(stack)-1]
+}
+
+// rename gives a new version to every assignment in b and the blocks it
+// dominates, replacing reads with the version reaching them
+func (r *renamer) rename(b *Block) {
+	pushed := []*Var{}
+	for _, instr := range b.Instrs {
+		if instr.Op != Phi {
+			for i, arg := range instr.Args {
+				if v, ok := arg.(*Var); ok && v.Sym != nil {
+					instr.Args[i] = r.current(v)
+				}
+			}
+		}
+		v := instr.Dst
+		if v == nil || v.Sym == nil {
+			continue
+		}
+		if instr.Op == Phi {
+			r.origins[instr] = v
+		}
+		r.versions[v]++
+		instr.Dst = &Var{Name: v.Name, T: v.T, Sym: v.Sym, Version: r.versions[v]}
+		r.stacks[v] = append(r.stacks[v], instr.Dst)
+		pushed = append(pushed, v)
+	}
+
+	for _, succ := range b.Succs {
+		for i, pred := range succ.Preds {
+			if pred != b {
+				continue
+			}
+			for _, instr := range succ.Instrs {
+				if instr.Op != Phi {
+					break
+				}
+				origin, ok := r.origins[instr]
+				if !ok {
+					// the phi hasn't been renamed yet, it still holds its variable
+					origin = instr.Dst
+				}
+				instr.Args[i] = r.current(origin)
+			}
+		}
+	}
+
+	for _, child := range r.children[b] {
+		r.rename(child)
+	}
+	for _, v := range pushed {
+		r.stacks[v] = r.stacks[v][:len(r.stacks[v])-1]
+	}
+}
+
+// FromSSA takes the function out of SSA form so the targets that don't read
+// phis can translate it. Every phi gets a variable of its own, assigned in
+// each predecessor right before the terminator and copied to the phi at the
+// start of the block, so phis reading each other or values the copies
+// replace still see the values of the edge. Versions become variables named
+// after them.
+func (f *Func) FromSSA() {
+	if !f.SSA {
+		return
+	}
+	vars := map[*Var]*Var{}
+	plain := func(value Value) Value {
+		v, ok := value.(*Var)
+		if !ok || v.Version == 0 {
+			return value
+		}
+		if _, ok := vars[v]; !ok {
+			vars[v] = renamed(v, "r"+strconv.Itoa(v.Version))
+		}
+		return vars[v]
+	}
+
+	phis := 0
+	for _, b := range f.Blocks {
+		copies := []*Instr{}
+		for len(b.Instrs) > 0 && b.Instrs[0].Op == Phi {
+			phi := b.Instrs[0]
+			b.Instrs = b.Instrs[1:]
+			phis++
+			edge := renamed(phi.Dst, "p"+strconv.Itoa(phis))
+			for i, pred := range b.Preds {
+				last := len(pred.Instrs) - 1
+				move := &Instr{Op: Copy, Dst: edge, Args: []Value{phi.Args[i]}, Pos: phi.Pos}
+				pred.Instrs = append(pred.Instrs[:last], move, pred.Instrs[last])
+			}
+			copies = append(copies, &Instr{Op: Copy, Dst: phi.Dst, Args: []Value{edge}, Pos: phi.Pos})
+		}
+		b.Instrs = append(copies, b.Instrs...)
+	}
+
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			for i, arg := range instr.Args {
+				instr.Args[i] = plain(arg)
+			}
+			if instr.Dst != nil {
+				instr.Dst = plain(instr.Dst).(*Var)
+			}
+		}
+	}
+	f.SSA = false
+}
+
+// renamed returns a variable of the source standing for v, its name is
+// numbered after the # with the suffix, which no shadowing count holds
+func renamed(v *Var, suffix string) *Var {
+	name, flag := strings.CutSuffix(v.Name, "?")
+	if !strings.Contains(name, "#") {
+		name += "#"
+	}
+	name += suffix
+	if flag {
+		name += "?"
+	}
+	return &Var{Name: name, T: v.T, Sym: v.Sym}
+}
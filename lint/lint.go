@@ -0,0 +1,392 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+type Severity int8
+
+const (
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+var SeverityNames = map[Severity]string{
+	Off:     "off",
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+func (s Severity) String() string {
+	return SeverityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range SeverityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// The Rule structure is a check run over the tree of a file, Run reports
+// what it finds through the pass
+type Rule struct {
+	ID       string
+	Doc      string
+	Severity Severity
+	Run      func(pass *Pass)
+}
+
+// The Finding structure is a problem a rule found
+type Finding struct {
+	Pos      ast.Position
+	Rule     string
+	Severity Severity
+	Msg      string
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", f.Pos, f.Severity, f.Rule, f.Msg)
+}
+
+var diagSeverities = map[Severity]diag.Severity{
+	Info:    diag.Info,
+	Warning: diag.Warning,
+	Error:   diag.Error,
+}
+
+// Diagnostic describes the finding with the ID of its rule as code
+func (f *Finding) Diagnostic() *diag.Diagnostic {
+	return &diag.Diagnostic{
+		Severity: diagSeverities[f.Severity],
+		Code:     f.Rule,
+		Msg:      f.Msg,
+		Primary:  diag.Span{Pos: f.Pos},
+	}
+}
+
+// The Pass structure gives a rule the file being linted and collects its
+// findings
+type Pass struct {
+	Rule *Rule
+	// Root is the program or module being linted
+	Root *ast.Program
+	// Options holds the settings of the rule from the configuration
+	Options  map[string]string
+	severity Severity
+	bindings map[*ast.Ident]ast.Declaration
+	findings []*Finding
+}
+
+func (p *Pass) Reportf(pos ast.Position, format string, args ...any) {
+	p.findings = append(p.findings, &Finding{
+		Pos:      pos,
+		Rule:     p.Rule.ID,
+		Severity: p.severity,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// Declaration returns the single declaration an identifier refers to, or nil
+// for predeclared and undefined names
+func (p *Pass) Declaration(ident *ast.Ident) ast.Declaration {
+	return p.bindings[ident]
+}
+
+// Rules lists the rules every linter starts with
+var Rules = []*Rule{
+	Unreachable,
+	ConstantCondition,
+	Shadow,
+	NonBooleanCondition,
+	Naming,
+}
+
+// ConfigFile is the name of the project file holding the configuration
+const ConfigFile = "alpha.json"
+
+// The Config structure is the "lint" section of the project file
+type Config struct {
+	// Rules overrides the severity of rules by their ID, off disables them
+	Rules map[string]Severity `json:"rules,omitempty"`
+	// Options holds the settings of rules by their ID
+	Options map[string]map[string]string `json:"options,omitempty"`
+}
+
+// LoadConfig reads the lint configuration of a project file
+func LoadConfig(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var project struct {
+		Lint Config `json:"lint"`
+	}
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &project.Lint, nil
+}
+
+// FindConfig looks for the project file in dir and its parents, it returns
+// an empty name when there is none
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		name := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// The Linter structure runs rules over files, the severity of each rule can
+// be changed by the configuration
+type Linter struct {
+	Rules  []*Rule
+	Config *Config
+}
+
+// NewLinter returns a linter running the default rules, config may be nil
+func NewLinter(config *Config) *Linter {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Linter{
+		Rules:  append([]*Rule{}, Rules...),
+		Config: config,
+	}
+}
+
+// File lints the named file
+func (l *Linter) File(name string) ([]*Finding, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.Source(path.Base(name), string(data))
+}
+
+// Source lints src as if it had been read from the named file, findings
+// suppressed by comments are left out and the rest is sorted by position.
+func (l *Linter) Source(name, src string) ([]*Finding, error) {
+	known := map[string]bool{}
+	for _, rule := range l.Rules {
+		known[rule.ID] = true
+	}
+	for id := range l.Config.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown lint rule %s", id)
+		}
+	}
+
+	p, err := parser.NewParser(tokenizer.FromString(name, src))
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.Program()
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := resolve(root)
+	ignored := suppressions(src)
+	findings := []*Finding{}
+	for _, rule := range l.Rules {
+		severity, ok := l.Config.Rules[rule.ID]
+		if !ok {
+			severity = rule.Severity
+		}
+		if severity == Off {
+			continue
+		}
+
+		pass := &Pass{
+			Rule:     rule,
+			Root:     root,
+			Options:  l.Config.Options[rule.ID],
+			severity: severity,
+			bindings: bindings,
+		}
+		rule.Run(pass)
+		for _, finding := range pass.findings {
+			if !ignored[finding.Pos.Row][rule.ID] {
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	return findings, nil
+}
+
+var ignoreComment = regexp.MustCompile(`//\s*alpha:ignore\s+(.*)$`)
+
+// suppressions returns the rules ignored on every line. A comment after code
+// applies to its own line, a comment alone on its line applies to the next.
+//
+//	// alpha:ignore RULE[, RULE]...
+func suppressions(src string) map[int]map[string]bool {
+	ignored := map[int]map[string]bool{}
+	for i, line := range strings.Split(src, "\n") {
+		match := ignoreComment.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		row := i + 1
+		if strings.TrimSpace(line[:match[0]]) == "" {
+			row++
+		}
+		if ignored[row] == nil {
+			ignored[row] = map[string]bool{}
+		}
+		ids := strings.FieldsFunc(line[match[2]:match[3]], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		for _, id := range ids {
+			ignored[row][id] = true
+		}
+	}
+	return ignored
+}
+
+// resolve binds the identifiers of a file to the single declaration they
+// refer to, following the scoping of the checker
+func resolve(root *ast.Program) map[*ast.Ident]ast.Declaration {
+	bindings := map[*ast.Ident]ast.Declaration{}
+	scopes := []map[string]ast.Declaration{{}}
+
+	lookup := func(ident *ast.Ident) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if decl, ok := scopes[i][ident.Name]; ok {
+				bindings[ident] = decl
+				return
+			}
+		}
+	}
+
+	var declaration func(decls []ast.Declaration)
+	var expression func(node ast.Expression)
+	var command func(node ast.Command)
+	declaration = func(decls []ast.Declaration) {
+		for _, decl := range decls {
+			if export, ok := decl.(*ast.ExportDecl); ok {
+				decl = export.Decl
+			}
+			switch decl := decl.(type) {
+			case *ast.ConstDecl:
+				{
+					expression(decl.Value)
+					scopes[len(scopes)-1][decl.Name.Name] = decl
+				}
+			case *ast.VarDecl:
+				scopes[len(scopes)-1][decl.Name.Name] = decl
+			}
+		}
+	}
+	expression = func(node ast.Expression) {
+		switch node := node.(type) {
+		case *ast.Ident:
+			lookup(node)
+		case *ast.ParenExpr:
+			expression(node.X)
+		case *ast.BinaryExpr:
+			{
+				expression(node.X)
+				expression(node.Y)
+			}
+		case *ast.CallExpr:
+			{
+				lookup(node.Name)
+				for _, arg := range node.Args {
+					expression(arg)
+				}
+			}
+		}
+	}
+	command = func(node ast.Command) {
+		switch node := node.(type) {
+		case *ast.BlockCommand:
+			for _, child := range node.Commands {
+				command(child)
+			}
+		case *ast.IfCommand:
+			{
+				expression(node.Cond)
+				command(node.Then)
+				command(node.Else)
+			}
+		case *ast.WhileCommand:
+			{
+				expression(node.Cond)
+				command(node.Body)
+			}
+		case *ast.LetCommand:
+			{
+				scopes = append(scopes, map[string]ast.Declaration{})
+				declaration(node.Decls)
+				command(node.Body)
+				scopes = scopes[:len(scopes)-1]
+			}
+		case *ast.AssignCommand:
+			{
+				lookup(node.Name)
+				expression(node.Value)
+			}
+		case *ast.CallCommand:
+			{
+				lookup(node.Name)
+				for _, arg := range node.Args {
+					expression(arg)
+				}
+			}
+		}
+	}
+
+	if root.Module != nil {
+		declaration(root.Module.Decls)
+	} else {
+		command(root.Body)
+	}
+	return bindings
+}
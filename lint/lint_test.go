@@ -0,0 +1,172 @@
+package lint_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/lint"
+)
+
+// run lints src with the given configuration and returns its findings, one
+// per line
+func run(t *testing.T, config *lint.Config, src string) string {
+	t.Helper()
+	findings, err := lint.NewLinter(config).Source("test.alpha", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	for _, finding := range findings {
+		sb.WriteString(finding.String() + "\n")
+	}
+	return sb.String()
+}
+
+// only returns a configuration turning off every rule but one
+func only(id string) *lint.Config {
+	config := &lint.Config{Rules: map[string]lint.Severity{}}
+	for _, rule := range lint.Rules {
+		if rule.ID != id {
+			config.Rules[rule.ID] = lint.Off
+		}
+	}
+	return config
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		src  string
+		want string
+	}{
+		{
+			rule: "unreachable",
+			src:  "begin exit(1); println(1) end",
+			want: "test.alpha:1:16: warning unreachable: unreachable code\n",
+		},
+		{
+			rule: "unreachable",
+			src:  "begin while 1 < 2 do println(1); println(2) end",
+			want: "test.alpha:1:34: warning unreachable: unreachable code\n",
+		},
+		{
+			rule: "unreachable",
+			src:  "if false then println(1) else println(2)",
+			want: "test.alpha:1:15: warning unreachable: unreachable code, the condition is always false\n",
+		},
+		{
+			rule: "unreachable",
+			src:  "let var x : Integer in begin x = readInt(); if x < 2 then exit(1) else println(x); println(x) end",
+		},
+		{
+			rule: "constant-condition",
+			src:  "if (1 + 1) == 2 then println(1) else println(2)",
+			want: "test.alpha:1:4: warning constant-condition: the condition is always true\n",
+		},
+		{
+			rule: "constant-condition",
+			src:  "while true do println(1)",
+			want: "test.alpha:1:7: warning constant-condition: the loop never ends, its condition is always true and its body never calls exit\n",
+		},
+		{
+			rule: "constant-condition",
+			src:  "while true do begin println(1); exit(0) end",
+		},
+		{
+			rule: "constant-condition",
+			src:  "let const true ~ 1 < 2 in if true then println(1) else println(2)",
+		},
+		{
+			rule: "shadow",
+			src:  "let var x : Integer in let var x : String in println(x)",
+			want: "test.alpha:1:32: warning shadow: declaration of x shadows the declaration at test.alpha:1:9\n",
+		},
+		{
+			rule: "shadow",
+			src:  "begin let var x : Integer in println(x); let var x : String in println(x) end",
+		},
+		{
+			rule: "non-boolean-condition",
+			src:  "let var n : Integer in while n + 1.5 do println(n)",
+			want: "test.alpha:1:30: error non-boolean-condition: the condition is of type Float, not Boolean\n",
+		},
+		{
+			rule: "non-boolean-condition",
+			src:  "if f(1) then println(1) else println(2)",
+		},
+		{
+			rule: "naming",
+			src:  "let const Max ~ 3; var my_count : Integer in println(Max, my_count)",
+			want: "test.alpha:1:11: info naming: constant name Max doesn't match ^[a-z][a-zA-Z0-9]*$\n" +
+				"test.alpha:1:24: info naming: variable name my_count doesn't match ^[a-z][a-zA-Z0-9]*$\n",
+		},
+		{
+			rule: "naming",
+			src:  "let const maxCount ~ 3 in println(maxCount)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.rule+": "+test.src, func(t *testing.T) {
+			if got := run(t, only(test.rule), test.src); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestConfig lints a file with the project file of a parent directory,
+// which changes the severity of rules, turns one off and sets the pattern
+// of constant names
+func TestConfig(t *testing.T) {
+	dir := filepath.Join("testdata", "project", "sub")
+	name, err := lint.FindConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.Abs(filepath.Join("testdata", "project", lint.ConfigFile)); name != want {
+		t.Fatalf("found %s, want %s", name, want)
+	}
+	config, err := lint.LoadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := lint.NewLinter(config).File(filepath.Join(dir, "main.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	for _, finding := range findings {
+		got.WriteString(finding.String() + "\n")
+	}
+	want := "main.alpha:2:11: warning naming: constant name limit doesn't match ^[A-Z][A-Z0-9_]*$\n" +
+		"main.alpha:6:8: error constant-condition: the condition is always true\n" +
+		"main.alpha:6:39: warning unreachable: unreachable code, the condition is always true\n" +
+		"main.alpha:11:5: warning unreachable: unreachable code\n"
+	if got.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want)
+	}
+}
+
+func TestUnknownRule(t *testing.T) {
+	config := &lint.Config{Rules: map[string]lint.Severity{"shadows": lint.Off}}
+	if _, err := lint.NewLinter(config).Source("test.alpha", "println(1)"); err == nil || err.Error() != "unknown lint rule shadows" {
+		t.Errorf("got %v, want an unknown rule", err)
+	}
+}
+
+// TestIgnore checks the comments suppressing findings, on the line of the
+// finding or alone on the line before it
+func TestIgnore(t *testing.T) {
+	src := `let var Outer : Integer in begin
+    let var Outer : String in println(Outer); // alpha:ignore shadow, naming
+    // alpha:ignore naming
+    let var Inner : Integer in println(Inner);
+    let var Last : Integer in println(Last) // alpha:ignore shadow
+end`
+	want := "test.alpha:1:9: info naming: variable name Outer doesn't match ^[a-z][a-zA-Z0-9]*$\n" +
+		"test.alpha:5:13: info naming: variable name Last doesn't match ^[a-z][a-zA-Z0-9]*$\n"
+	if got := run(t, nil, src); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,441 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+var Unreachable = &Rule{
+	ID:       "unreachable",
+	Doc:      "commands that can never run, after a call to exit or an endless loop and in branches a constant condition never takes",
+	Severity: Warning,
+	Run: func(pass *Pass) {
+		ast.Inspect(pass.Root, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.BlockCommand:
+				{
+					for i, command := range node.Commands {
+						if !terminates(pass, command) {
+							continue
+						}
+						for _, next := range node.Commands[i+1:] {
+							if _, ok := next.(*ast.SkipCommand); !ok {
+								pass.Reportf(next.Position(), "unreachable code")
+								break
+							}
+						}
+						break
+					}
+				}
+			case *ast.IfCommand, *ast.WhileCommand:
+				{
+					value, ok := condition(pass, node)
+					var dead ast.Command
+					switch node := node.(type) {
+					case *ast.IfCommand:
+						{
+							dead = node.Then
+							if value {
+								dead = node.Else
+							}
+						}
+					case *ast.WhileCommand:
+						if !value {
+							dead = node.Body
+						}
+					}
+					if _, skip := dead.(*ast.SkipCommand); ok && dead != nil && !skip {
+						pass.Reportf(dead.Position(), "unreachable code, the condition is always %t", value)
+					}
+				}
+			}
+			return true
+		})
+	},
+}
+
+var ConstantCondition = &Rule{
+	ID:       "constant-condition",
+	Doc:      "conditions that are always true or always false, loops on true are allowed when their body calls exit",
+	Severity: Warning,
+	Run: func(pass *Pass) {
+		ast.Inspect(pass.Root, func(node ast.Node) bool {
+			value, ok := condition(pass, node)
+			if !ok {
+				return true
+			}
+			if while, ok := node.(*ast.WhileCommand); ok && value {
+				if !callsExit(pass, while.Body) {
+					pass.Reportf(while.Cond.Position(), "the loop never ends, its condition is always true and its body never calls exit")
+				}
+				return true
+			}
+			pass.Reportf(cond(node).Position(), "the condition is always %t", value)
+			return true
+		})
+	},
+}
+
+var Shadow = &Rule{
+	ID:       "shadow",
+	Doc:      "declarations of a let hiding a declaration of an enclosing let or of the module",
+	Severity: Warning,
+	Run: func(pass *Pass) {
+		scopes := []map[string]*ast.Ident{{}}
+		declare := func(decl ast.Declaration) {
+			name := declName(decl)
+			if name == nil {
+				return
+			}
+			for _, scope := range scopes[:len(scopes)-1] {
+				if prev, ok := scope[name.Name]; ok {
+					pass.Reportf(name.Pos, "declaration of %s shadows the declaration at %s", name.Name, prev.Pos)
+					break
+				}
+			}
+			scopes[len(scopes)-1][name.Name] = name
+		}
+
+		var command func(node ast.Command)
+		command = func(node ast.Command) {
+			switch node := node.(type) {
+			case *ast.BlockCommand:
+				for _, child := range node.Commands {
+					command(child)
+				}
+			case *ast.IfCommand:
+				{
+					command(node.Then)
+					command(node.Else)
+				}
+			case *ast.WhileCommand:
+				command(node.Body)
+			case *ast.LetCommand:
+				{
+					scopes = append(scopes, map[string]*ast.Ident{})
+					for _, decl := range node.Decls {
+						declare(decl)
+					}
+					command(node.Body)
+					scopes = scopes[:len(scopes)-1]
+				}
+			}
+		}
+
+		if pass.Root.Module != nil {
+			for _, decl := range pass.Root.Module.Decls {
+				declare(decl)
+			}
+			return
+		}
+		command(pass.Root.Body)
+	},
+}
+
+var NonBooleanCondition = &Rule{
+	ID:       "non-boolean-condition",
+	Doc:      "conditions of if and while commands whose type is known not to be Boolean",
+	Severity: Error,
+	Run: func(pass *Pass) {
+		ast.Inspect(pass.Root, func(node ast.Node) bool {
+			cond := cond(node)
+			if cond == nil {
+				return true
+			}
+			if t := typeOf(pass, cond); t != "" && t != "Boolean" {
+				pass.Reportf(cond.Position(), "the condition is of type %s, not Boolean", t)
+			}
+			return true
+		})
+	},
+}
+
+var Naming = &Rule{
+	ID:       "naming",
+	Doc:      "names of constants and variables not matching the patterns of the const and var options, lower camel case by default",
+	Severity: Info,
+	Run: func(pass *Pass) {
+		patterns := map[string]*regexp.Regexp{}
+		for _, kind := range []string{"const", "var"} {
+			expr := `^[a-z][a-zA-Z0-9]*$`
+			if option, ok := pass.Options[kind]; ok {
+				expr = option
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				pass.Reportf(pass.Root.Pos, "invalid %s pattern: %s", kind, err)
+				return
+			}
+			patterns[kind] = re
+		}
+
+		ast.Inspect(pass.Root, func(node ast.Node) bool {
+			var kind, what string
+			switch node.(type) {
+			case *ast.ConstDecl:
+				kind, what = "const", "constant"
+			case *ast.VarDecl:
+				kind, what = "var", "variable"
+			default:
+				return true
+			}
+			name := declName(node.(ast.Declaration))
+			if re := patterns[kind]; !re.MatchString(name.Name) {
+				pass.Reportf(name.Pos, "%s name %s doesn't match %s", what, name.Name, re)
+			}
+			return true
+		})
+	},
+}
+
+// declName returns the name a single declaration declares
+func declName(decl ast.Declaration) *ast.Ident {
+	switch decl := decl.(type) {
+	case *ast.ConstDecl:
+		return decl.Name
+	case *ast.VarDecl:
+		return decl.Name
+	case *ast.ExportDecl:
+		return declName(decl.Decl)
+	}
+	return nil
+}
+
+// cond returns the condition of an if or while command, or nil
+func cond(node ast.Node) ast.Expression {
+	switch node := node.(type) {
+	case *ast.IfCommand:
+		return node.Cond
+	case *ast.WhileCommand:
+		return node.Cond
+	}
+	return nil
+}
+
+// isExit reports whether a command calls the predeclared exit
+func isExit(pass *Pass, node ast.Command) bool {
+	call, ok := node.(*ast.CallCommand)
+	return ok && call.Name.Name == "exit" && pass.Declaration(call.Name) == nil
+}
+
+// callsExit reports whether some command below node calls exit
+func callsExit(pass *Pass, node ast.Command) bool {
+	found := false
+	ast.Inspect(node, func(node ast.Node) bool {
+		if command, ok := node.(ast.Command); ok && isExit(pass, command) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// terminates reports whether a single command never lets the commands after
+// it run, either by calling exit or by looping forever
+func terminates(pass *Pass, node ast.Command) bool {
+	switch node := node.(type) {
+	case *ast.BlockCommand:
+		{
+			for _, command := range node.Commands {
+				if terminates(pass, command) {
+					return true
+				}
+			}
+		}
+	case *ast.IfCommand:
+		{
+			if value, ok := condition(pass, node); ok {
+				if value {
+					return terminates(pass, node.Then)
+				}
+				return terminates(pass, node.Else)
+			}
+			return terminates(pass, node.Then) && terminates(pass, node.Else)
+		}
+	case *ast.WhileCommand:
+		{
+			value, ok := condition(pass, node)
+			return ok && value
+		}
+	case *ast.LetCommand:
+		return terminates(pass, node.Body)
+	case *ast.CallCommand:
+		return isExit(pass, node)
+	}
+	return false
+}
+
+// condition returns the value of the condition of an if or while command
+// when it is constant
+func condition(pass *Pass, node ast.Node) (bool, bool) {
+	cond := cond(node)
+	if cond == nil {
+		return false, false
+	}
+	value, ok := constant(pass, cond).(bool)
+	return value, ok
+}
+
+// constant evaluates expressions made of literals, true and false, it
+// returns nil for anything else
+func constant(pass *Pass, node ast.Expression) any {
+	switch node := node.(type) {
+	case *ast.IntegerLit:
+		return node.Value
+	case *ast.FloatLit:
+		return node.Value
+	case *ast.StringLit:
+		return node.Value
+	case *ast.Ident:
+		{
+			if pass.Declaration(node) != nil {
+				return nil
+			}
+			switch node.Name {
+			case "true":
+				return true
+			case "false":
+				return false
+			}
+			return nil
+		}
+	case *ast.ParenExpr:
+		return constant(pass, node.X)
+	case *ast.BinaryExpr:
+		{
+			lhs, rhs := constant(pass, node.X), constant(pass, node.Y)
+			if lhs == nil || rhs == nil {
+				return nil
+			}
+			return binary(node.Op, lhs, rhs)
+		}
+	}
+	return nil
+}
+
+// binary applies an operator to constants, integers are promoted when mixed
+// with floats
+func binary(op ast.Operator, lhs, rhs any) any {
+	if a, ok := lhs.(int); ok {
+		if _, ok := rhs.(float64); ok {
+			lhs = float64(a)
+		}
+	}
+	if b, ok := rhs.(int); ok {
+		if _, ok := lhs.(float64); ok {
+			rhs = float64(b)
+		}
+	}
+	if op == ast.Equals || op == ast.Comparison {
+		if fmt.Sprintf("%T", lhs) != fmt.Sprintf("%T", rhs) {
+			return nil
+		}
+		return lhs == rhs
+	}
+
+	switch a := lhs.(type) {
+	case int:
+		{
+			b, ok := rhs.(int)
+			if !ok {
+				return nil
+			}
+			switch op {
+			case ast.Add:
+				return a + b
+			case ast.Sub:
+				return a - b
+			case ast.Mul:
+				return a * b
+			case ast.Div:
+				{
+					if b == 0 {
+						return nil
+					}
+					return a / b
+				}
+			case ast.Less:
+				return a < b
+			case ast.Greater:
+				return a > b
+			case ast.LessEqual:
+				return a <= b
+			case ast.GreaterEqual:
+				return a >= b
+			}
+		}
+	case float64:
+		{
+			b, ok := rhs.(float64)
+			if !ok {
+				return nil
+			}
+			switch op {
+			case ast.Add:
+				return a + b
+			case ast.Sub:
+				return a - b
+			case ast.Mul:
+				return a * b
+			case ast.Div:
+				return a / b
+			case ast.Less:
+				return a < b
+			case ast.Greater:
+				return a > b
+			case ast.LessEqual:
+				return a <= b
+			case ast.GreaterEqual:
+				return a >= b
+			}
+		}
+	}
+	return nil
+}
+
+// typeOf returns the name of the type of an expression when it can be told
+// without checking the program, or an empty string
+func typeOf(pass *Pass, node ast.Expression) string {
+	switch node := node.(type) {
+	case *ast.IntegerLit:
+		return "Integer"
+	case *ast.FloatLit:
+		return "Float"
+	case *ast.StringLit:
+		return "String"
+	case *ast.Ident:
+		{
+			switch decl := pass.Declaration(node).(type) {
+			case *ast.VarDecl:
+				return decl.Type.Name
+			case *ast.ConstDecl:
+				return typeOf(pass, decl.Value)
+			}
+			if node.Name == "true" || node.Name == "false" {
+				return "Boolean"
+			}
+			return ""
+		}
+	case *ast.ParenExpr:
+		return typeOf(pass, node.X)
+	case *ast.BinaryExpr:
+		{
+			if !node.Op.IsArithmetic() {
+				return "Boolean"
+			}
+			// the operands must be numeric, any Float makes the result a
+			// Float
+			lhs, rhs := typeOf(pass, node.X), typeOf(pass, node.Y)
+			switch {
+			case lhs == "Float" || rhs == "Float":
+				return "Float"
+			case lhs == "" || rhs == "":
+				return ""
+			}
+			return "Integer"
+		}
+	}
+	return ""
+}
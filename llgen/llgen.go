@@ -0,0 +1,335 @@
+package llgen
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// The Generator structure translates functions of the intermediate
+// representation into a module of textual LLVM IR defining main. Variables
+// of functions not in SSA form are given a stack slot.
+type Generator struct {
+	fn      *ir.Func
+	out     bytes.Buffer
+	allocas []string
+	slots   map[*ir.Var]string
+	copies  map[*ir.Var]ir.Value
+	regs    int
+	globals bytes.Buffer
+	strings map[string]string
+	errors  []error
+}
+
+func NewGenerator(fn *ir.Func) *Generator {
+	return &Generator{
+		fn:      fn,
+		slots:   map[*ir.Var]string{},
+		copies:  map[*ir.Var]ir.Value{},
+		strings: map[string]string{},
+	}
+}
+
+// Generate translates fn into a module only depending on the C library,
+// link it with -lm
+func Generate(fn *ir.Func) ([]byte, error) {
+	return NewGenerator(fn).Program()
+}
+
+func (g *Generator) errorf(pos ast.Position, format string, args ...any) {
+	g.errors = append(g.errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+// line writes an instruction
+func (g *Generator) line(format string, args ...any) {
+	g.out.WriteString("  ")
+	fmt.Fprintf(&g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+// reg returns a new register, instruction defines it with the arguments
+func (g *Generator) reg(instruction string, args ...any) string {
+	g.regs++
+	name := "%r" + strconv.Itoa(g.regs)
+	g.line("%s = %s", name, fmt.Sprintf(instruction, args...))
+	return name
+}
+
+// slot returns the stack slot of a variable, every slot is allocated in the
+// entry block so loops don't grow the stack
+func (g *Generator) slot(v *ir.Var) string {
+	if name, ok := g.slots[v]; ok {
+		return name
+	}
+	name := "%" + v.Ident()
+	g.slots[v] = name
+	g.allocas = append(g.allocas, fmt.Sprintf("%s = alloca %s", name, llType(v.T)))
+	return name
+}
+
+// isSlot reports whether v is kept in memory instead of a register
+func (g *Generator) isSlot(v *ir.Var) bool {
+	return !g.fn.SSA && v.Sym != nil
+}
+
+// register returns the register holding the value of v
+func register(v *ir.Var) string {
+	return "%" + v.Ident()
+}
+
+// literal returns a pointer to a string constant, equal literals share
+// their constant
+func (g *Generator) literal(s string) string {
+	name, ok := g.strings[s]
+	if !ok {
+		name = "@.str." + strconv.Itoa(len(g.strings))
+		g.strings[s] = name
+		fmt.Fprintf(&g.globals, "%s = private unnamed_addr constant [%d x i8] c%s\n", name, len(s)+1, quote(s))
+	}
+	return name
+}
+
+// Program translates the function into the main function of the module,
+// the stack slots are allocated before the instructions of the entry block
+func (g *Generator) Program() ([]byte, error) {
+	for _, b := range g.fn.Blocks {
+		for _, instr := range b.Instrs {
+			if (instr.Op == ir.Copy || instr.Op == ir.Declare) && !g.isSlot(instr.Dst) {
+				g.copies[instr.Dst] = instr.Args[0]
+			}
+		}
+	}
+
+	var entry bytes.Buffer
+	for i, b := range g.fn.Blocks {
+		if i > 0 {
+			fmt.Fprintf(&g.out, "%s:\n", b)
+		}
+		g.Block(b)
+		if i == 0 {
+			entry, g.out = g.out, bytes.Buffer{}
+		}
+	}
+	if len(g.errors) > 0 {
+		return nil, g.errors[0]
+	}
+
+	var out bytes.Buffer
+	out.WriteString("; Code generated by alpha compile. DO NOT EDIT.\n\n")
+	out.Write(g.globals.Bytes())
+	fmt.Fprintf(&out, "\n%s%s\ndefine i32 @main() {\n%s:\n", failures(), runtime, g.fn.Blocks[0])
+	for _, alloca := range g.allocas {
+		out.WriteString("  " + alloca + "\n")
+	}
+	out.Write(entry.Bytes())
+	out.Write(g.out.Bytes())
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+// Block translates the instructions of a basic block
+func (g *Generator) Block(b *ir.Block) {
+	for _, instr := range b.Instrs {
+		switch {
+		case instr.Op == ir.Copy || instr.Op == ir.Declare:
+			{
+				if g.isSlot(instr.Dst) {
+					t := llType(instr.Dst.T)
+					g.line("store %s %s, ptr %s", t, g.value(instr.Args[0]), g.slot(instr.Dst))
+				}
+			}
+		case instr.Op == ir.Convert:
+			g.line("%s = sitofp i64 %s to double", register(instr.Dst), g.value(instr.Args[0]))
+		case instr.Op.IsBinary():
+			g.binary(instr)
+		case instr.Op == ir.Call:
+			g.call(instr)
+		case instr.Op == ir.Assert:
+			g.line("call void @alpha_assert(i1 %s, ptr %s, ptr %s)", g.value(instr.Args[0]), g.literal(instr.Pos.String()), g.value(instr.Args[1]))
+		case instr.Op == ir.Phi:
+			{
+				args := make([]string, len(instr.Args))
+				for i, arg := range instr.Args {
+					args[i] = fmt.Sprintf("[ %s, %%%s ]", g.value(arg), b.Preds[i])
+				}
+				g.line("%s = phi %s %s", register(instr.Dst), llType(instr.Dst.T), strings.Join(args, ", "))
+			}
+		case instr.Op == ir.Jump:
+			g.line("br label %%%s", b.Succs[0])
+		case instr.Op == ir.Branch:
+			g.line("br i1 %s, label %%%s, label %%%s", g.value(instr.Args[0]), b.Succs[0], b.Succs[1])
+		case instr.Op == ir.Return:
+			g.line("ret i32 0")
+		}
+	}
+}
+
+// value returns the operand for a value, variables kept in memory are
+// loaded and copies are replaced by their source
+func (g *Generator) value(value ir.Value) string {
+	switch v := value.(type) {
+	case *ir.Var:
+		{
+			if src, ok := g.copies[v]; ok {
+				return g.value(src)
+			}
+			if g.isSlot(v) {
+				t := llType(v.T)
+				return g.reg("load %s, ptr %s", t, g.slot(v))
+			}
+			return register(v)
+		}
+	case *ir.Const:
+		{
+			switch c := v.Value.(type) {
+			case int:
+				return strconv.Itoa(c)
+			case float64:
+				return formatFloat(c)
+			case string:
+				return g.literal(c)
+			case bool:
+				return strconv.FormatBool(c)
+			}
+		}
+	}
+	return "undef"
+}
+
+// binary translates an operation, strings are compared with strcmp and
+// integer divisions check their divisor
+func (g *Generator) binary(instr *ir.Instr) {
+	lhs, rhs := g.value(instr.Args[0]), g.value(instr.Args[1])
+	dst := register(instr.Dst)
+	switch t := instr.Args[0].Type(); {
+	case t == types.String:
+		{
+			cmp := g.reg("call i32 @strcmp(ptr %s, ptr %s)", lhs, rhs)
+			g.line("%s = icmp eq i32 %s, 0", dst, cmp)
+		}
+	case t == types.Float:
+		g.line("%s = %s double %s, %s", dst, floatOperators[instr.Op], lhs, rhs)
+	case instr.Op == ir.Div:
+		g.line("%s = call i64 @alpha_div(i64 %s, i64 %s, ptr %s)", dst, lhs, rhs, g.literal(instr.Pos.String()))
+	default:
+		g.line("%s = %s %s %s, %s", dst, operators[instr.Op], llType(t), lhs, rhs)
+	}
+}
+
+// call translates a call to a builtin, print and println take values of any
+// type so they are expanded into a call per argument.
+func (g *Generator) call(instr *ir.Instr) {
+	sym := instr.Callee
+	switch {
+	case sym.Name == "print" || sym.Name == "println":
+		{
+			for _, arg := range instr.Args {
+				t := arg.Type()
+				g.line("call void @alpha_print_%s(%s %s)", printer(t), llType(t), g.value(arg))
+			}
+			if sym.Name == "println" {
+				g.line("call void @alpha_print_str(ptr %s)", g.literal("\n"))
+			}
+			return
+		}
+	case !builtins[sym.Name]:
+		{
+			g.errorf(instr.Pos, "%s can't be translated to LLVM IR, only the prelude builtins can", sym.Name)
+			return
+		}
+	}
+
+	values := make([]string, len(instr.Args))
+	for i, arg := range instr.Args {
+		values[i] = llType(arg.Type()) + " " + g.value(arg)
+	}
+	if failing[sym.Name] {
+		values = append(values, "ptr "+g.literal(instr.Pos.String()))
+	}
+	call := fmt.Sprintf("call %s @alpha_%s(%s)", llType(sym.Sig.Result), sym.Name, strings.Join(values, ", "))
+	if instr.Dst == nil {
+		g.line("%s", call)
+		return
+	}
+	g.line("%s = %s", register(instr.Dst), call)
+}
+
+// formatFloat writes a double in hexadecimal, the only notation LLVM reads
+// back exactly for every value
+func formatFloat(f float64) string {
+	return fmt.Sprintf("0x%016X", math.Float64bits(f))
+}
+
+// quote writes an LLVM string constant including its terminating NUL, every
+// byte outside of printable ASCII is escaped in hexadecimal.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&sb, "\\%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteString("\\00\"")
+	return sb.String()
+}
+
+// operators maps the operations to the integer instructions, booleans share
+// the equality of integers
+var operators = map[ir.Op]string{
+	ir.Add:          "add",
+	ir.Sub:          "sub",
+	ir.Mul:          "mul",
+	ir.Less:         "icmp slt",
+	ir.Greater:      "icmp sgt",
+	ir.LessEqual:    "icmp sle",
+	ir.GreaterEqual: "icmp sge",
+	ir.Equal:        "icmp eq",
+}
+
+var floatOperators = map[ir.Op]string{
+	ir.Add:          "fadd",
+	ir.Sub:          "fsub",
+	ir.Mul:          "fmul",
+	ir.Div:          "fdiv",
+	ir.Less:         "fcmp olt",
+	ir.Greater:      "fcmp ogt",
+	ir.LessEqual:    "fcmp ole",
+	ir.GreaterEqual: "fcmp oge",
+	ir.Equal:        "fcmp oeq",
+}
+
+func llType(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "i64"
+	case types.Float:
+		return "double"
+	case types.String:
+		return "ptr"
+	case types.Void:
+		return "void"
+	}
+	return "i1"
+}
+
+func printer(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "int"
+	case types.Float:
+		return "float"
+	case types.Boolean:
+		return "bool"
+	}
+	return "str"
+}
@@ -0,0 +1,175 @@
+package llgen_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/llgen"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// interpreterCommand returns the command line running a module with lli, LLVM 14
+// only reads opaque pointers when asked to and older versions can't
+func interpreterCommand(t *testing.T) []string {
+	t.Helper()
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli isn't in $PATH")
+	}
+	out, err := exec.Command(lli, "--version").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`LLVM version (\d+)`).FindSubmatch(out)
+	if match == nil {
+		t.Fatalf("can't tell the version of lli from %q", out)
+	}
+	switch version, _ := strconv.Atoi(string(match[1])); {
+	case version < 14:
+		t.Skipf("lli %d doesn't read opaque pointers", version)
+	case version < 15:
+		return []string{lli, "-opaque-pointers"}
+	}
+	return []string{lli}
+}
+
+// compare runs a program with the interpreter and its module with lli,
+// before and after optimizing. The output must match, and so must the exit
+// status and the position of a runtime error.
+func compare(t *testing.T, program *loader.Program, input string) {
+	t.Helper()
+	lli := interpreterCommand(t)
+	var want bytes.Buffer
+	wantErr := interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(input), &want).Run(context.Background(), program.Root)
+
+	for _, optimize := range []bool{false, true} {
+		fn, err := ir.Lower(program.Root, program.Info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if optimize {
+			fn.Optimize(nil)
+		}
+		src, err := llgen.Generate(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		module := filepath.Join(t.TempDir(), "main.ll")
+		if err := os.WriteFile(module, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var got, stderr bytes.Buffer
+		cmd := exec.Command(lli[0], append(lli[1:], module)...)
+		cmd.Stdin = strings.NewReader(input)
+		cmd.Stdout, cmd.Stderr = &got, &stderr
+		err = cmd.Run()
+		status := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("optimized %t: the module prints %q, the interpreter %q\n%s", optimize, got.String(), want.String(), stderr.String())
+		}
+
+		var exit *stdlib.ExitError
+		var runtimeErr *interpreter.RuntimeError
+		switch {
+		case errors.As(wantErr, &exit):
+			{
+				if status != exit.Code {
+					t.Errorf("optimized %t: the module exits with status %d, the interpreter with %d", optimize, status, exit.Code)
+				}
+			}
+		case errors.As(wantErr, &runtimeErr):
+			{
+				if msg := runtimeErr.Pos.String() + ": runtime error: " + runtimeErr.Msg + "\n"; status != 1 || stderr.String() != msg {
+					t.Errorf("optimized %t: the module exits with status %d and %q, the interpreter fails at %s with %q", optimize, status, stderr.String(), runtimeErr.Pos, runtimeErr.Msg)
+				}
+			}
+		case wantErr != nil:
+			t.Fatal(wantErr)
+		case status != 0:
+			t.Errorf("optimized %t: the module exits with status %d and %q", optimize, status, stderr.String())
+		}
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	files := []string{}
+	for _, pattern := range []string{filepath.Join("..", "tests", "*.alpha"), filepath.Join("..", "ir", "testdata", "*.alpha")} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if filepath.Base(file) == "while-do.alpha" {
+				t.Skip("loops forever")
+			}
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(file)
+			if err != nil {
+				t.Skip(err)
+			}
+			compare(t, program, "")
+		})
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	tests := []string{
+		`println(1.0 / 0.0, 0.0 - 1.0 / 0.0, 0.0 / 0.0, 1.5, 100000000.0)`,
+		`let var x : Integer in begin x = 0 - 9223372036854775807 - 1; println(x / (0 - 1), x - 1) end`,
+		`let var s : String in println(s)`,
+		`begin println(concat("a", "b"), substr("hola", 2, 2)); exit(4) end`,
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, program, "")
+		})
+	}
+}
+
+// TestFailures checks the messages the builtins fail with against the ones
+// of the interpreter
+func TestFailures(t *testing.T) {
+	tests := []struct {
+		src   string
+		input string
+	}{
+		{src: `println(readInt())`, input: "12 x\n"},
+		{src: `println(readInt())`, input: "a\"b\\\t\x01\x7f\u00e9 x\r\n"},
+		{src: `println(substr("hola", 3, 2))`},
+		{src: `println(substr("hola", 0 - 1, 2))`},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource("test.alpha", test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, program, test.input)
+		})
+	}
+}
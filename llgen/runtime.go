@@ -0,0 +1,400 @@
+package llgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/stdlib"
+)
+
+// builtins lists the prelude builtins the runtime implements
+var builtins = map[string]bool{
+	"readLine": true,
+	"readInt":  true,
+	"length":   true,
+	"concat":   true,
+	"substr":   true,
+	"toInt":    true,
+	"toFloat":  true,
+	"abs":      true,
+	"sqrt":     true,
+	"pow":      true,
+	"exit":     true,
+}
+
+// failing lists the builtins that take the position of the call to report
+// their runtime errors
+var failing = map[string]bool{
+	"readInt": true,
+	"substr":  true,
+}
+
+// runtime is added to every module, it only depends on the C library and
+// must behave like the runtime of the C target
+const runtime = `declare i32 @printf(ptr, ...)
+declare i32 @dprintf(i32, ptr, ...)
+declare i32 @snprintf(ptr, i64, ptr, ...)
+declare i32 @fflush(ptr)
+declare i32 @getchar()
+declare ptr @malloc(i64)
+declare ptr @realloc(ptr, i64)
+declare i64 @strlen(ptr)
+declare i32 @strcmp(ptr, ptr)
+declare ptr @memcpy(ptr, ptr, i64)
+declare i64 @strtoll(ptr, ptr, i32)
+declare void @exit(i32) noreturn
+declare double @llvm.fabs.f64(double)
+declare double @llvm.sqrt.f64(double)
+declare double @llvm.pow.f64(double, double)
+
+@.alpha.int = private unnamed_addr constant [5 x i8] c"%lld\00"
+@.alpha.float = private unnamed_addr constant [5 x i8] c"%.6g\00"
+@.alpha.str = private unnamed_addr constant [3 x i8] c"%s\00"
+@.alpha.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.alpha.false = private unnamed_addr constant [6 x i8] c"false\00"
+@.alpha.nan = private unnamed_addr constant [4 x i8] c"NaN\00"
+@.alpha.inf = private unnamed_addr constant [5 x i8] c"+Inf\00"
+@.alpha.minf = private unnamed_addr constant [5 x i8] c"-Inf\00"
+@.alpha.fail = private unnamed_addr constant [23 x i8] c"%s: runtime error: %s\0A\00"
+@.alpha.div = private unnamed_addr constant [25 x i8] c"integer division by zero\00"
+@.alpha.prefix = private unnamed_addr constant [20 x i8] c"%s: runtime error: \00"
+@.alpha.newline = private unnamed_addr constant [2 x i8] c"\0A\00"
+@.alpha.letters = private unnamed_addr constant [8 x i8] c"abtnvfr\00"
+@.alpha.hex = private unnamed_addr constant [7 x i8] c"\5Cx%02x\00"
+
+define private void @alpha_fail(ptr %pos, ptr %msg) noreturn {
+  %1 = call i32 @fflush(ptr null)
+  %2 = call i32 (i32, ptr, ...) @dprintf(i32 2, ptr @.alpha.fail, ptr %pos, ptr %msg)
+  call void @exit(i32 1)
+  unreachable
+}
+
+; alpha_begin_failure and alpha_end_failure surround the message of a
+; builtin, formatted with one of the constants of stdlib.Failures
+define private void @alpha_begin_failure(ptr %pos) {
+  %1 = call i32 @fflush(ptr null)
+  %2 = call i32 (i32, ptr, ...) @dprintf(i32 2, ptr @.alpha.prefix, ptr %pos)
+  ret void
+}
+
+define private void @alpha_end_failure() noreturn {
+  %1 = call i32 (i32, ptr, ...) @dprintf(i32 2, ptr @.alpha.newline)
+  call void @exit(i32 1)
+  unreachable
+}
+
+; alpha_quote quotes s the way %q does in Go, bytes outside of ASCII are
+; copied as they are
+define private ptr @alpha_quote(ptr %s) {
+entry:
+  %len = call i64 @strlen(ptr %s)
+  %escapes = mul i64 %len, 4
+  %size = add i64 %escapes, 3
+  %out = call ptr @malloc(i64 %size)
+  store i8 34, ptr %out
+  br label %loop
+loop:
+  %i = phi i64 [0, %entry], [%i.next, %next]
+  %o = phi i64 [1, %entry], [%o.next, %next]
+  %at = getelementptr inbounds i8, ptr %s, i64 %i
+  %c = load i8, ptr %at
+  %dst = getelementptr inbounds i8, ptr %out, i64 %o
+  %o1 = add i64 %o, 1
+  %o2 = add i64 %o, 2
+  %o4 = add i64 %o, 4
+  %end = icmp eq i8 %c, 0
+  br i1 %end, label %close, label %byte
+byte:
+  switch i8 %c, label %other [
+    i8 34, label %escape
+    i8 92, label %escape
+    i8 7, label %control
+    i8 8, label %control
+    i8 9, label %control
+    i8 10, label %control
+    i8 11, label %control
+    i8 12, label %control
+    i8 13, label %control
+  ]
+escape:
+  store i8 92, ptr %dst
+  %escaped = getelementptr inbounds i8, ptr %dst, i64 1
+  store i8 %c, ptr %escaped
+  br label %next
+control:
+  %index = sub i8 %c, 7
+  %index64 = zext i8 %index to i64
+  %letterp = getelementptr inbounds [8 x i8], ptr @.alpha.letters, i64 0, i64 %index64
+  %letter = load i8, ptr %letterp
+  store i8 92, ptr %dst
+  %letterat = getelementptr inbounds i8, ptr %dst, i64 1
+  store i8 %letter, ptr %letterat
+  br label %next
+other:
+  %low = icmp ult i8 %c, 32
+  %del = icmp eq i8 %c, 127
+  %hidden = or i1 %low, %del
+  br i1 %hidden, label %hex, label %copy
+hex:
+  %wide = zext i8 %c to i32
+  %written = call i32 (ptr, i64, ptr, ...) @snprintf(ptr %dst, i64 5, ptr @.alpha.hex, i32 %wide)
+  br label %next
+copy:
+  store i8 %c, ptr %dst
+  br label %next
+next:
+  %o.next = phi i64 [%o2, %escape], [%o2, %control], [%o4, %hex], [%o1, %copy]
+  %i.next = add i64 %i, 1
+  br label %loop
+close:
+  store i8 34, ptr %dst
+  %nul = getelementptr inbounds i8, ptr %dst, i64 1
+  store i8 0, ptr %nul
+  ret ptr %out
+}
+
+define private void @alpha_assert(i1 %ok, ptr %pos, ptr %msg) {
+  br i1 %ok, label %pass, label %fail
+fail:
+  call void @alpha_fail(ptr %pos, ptr %msg)
+  unreachable
+pass:
+  ret void
+}
+
+define private void @alpha_print_int(i64 %value) {
+  %1 = call i32 (ptr, ...) @printf(ptr @.alpha.int, i64 %value)
+  ret void
+}
+
+define private void @alpha_print_float(double %value) {
+  %nan = fcmp uno double %value, 0.0
+  br i1 %nan, label %isnan, label %number
+isnan:
+  call void @alpha_print_str(ptr @.alpha.nan)
+  ret void
+number:
+  %abs = call double @llvm.fabs.f64(double %value)
+  %inf = fcmp oeq double %abs, 0x7FF0000000000000
+  br i1 %inf, label %isinf, label %finite
+isinf:
+  %positive = fcmp ogt double %value, 0.0
+  %sign = select i1 %positive, ptr @.alpha.inf, ptr @.alpha.minf
+  call void @alpha_print_str(ptr %sign)
+  ret void
+finite:
+  %1 = call i32 (ptr, ...) @printf(ptr @.alpha.float, double %value)
+  ret void
+}
+
+define private void @alpha_print_str(ptr %value) {
+  %1 = call i32 (ptr, ...) @printf(ptr @.alpha.str, ptr %value)
+  ret void
+}
+
+define private void @alpha_print_bool(i1 %value) {
+  %1 = select i1 %value, ptr @.alpha.true, ptr @.alpha.false
+  call void @alpha_print_str(ptr %1)
+  ret void
+}
+
+define private i64 @alpha_div(i64 %a, i64 %b, ptr %pos) {
+  %zero = icmp eq i64 %b, 0
+  br i1 %zero, label %fail, label %nonzero
+fail:
+  call void @alpha_fail(ptr %pos, ptr @.alpha.div)
+  unreachable
+nonzero:
+  %minus = icmp eq i64 %b, -1
+  br i1 %minus, label %negate, label %ok
+negate:
+  %1 = sub i64 0, %a
+  ret i64 %1
+ok:
+  %2 = sdiv i64 %a, %b
+  ret i64 %2
+}
+
+define private ptr @alpha_readLine() {
+entry:
+  %len = alloca i64
+  %cap = alloca i64
+  %line = alloca ptr
+  store i64 0, ptr %len
+  store i64 64, ptr %cap
+  %first = call ptr @malloc(i64 64)
+  store ptr %first, ptr %line
+  br label %read
+read:
+  %c = call i32 @getchar()
+  %eof = icmp eq i32 %c, -1
+  %newline = icmp eq i32 %c, 10
+  %stop = or i1 %eof, %newline
+  br i1 %stop, label %done, label %check
+check:
+  %l = load i64, ptr %len
+  %k = load i64, ptr %cap
+  %next = add i64 %l, 1
+  %full = icmp uge i64 %next, %k
+  br i1 %full, label %grow, label %append
+grow:
+  %k2 = mul i64 %k, 2
+  store i64 %k2, ptr %cap
+  %old = load ptr, ptr %line
+  %new = call ptr @realloc(ptr %old, i64 %k2)
+  store ptr %new, ptr %line
+  br label %append
+append:
+  %p = load ptr, ptr %line
+  %at = getelementptr inbounds i8, ptr %p, i64 %l
+  %byte = trunc i32 %c to i8
+  store i8 %byte, ptr %at
+  store i64 %next, ptr %len
+  br label %read
+done:
+  %n = load i64, ptr %len
+  %empty = icmp eq i64 %n, 0
+  br i1 %empty, label %terminate, label %trim
+trim:
+  %lastn = sub i64 %n, 1
+  %lp = load ptr, ptr %line
+  %lastp = getelementptr inbounds i8, ptr %lp, i64 %lastn
+  %last = load i8, ptr %lastp
+  %cr = icmp eq i8 %last, 13
+  br i1 %cr, label %drop, label %terminate
+drop:
+  store i64 %lastn, ptr %len
+  br label %terminate
+terminate:
+  %result = load ptr, ptr %line
+  %size = load i64, ptr %len
+  %end = getelementptr inbounds i8, ptr %result, i64 %size
+  store i8 0, ptr %end
+  ret ptr %result
+}
+
+define private i64 @alpha_readInt(ptr %pos) {
+entry:
+  %end = alloca ptr
+  %line = call ptr @alpha_readLine()
+  %value = call i64 @strtoll(ptr %line, ptr %end, i32 10)
+  %parsed = load ptr, ptr %end
+  %none = icmp eq ptr %parsed, %line
+  br i1 %none, label %fail, label %skip
+skip:
+  %e = load ptr, ptr %end
+  %c = load i8, ptr %e
+  %space = icmp eq i8 %c, 32
+  %tab = icmp eq i8 %c, 9
+  %blank = or i1 %space, %tab
+  br i1 %blank, label %next, label %check
+next:
+  %e2 = getelementptr inbounds i8, ptr %e, i64 1
+  store ptr %e2, ptr %end
+  br label %skip
+check:
+  %rest = icmp ne i8 %c, 0
+  br i1 %rest, label %fail, label %ok
+fail:
+  call void @alpha_begin_failure(ptr %pos)
+  %quoted = call ptr @alpha_quote(ptr %line)
+  %printed = call i32 (i32, ptr, ...) @dprintf(i32 2, ptr @.alpha.failure.readInt, ptr %quoted)
+  call void @alpha_end_failure()
+  unreachable
+ok:
+  ret i64 %value
+}
+
+define private i64 @alpha_length(ptr %s) {
+  %1 = call i64 @strlen(ptr %s)
+  ret i64 %1
+}
+
+define private ptr @alpha_concat(ptr %a, ptr %b) {
+  %la = call i64 @strlen(ptr %a)
+  %lb = call i64 @strlen(ptr %b)
+  %1 = add i64 %la, %lb
+  %2 = add i64 %1, 1
+  %out = call ptr @malloc(i64 %2)
+  %3 = call ptr @memcpy(ptr %out, ptr %a, i64 %la)
+  %4 = getelementptr inbounds i8, ptr %out, i64 %la
+  %5 = add i64 %lb, 1
+  %6 = call ptr @memcpy(ptr %4, ptr %b, i64 %5)
+  ret ptr %out
+}
+
+define private ptr @alpha_substr(ptr %s, i64 %start, i64 %n, ptr %pos) {
+  %len = call i64 @strlen(ptr %s)
+  %1 = icmp slt i64 %start, 0
+  %2 = icmp slt i64 %n, 0
+  %3 = icmp sgt i64 %start, %len
+  %left = sub i64 %len, %start
+  %4 = icmp sgt i64 %n, %left
+  %5 = or i1 %1, %2
+  %6 = or i1 %3, %4
+  %7 = or i1 %5, %6
+  br i1 %7, label %fail, label %ok
+fail:
+  call void @alpha_begin_failure(ptr %pos)
+  %printed = call i32 (i32, ptr, ...) @dprintf(i32 2, ptr @.alpha.failure.substr, i64 %n, i64 %start, i64 %len)
+  call void @alpha_end_failure()
+  unreachable
+ok:
+  %8 = add i64 %n, 1
+  %out = call ptr @malloc(i64 %8)
+  %9 = getelementptr inbounds i8, ptr %s, i64 %start
+  %10 = call ptr @memcpy(ptr %out, ptr %9, i64 %n)
+  %11 = getelementptr inbounds i8, ptr %out, i64 %n
+  store i8 0, ptr %11
+  ret ptr %out
+}
+
+define private i64 @alpha_toInt(double %f) {
+  %1 = fptosi double %f to i64
+  ret i64 %1
+}
+
+define private double @alpha_toFloat(i64 %i) {
+  %1 = sitofp i64 %i to double
+  ret double %1
+}
+
+define private double @alpha_abs(double %f) {
+  %1 = call double @llvm.fabs.f64(double %f)
+  ret double %1
+}
+
+define private double @alpha_sqrt(double %f) {
+  %1 = call double @llvm.sqrt.f64(double %f)
+  ret double %1
+}
+
+define private double @alpha_pow(double %x, double %y) {
+  %1 = call double @llvm.pow.f64(double %x, double %y)
+  ret double %1
+}
+
+define private void @alpha_exit(i64 %code) {
+  %1 = call i32 @fflush(ptr null)
+  %2 = trunc i64 %code to i32
+  call void @exit(i32 %2)
+  unreachable
+}
+`
+
+// failures defines the messages of stdlib.Failures as constants named
+// @.alpha.failure. followed by the name of the builtin
+func failures() string {
+	names := []string{}
+	for name := range stdlib.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		format := stdlib.Printf(name)
+		fmt.Fprintf(&sb, "@.alpha.failure.%s = private unnamed_addr constant [%d x i8] c%s\n", name, len(format)+1, quote(format))
+	}
+	return sb.String()
+}
@@ -0,0 +1,273 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zSnails/alpha/checker"
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+	"github.com/zSnails/alpha/types"
+)
+
+// PathEnv is the environment variable holding the module search path, its
+// entries are separated like the ones in $PATH
+const PathEnv = "ALPHA_PATH"
+
+// Extension is added to imports that don't specify one
+const Extension = ".alpha"
+
+// SearchPathFromEnv returns the module search path configured through the
+// environment.
+func SearchPathFromEnv() []string {
+	return filepath.SplitList(os.Getenv(PathEnv))
+}
+
+// SearchPath returns the module search path for a list of directories
+// separated like the ones in $PATH, the ones of the environment come after
+// them.
+func SearchPath(includes string) []string {
+	return append(filepath.SplitList(includes), SearchPathFromEnv()...)
+}
+
+// Program is a checked program linked together with every module it
+// imports, directly or not.
+type Program struct {
+	// Root is the linked single command, the declarations of the imported
+	// modules wrap the entry program in a let command.
+	Root ast.Command
+	// Info holds the checker findings for every file
+	Info *checker.Info
+	// Files lists the paths of the loaded files, the entry program goes last
+	Files []string
+}
+
+type module struct {
+	path    string
+	root    *ast.Program
+	exports []*types.Symbol
+	loading bool
+}
+
+// The Loader structure resolves the imports of a program, checks every file
+// involved and links them together
+type Loader struct {
+	searchPath []string
+	universe   *types.Scope
+	modules    map[string]*module
+	order      []*module
+	stack      []*module
+	info       *checker.Info
+}
+
+// NewLoader returns a loader looking for modules in the given search path,
+// files are checked against the universe scope.
+func NewLoader(searchPath []string, universe *types.Scope) *Loader {
+	return &Loader{
+		searchPath: searchPath,
+		universe:   universe,
+		modules:    map[string]*module{},
+		info:       checker.NewInfo(),
+	}
+}
+
+// Load parses and checks the program in the named file along with all of
+// its imports, linking them into a single program.
+func (l *Loader) Load(name string) (*Program, error) {
+	tok, err := tokenizer.FromFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.load(name, tok)
+}
+
+// LoadSource is like Load but reads the program from src, imports are
+// resolved as if the program lived in the named file.
+func (l *Loader) LoadSource(name, src string) (*Program, error) {
+	return l.load(name, tokenizer.FromString(name, src))
+}
+
+func (l *Loader) load(name string, tok *tokenizer.Tokenizer) (*Program, error) {
+	main, err := l.parse(name, tok)
+	if err != nil {
+		return nil, err
+	}
+
+	body := main.root.Body
+	if body == nil {
+		return nil, diag.Errorf(main.root.Module.Pos, "a module can't be run as a program")
+	}
+
+	// The entry program is registered like any other module so files
+	// importing it back are reported as cycles.
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	main.loading = true
+	l.modules[path] = main
+	l.stack = append(l.stack, main)
+
+	if err := l.check(main); err != nil {
+		return nil, err
+	}
+
+	program := &Program{
+		Root: body,
+		Info: l.info,
+	}
+	for _, mod := range l.order {
+		program.Files = append(program.Files, mod.path)
+	}
+	program.Files = append(program.Files, main.path)
+
+	if len(l.order) == 0 {
+		return program, nil
+	}
+
+	let := &ast.LetCommand{Pos: body.Position(), Body: body}
+	for _, mod := range l.order {
+		for _, decl := range mod.root.Module.Decls {
+			if export, ok := decl.(*ast.ExportDecl); ok {
+				decl = export.Decl
+			}
+			let.Decls = append(let.Decls, decl)
+		}
+	}
+	program.Root = let
+	return program, nil
+}
+
+func (l *Loader) parse(name string, tok *tokenizer.Tokenizer) (*module, error) {
+	parser, err := parser.NewParser(tok)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parser.Program()
+	if err != nil {
+		return nil, err
+	}
+
+	return &module{
+		path: name,
+		root: root,
+	}, nil
+}
+
+// check loads the imports of the module and then checks the module itself
+// in a scope holding everything they export.
+func (l *Loader) check(mod *module) error {
+	scope := types.NewScope(l.universe)
+	origins := map[*types.Symbol]string{}
+
+	for _, imp := range mod.root.Imports {
+		imported, err := l.importModule(mod, imp)
+		if err != nil {
+			return err
+		}
+
+		for _, sym := range imported.exports {
+			if prev := scope.Insert(sym); prev != nil && prev != sym {
+				return diag.Errorf(imp.Pos, "%s is exported by both %s and %s", sym.Name, origins[prev], filepath.Base(imported.path))
+			}
+			origins[sym] = filepath.Base(imported.path)
+		}
+	}
+
+	return checker.NewChecker(l.info, scope).Program(mod.root)
+}
+
+func (l *Loader) importModule(from *module, node *ast.Import) (*module, error) {
+	path, err := l.resolve(from, node)
+	if err != nil {
+		return nil, err
+	}
+
+	if mod, ok := l.modules[path]; ok {
+		if mod.loading {
+			return nil, diag.Errorf(node.Pos, "import cycle not allowed: %s", l.cycle(mod))
+		}
+		return mod, nil
+	}
+
+	tok, err := tokenizer.FromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mod, err := l.parse(path, tok)
+	if err != nil {
+		return nil, err
+	}
+
+	body := mod.root.Module
+	if body == nil {
+		return nil, diag.Errorf(node.Pos, "imported file %s is not a module", path)
+	}
+
+	mod.loading = true
+	l.modules[path] = mod
+	l.stack = append(l.stack, mod)
+
+	if err := l.check(mod); err != nil {
+		return nil, err
+	}
+
+	for _, decl := range body.Decls {
+		if export, ok := decl.(*ast.ExportDecl); ok {
+			if sym, ok := l.info.Defs[export.Decl]; ok {
+				mod.exports = append(mod.exports, sym)
+			}
+		}
+	}
+
+	mod.loading = false
+	l.stack = l.stack[:len(l.stack)-1]
+	l.order = append(l.order, mod)
+	return mod, nil
+}
+
+// cycle describes the chain of imports that leads back to mod
+func (l *Loader) cycle(mod *module) string {
+	var names []string
+	for i := len(l.stack) - 1; i >= 0; i-- {
+		names = append([]string{filepath.Base(l.stack[i].path)}, names...)
+		if l.stack[i] == mod {
+			break
+		}
+	}
+	names = append(names, filepath.Base(mod.path))
+	return strings.Join(names, " -> ")
+}
+
+// resolve finds the file an import refers to, imports are looked up next to
+// the importing file first and then in every directory of the search path.
+func (l *Loader) resolve(from *module, node *ast.Import) (string, error) {
+	name := node.Path
+	if filepath.Ext(name) == "" {
+		name += Extension
+	}
+
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = []string{filepath.Join(filepath.Dir(from.path), name)}
+		for _, dir := range l.searchPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			abs, err := filepath.Abs(candidate)
+			if err != nil {
+				return "", err
+			}
+			return abs, nil
+		}
+	}
+	return "", diag.Errorf(node.Pos, "cannot find module %q in any of %s", node.Path, strings.Join(candidates, ", "))
+}
@@ -0,0 +1,143 @@
+package loader_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+func newLoader(searchPath ...string) *loader.Loader {
+	return loader.NewLoader(searchPath, stdlib.Prelude().Scope(types.Universe()))
+}
+
+// files returns the loaded files relative to testdata
+func files(t *testing.T, program *loader.Program) []string {
+	t.Helper()
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []string{}
+	for _, file := range program.Files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, err := filepath.Rel(testdata, abs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		file, err string
+	}{
+		{file: "main.alpha", err: "import cycle not allowed: a.alpha -> b.alpha -> a.alpha"},
+		{file: "a.alpha", err: "a module can't be run as a program"},
+		{file: "self.alpha", err: "import cycle not allowed: self.alpha -> self.alpha"},
+	}
+	for _, test := range tests {
+		_, err := newLoader().Load(filepath.Join("testdata", "cycle", test.file))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got %v, want %q", test.file, err, test.err)
+		}
+	}
+}
+
+// TestSearchOrder checks that imports are looked up next to the importing
+// file first and then in the directories of the search path in order
+func TestSearchOrder(t *testing.T) {
+	first := filepath.Join("testdata", "search", "first")
+	second := filepath.Join("testdata", "search", "second")
+	tests := []struct {
+		file       string
+		searchPath []string
+		files      []string
+	}{
+		{file: "main.alpha", searchPath: []string{first, second}, files: []string{"search/lib.alpha", "search/main.alpha"}},
+		{file: "app/main.alpha", searchPath: []string{first, second}, files: []string{"search/first/lib.alpha", "search/second/only.alpha", "search/app/main.alpha"}},
+		{file: "app/main.alpha", searchPath: []string{second, first}, files: []string{"search/second/lib.alpha", "search/second/only.alpha", "search/app/main.alpha"}},
+	}
+	for _, test := range tests {
+		program, err := newLoader(test.searchPath...).Load(filepath.Join("testdata", "search", test.file))
+		if err != nil {
+			t.Errorf("%s %v: %v", test.file, test.searchPath, err)
+			continue
+		}
+		if got := files(t, program); strings.Join(got, " ") != strings.Join(test.files, " ") {
+			t.Errorf("%s %v: loaded %v, want %v", test.file, test.searchPath, got, test.files)
+		}
+	}
+
+	_, err := newLoader(first).Load(filepath.Join("testdata", "search", "app", "main.alpha"))
+	if err == nil || !strings.Contains(err.Error(), `cannot find module "only"`) {
+		t.Errorf("got %v, want only to be missing", err)
+	}
+}
+
+func TestSearchPathFromEnv(t *testing.T) {
+	first := filepath.Join("testdata", "search", "first")
+	second := filepath.Join("testdata", "search", "second")
+	t.Setenv(loader.PathEnv, strings.Join([]string{second, first}, string(filepath.ListSeparator)))
+	if got := loader.SearchPathFromEnv(); strings.Join(got, " ") != second+" "+first {
+		t.Fatalf("got %v", got)
+	}
+	program, err := newLoader(loader.SearchPathFromEnv()...).Load(filepath.Join("testdata", "search", "app", "main.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := files(t, program); got[0] != "search/second/lib.alpha" {
+		t.Errorf("loaded %v, want the lib of %s", got, second)
+	}
+
+	// the directories given with -I come before the ones of the environment
+	searchPath := loader.SearchPath(first)
+	if strings.Join(searchPath, " ") != first+" "+second+" "+first {
+		t.Fatalf("got %v", searchPath)
+	}
+	program, err = newLoader(searchPath...).Load(filepath.Join("testdata", "search", "app", "main.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := files(t, program); got[0] != "search/first/lib.alpha" {
+		t.Errorf("loaded %v, want the lib of %s", got, first)
+	}
+
+	t.Setenv(loader.PathEnv, "")
+	if got := loader.SearchPathFromEnv(); len(got) != 0 {
+		t.Errorf("got %v for an empty %s", got, loader.PathEnv)
+	}
+}
+
+func TestExports(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{src: `import "lib" println(shown)`},
+		{src: `import "lib" begin counter = 2; println(counter) end`},
+		{src: `import "lib" println(hidden)`, err: "undefined: hidden"},
+		{src: `import "three" println(y)`},
+		// three imports one without exporting what it imports
+		{src: `import "three" println(x)`, err: "undefined: x"},
+		{src: `import "one" import "two" println(x)`, err: "x is exported by both one.alpha and two.alpha"},
+		// the same module imported twice exports the same symbols
+		{src: `import "one" import "three" println(x, y)`},
+	}
+	for _, test := range tests {
+		_, err := newLoader().LoadSource(filepath.Join("testdata", "visibility", "main.alpha"), test.src)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: %v", test.src, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%q: got %v, want %q", test.src, err, test.err)
+		}
+	}
+}
@@ -2,120 +2,314 @@ package ast
 
 import (
 	"fmt"
-	"strings"
 )
 
-type NodeType int8
+// Position describes where a construct starts in its source file
+type Position struct {
+	File string `json:"file,omitempty"`
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+}
 
-const (
-	Program NodeType = iota
-	Command
-	SingleCommand
-	Declaration
-	SingleDeclaration
-	TypeDenoter
-	Expression
-	PrimaryExpression
-	Operator
-	Integer
-	Float
-	Identifier
-	String
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Row, p.Col)
+}
+
+// Node is implemented by every construct of the tree
+type Node interface {
+	Position() Position
+}
+
+// Command is implemented by the single command constructs
+type Command interface {
+	Node
+	commandNode()
+}
+
+// Expression is implemented by the expression and primary expression
+// constructs
+type Expression interface {
+	Node
+	expressionNode()
+}
+
+// Declaration is implemented by the single declaration constructs
+type Declaration interface {
+	Node
+	declarationNode()
+}
+
+// The Program structure is the root of a file, it holds either the body of
+// a program or the declarations of a module.
+//
+//	program ::= (import String)* (singleCommand | module)
+type Program struct {
+	Pos     Position
+	Imports []*Import
+	// Body is the single command of a program, nil for modules
+	Body Command
+	// Module holds the declarations of a module, nil for programs
+	Module *Module
+}
+
+// The Import structure names a module the file depends on
+type Import struct {
+	Pos  Position
+	Path string
+}
+
+// The Module structure holds the declarations of a file meant to be
+// imported, only the ones wrapped in an ExportDecl are visible to importers.
+//
+//	module ::= [export] singleDeclaration (; [export] singleDeclaration)*
+type Module struct {
+	Pos   Position
+	Decls []Declaration
+}
+
+// The SkipCommand structure is the empty command
+type SkipCommand struct {
+	Pos Position
+}
+
+// The AssignCommand structure stores a value in a variable
+//
+//	Identifier = expression
+type AssignCommand struct {
+	Pos   Position
+	Name  *Ident
+	Value Expression
+}
+
+// The CallCommand structure calls a function discarding its result
+//
+//	Identifier arguments
+type CallCommand struct {
+	Pos  Position
+	Name *Ident
+	Args []Expression
+}
+
+// The IfCommand structure runs Then when Cond holds and Else otherwise
+//
+//	if expression then singleCommand else singleCommand
+type IfCommand struct {
+	Pos  Position
+	Cond Expression
+	Then Command
+	Else Command
+}
+
+// The WhileCommand structure runs Body as long as Cond holds
+//
+//	while expression do singleCommand
+type WhileCommand struct {
+	Pos  Position
+	Cond Expression
+	Body Command
+}
+
+// The LetCommand structure runs Body in a scope holding Decls
+//
+//	let declaration in singleCommand
+type LetCommand struct {
+	Pos   Position
+	Decls []Declaration
+	Body  Command
+}
+
+// The BlockCommand structure runs a sequence of commands
+//
+//	begin command end
+//	command ::= singleCommand (; singleCommand)*
+type BlockCommand struct {
+	Pos      Position
+	Commands []Command
+}
+
+// The ConstDecl structure binds a name to the value of an expression
+//
+//	const Identifier ~ expression
+type ConstDecl struct {
+	Pos   Position
+	Name  *Ident
+	Value Expression
+}
+
+// The VarDecl structure declares a variable of the denoted type
+//
+//	var Identifier : typeDenoter
+type VarDecl struct {
+	Pos  Position
+	Name *Ident
+	Type *TypeDenoter
+}
+
+// The ExportDecl structure makes the declaration of a module visible to
+// the files importing it
+type ExportDecl struct {
+	Pos  Position
+	Decl Declaration
+}
+
+// The TypeDenoter structure names the type of a variable
+//
+//	typeDenoter ::= Identifier
+type TypeDenoter struct {
+	Pos  Position
+	Name string
+}
+
+// The Ident structure is a name, either used as a value or naming what a
+// command or declaration refers to
+type Ident struct {
+	Pos  Position
+	Name string
+}
+
+type IntegerLit struct {
+	Pos   Position
+	Value int
+}
+
+type FloatLit struct {
+	Pos   Position
+	Value float64
+}
+
+// The StringLit structure holds the contents of a string literal, without
+// its quotes
+type StringLit struct {
+	Pos   Position
+	Value string
+}
+
+// The CallExpr structure calls a function using its result
+//
+//	Identifier arguments
+//	arguments ::= ( [expression (, expression)*] )
+type CallExpr struct {
+	Pos  Position
+	Name *Ident
+	Args []Expression
+}
+
+// The ParenExpr structure is an expression between parentheses
+type ParenExpr struct {
+	Pos Position
+	X   Expression
+}
+
+// The BinaryExpr structure applies an operator, operators have no
+// precedence so the chains of the source nest to the left
+//
+//	expression ::= primaryExpression (operator primaryExpression)*
+type BinaryExpr struct {
+	Pos   Position
+	X     Expression
+	Op    Operator
+	OpPos Position
+	Y     Expression
+}
+
+type Operator int8
 
+const (
+	Add Operator = iota
+	Sub
+	Mul
+	Div
+	Less
+	Greater
+	LessEqual
+	GreaterEqual
 	Equals
-	If
-	Then
-	Else
-	While
-	Do
-	Let
-	Const
-	Var
-	Tilde
-	In
-	Begin
-	End
+	Comparison
 )
 
-var ConstructNames = map[NodeType]string{
-
-	Program:           "Program",
-	Command:           "Command",
-	SingleCommand:     "SingleCommand",
-	Declaration:       "Declaration",
-	SingleDeclaration: "SingleDeclaration",
-	TypeDenoter:       "TypeDenoter",
-	Expression:        "Expression",
-	PrimaryExpression: "PrimaryExpression",
-	Operator:          "Operator",
-	Integer:           "Integer",
-	Float:             "Float",
-	Identifier:        "Identifier",
-	String:            "String",
-
-	Equals: "=",
-	If:     "if",
-	Then:   "then",
-	Else:   "else",
-	While:  "while",
-	Do:     "do",
-	Let:    "let",
-	Const:  "const",
-	Var:    "var",
-	Tilde:  "~",
-	In:     "in",
-	Begin:  "begin",
-	End:    "end",
-}
-
-type Node struct {
-	Type     NodeType `json:"type"`
-	Value    any      `json:"value,omitempty"`
-	Children []*Node  `json:"children,omitempty"`
-}
-
-func (n *Node) AddChild(child *Node) {
-	n.Children = append(n.Children, child)
-}
-
-func (n *Node) String() string {
-	if n == nil {
-		return ""
-	}
-	var sb strings.Builder
-	n.buildSExp(&sb, 0)
-	return sb.String()
-}
-
-func (n *Node) buildSExp(sb *strings.Builder, level int) {
-	if n == nil {
-		return
-	}
-	indent := strings.Repeat("    ", level)
-
-	switch v := n.Value.(type) {
-	case string:
-		fmt.Fprintf(sb, "%s\"%s\"", indent, v)
-	case nil:
-		fmt.Fprintf(sb, "%s%s", indent, ConstructNames[n.Type])
-	default:
-		fmt.Fprintf(sb, "%s%v", indent, n.Value)
-	}
-
-	if len(n.Children) > 0 {
-		fmt.Fprint(sb, " (")
-		for _, child := range n.Children {
-			fmt.Fprintln(sb)
-			child.buildSExp(sb, level+1)
-		}
-		fmt.Fprint(sb, ")")
-	}
-}
-
-func NewNode(_type NodeType, value any) *Node {
-	return &Node{
-		Type:     _type,
-		Value:    value,
-		Children: []*Node{},
-	}
+var OperatorNames = map[Operator]string{
+	Add:          "+",
+	Sub:          "-",
+	Mul:          "*",
+	Div:          "/",
+	Less:         "<",
+	Greater:      ">",
+	LessEqual:    "<=",
+	GreaterEqual: ">=",
+	Equals:       "=",
+	Comparison:   "==",
+}
+
+func (op Operator) String() string {
+	return OperatorNames[op]
+}
+
+// IsArithmetic reports whether the operator computes a number instead of
+// comparing its operands
+func (op Operator) IsArithmetic() bool {
+	return op <= Div
 }
+
+func (n *Program) Position() Position       { return n.Pos }
+func (n *Import) Position() Position        { return n.Pos }
+func (n *Module) Position() Position        { return n.Pos }
+func (n *SkipCommand) Position() Position   { return n.Pos }
+func (n *AssignCommand) Position() Position { return n.Pos }
+func (n *CallCommand) Position() Position   { return n.Pos }
+func (n *IfCommand) Position() Position     { return n.Pos }
+func (n *WhileCommand) Position() Position  { return n.Pos }
+func (n *LetCommand) Position() Position    { return n.Pos }
+func (n *BlockCommand) Position() Position  { return n.Pos }
+func (n *ConstDecl) Position() Position     { return n.Pos }
+func (n *VarDecl) Position() Position       { return n.Pos }
+func (n *ExportDecl) Position() Position    { return n.Pos }
+func (n *TypeDenoter) Position() Position   { return n.Pos }
+func (n *Ident) Position() Position         { return n.Pos }
+func (n *IntegerLit) Position() Position    { return n.Pos }
+func (n *FloatLit) Position() Position      { return n.Pos }
+func (n *StringLit) Position() Position     { return n.Pos }
+func (n *CallExpr) Position() Position      { return n.Pos }
+func (n *ParenExpr) Position() Position     { return n.Pos }
+func (n *BinaryExpr) Position() Position    { return n.Pos }
+
+func (*SkipCommand) commandNode()   {}
+func (*AssignCommand) commandNode() {}
+func (*CallCommand) commandNode()   {}
+func (*IfCommand) commandNode()     {}
+func (*WhileCommand) commandNode()  {}
+func (*LetCommand) commandNode()    {}
+func (*BlockCommand) commandNode()  {}
+
+func (*ConstDecl) declarationNode()  {}
+func (*VarDecl) declarationNode()    {}
+func (*ExportDecl) declarationNode() {}
+
+func (*Ident) expressionNode()      {}
+func (*IntegerLit) expressionNode() {}
+func (*FloatLit) expressionNode()   {}
+func (*StringLit) expressionNode()  {}
+func (*CallExpr) expressionNode()   {}
+func (*ParenExpr) expressionNode()  {}
+func (*BinaryExpr) expressionNode() {}
+
+func (n *Program) pos() *Position       { return &n.Pos }
+func (n *Import) pos() *Position        { return &n.Pos }
+func (n *Module) pos() *Position        { return &n.Pos }
+func (n *SkipCommand) pos() *Position   { return &n.Pos }
+func (n *AssignCommand) pos() *Position { return &n.Pos }
+func (n *CallCommand) pos() *Position   { return &n.Pos }
+func (n *IfCommand) pos() *Position     { return &n.Pos }
+func (n *WhileCommand) pos() *Position  { return &n.Pos }
+func (n *LetCommand) pos() *Position    { return &n.Pos }
+func (n *BlockCommand) pos() *Position  { return &n.Pos }
+func (n *ConstDecl) pos() *Position     { return &n.Pos }
+func (n *VarDecl) pos() *Position       { return &n.Pos }
+func (n *ExportDecl) pos() *Position    { return &n.Pos }
+func (n *TypeDenoter) pos() *Position   { return &n.Pos }
+func (n *Ident) pos() *Position         { return &n.Pos }
+func (n *IntegerLit) pos() *Position    { return &n.Pos }
+func (n *FloatLit) pos() *Position      { return &n.Pos }
+func (n *StringLit) pos() *Position     { return &n.Pos }
+func (n *CallExpr) pos() *Position      { return &n.Pos }
+func (n *ParenExpr) pos() *Position     { return &n.Pos }
+func (n *BinaryExpr) pos() *Position    { return &n.Pos }
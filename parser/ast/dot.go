@@ -0,0 +1,39 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dot returns the tree below node as a Graphviz graph, every node is labelled
+// with the name of its construct and what it holds, edges go from parents to
+// their children in source order
+func Dot(node Node) string {
+	var sb strings.Builder
+	sb.WriteString("digraph ast {\n")
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	ids := 0
+	var dot func(node Node) int
+	dot = func(node Node) int {
+		id := ids
+		ids++
+		text := ConstructName(node)
+		if label := label(node); label != "" {
+			text += "\n" + label
+		}
+		fmt.Fprintf(&sb, "  n%d [label=%s];\n", id, dotQuote(text))
+		for _, child := range children(node) {
+			fmt.Fprintf(&sb, "  n%d -> n%d;\n", id, dot(child))
+		}
+		return id
+	}
+	dot(node)
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotQuote returns s as a quoted Graphviz string with its lines centered
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
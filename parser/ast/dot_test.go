@@ -0,0 +1,36 @@
+package ast_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestDot compares the graph of testdata/dot.alpha with its golden file, go
+// test -update rewrites it
+func TestDot(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "dot.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ast.Dot(parse(t, string(src)))
+
+	golden := filepath.Join("testdata", "dot.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("the graph differs from %s\n%s", golden, got)
+	}
+}
@@ -0,0 +1,189 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Format returns the source of the tree below node, blocks are indented with
+// four spaces. Parsing the source of a tree the parser built gives the same
+// tree back, save for the positions and the comments.
+func Format(node Node) string {
+	f := &formatter{}
+	f.node(node)
+	return f.sb.String()
+}
+
+type formatter struct {
+	sb    strings.Builder
+	level int
+}
+
+// newline starts a line at the current indentation
+func (f *formatter) newline() {
+	f.sb.WriteString("\n" + strings.Repeat("    ", f.level))
+}
+
+func (f *formatter) node(node Node) {
+	switch n := node.(type) {
+	case *Program:
+		{
+			for _, imp := range n.Imports {
+				f.node(imp)
+				f.sb.WriteString("\n")
+			}
+			if n.Module != nil {
+				f.node(n.Module)
+			}
+			if n.Body != nil {
+				f.node(n.Body)
+			}
+			f.sb.WriteString("\n")
+		}
+	case *Import:
+		f.sb.WriteString("import " + quote(n.Path))
+	case *Module:
+		for i, decl := range n.Decls {
+			if i > 0 {
+				f.sb.WriteString(";\n")
+			}
+			f.node(decl)
+		}
+	case *AssignCommand:
+		{
+			f.node(n.Name)
+			f.sb.WriteString(" = ")
+			f.node(n.Value)
+		}
+	case *CallCommand:
+		f.call(n.Name, n.Args)
+	case *IfCommand:
+		{
+			f.sb.WriteString("if ")
+			f.node(n.Cond)
+			f.sb.WriteString(" then ")
+			f.node(n.Then)
+			f.sb.WriteString(" else ")
+			f.node(n.Else)
+		}
+	case *WhileCommand:
+		{
+			f.sb.WriteString("while ")
+			f.node(n.Cond)
+			f.sb.WriteString(" do ")
+			f.node(n.Body)
+		}
+	case *LetCommand:
+		{
+			f.sb.WriteString("let ")
+			for i, decl := range n.Decls {
+				if i > 0 {
+					f.sb.WriteString("; ")
+				}
+				f.node(decl)
+			}
+			f.sb.WriteString(" in ")
+			f.node(n.Body)
+		}
+	case *BlockCommand:
+		{
+			if len(n.Commands) == 1 {
+				if _, ok := n.Commands[0].(*SkipCommand); ok {
+					f.sb.WriteString("begin end")
+					return
+				}
+			}
+			f.sb.WriteString("begin")
+			f.level++
+			for i, command := range n.Commands {
+				if i > 0 {
+					f.sb.WriteString(";")
+				}
+				// an empty command leaves its line empty
+				if _, ok := command.(*SkipCommand); ok {
+					f.sb.WriteString("\n")
+					continue
+				}
+				f.newline()
+				f.node(command)
+			}
+			f.level--
+			f.newline()
+			f.sb.WriteString("end")
+		}
+	case *ConstDecl:
+		{
+			f.sb.WriteString("const ")
+			f.node(n.Name)
+			f.sb.WriteString(" ~ ")
+			f.node(n.Value)
+		}
+	case *VarDecl:
+		{
+			f.sb.WriteString("var ")
+			f.node(n.Name)
+			f.sb.WriteString(": ")
+			f.node(n.Type)
+		}
+	case *ExportDecl:
+		{
+			f.sb.WriteString("export")
+			if n.Decl != nil {
+				f.sb.WriteString(" ")
+				f.node(n.Decl)
+			}
+		}
+	case *TypeDenoter:
+		f.sb.WriteString(n.Name)
+	case *Ident:
+		f.sb.WriteString(n.Name)
+	case *IntegerLit:
+		f.sb.WriteString(strconv.Itoa(n.Value))
+	case *FloatLit:
+		{
+			// a float needs its point not to be read back as an integer
+			text := strconv.FormatFloat(n.Value, 'f', -1, 64)
+			if !strings.Contains(text, ".") {
+				text += ".0"
+			}
+			f.sb.WriteString(text)
+		}
+	case *StringLit:
+		f.sb.WriteString(quote(n.Value))
+	case *CallExpr:
+		f.call(n.Name, n.Args)
+	case *ParenExpr:
+		{
+			f.sb.WriteString("(")
+			f.node(n.X)
+			f.sb.WriteString(")")
+		}
+	case *BinaryExpr:
+		{
+			f.node(n.X)
+			f.sb.WriteString(" " + n.Op.String() + " ")
+			f.node(n.Y)
+		}
+	}
+}
+
+func (f *formatter) call(name *Ident, args []Expression) {
+	f.node(name)
+	f.sb.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			f.sb.WriteString(", ")
+		}
+		f.node(arg)
+	}
+	f.sb.WriteString(")")
+}
+
+// quote returns a string literal holding s, strings have no escapes so the
+// single quote is used when s holds a double one
+func quote(s string) string {
+	if strings.Contains(s, `"`) {
+		return "'" + s + "'"
+	}
+	return `"` + s + `"`
+}
@@ -0,0 +1,528 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the JSON encoding of trees, it changes
+// whenever a construct is added or encoded differently
+const SchemaVersion = 1
+
+// The document structure is the top level of the JSON encoding
+//
+//	{"version": 1, "root": node}
+type document struct {
+	Version int             `json:"version"`
+	Root    json.RawMessage `json:"root"`
+}
+
+// header starts the object of every node, kind is the name of its construct
+type header struct {
+	Kind string   `json:"kind"`
+	Pos  Position `json:"pos"`
+}
+
+// The objects of the constructs, children are kept encoded until their kind
+// is known
+type (
+	programJSON struct {
+		header
+		Imports []json.RawMessage `json:"imports"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+		Module  json.RawMessage   `json:"module,omitempty"`
+	}
+	importJSON struct {
+		header
+		Path string `json:"path"`
+	}
+	moduleJSON struct {
+		header
+		Decls []json.RawMessage `json:"decls"`
+	}
+	bindingJSON struct {
+		header
+		Name  json.RawMessage `json:"name"`
+		Value json.RawMessage `json:"value"`
+	}
+	callJSON struct {
+		header
+		Name json.RawMessage   `json:"name"`
+		Args []json.RawMessage `json:"args"`
+	}
+	ifJSON struct {
+		header
+		Cond json.RawMessage `json:"cond"`
+		Then json.RawMessage `json:"then"`
+		Else json.RawMessage `json:"else"`
+	}
+	whileJSON struct {
+		header
+		Cond json.RawMessage `json:"cond"`
+		Body json.RawMessage `json:"body"`
+	}
+	letJSON struct {
+		header
+		Decls []json.RawMessage `json:"decls"`
+		Body  json.RawMessage   `json:"body"`
+	}
+	blockJSON struct {
+		header
+		Commands []json.RawMessage `json:"commands"`
+	}
+	varJSON struct {
+		header
+		Name json.RawMessage `json:"name"`
+		Type json.RawMessage `json:"type"`
+	}
+	exportJSON struct {
+		header
+		Decl json.RawMessage `json:"decl"`
+	}
+	nameJSON struct {
+		header
+		Name string `json:"name"`
+	}
+	integerJSON struct {
+		header
+		Value int `json:"value"`
+	}
+	floatJSON struct {
+		header
+		Value float64 `json:"value"`
+	}
+	stringJSON struct {
+		header
+		Value string `json:"value"`
+	}
+	parenJSON struct {
+		header
+		X json.RawMessage `json:"x"`
+	}
+	binaryJSON struct {
+		header
+		X     json.RawMessage `json:"x"`
+		Op    string          `json:"op"`
+		OpPos Position        `json:"opPos"`
+		Y     json.RawMessage `json:"y"`
+	}
+)
+
+var null = json.RawMessage("null")
+
+// The SchemaError structure is a document that can be read as JSON but
+// doesn't hold a tree, Pos is the position of the node at fault when the
+// document gives one
+type SchemaError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Pos.Row == 0 {
+		return "ast.Unmarshal: " + e.Msg
+	}
+	return fmt.Sprintf("ast.Unmarshal: %s: %s", e.Pos, e.Msg)
+}
+
+// Marshal encodes the tree below node as JSON. Every node is an object
+// holding the name of its construct in "kind", its position in "pos" and its
+// fields in lower camel case, operators are encoded as they are written.
+//
+//	{"version": 1, "root": {"kind": "Ident", "pos": {"row": 1, "col": 1}, "name": "x"}}
+func Marshal(node Node) ([]byte, error) {
+	e := &encoder{}
+	root := e.node(node)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return json.Marshal(document{Version: SchemaVersion, Root: root})
+}
+
+type encoder struct {
+	err error
+}
+
+// node encodes a node, a missing child is encoded as null
+func (e *encoder) node(node Node) json.RawMessage {
+	if node == nil || e.err != nil {
+		return null
+	}
+
+	h := header{Kind: ConstructName(node), Pos: node.Position()}
+	var v any
+	switch n := node.(type) {
+	case *Program:
+		{
+			program := programJSON{header: h, Imports: encodeList(e, n.Imports)}
+			if n.Body != nil {
+				program.Body = e.node(n.Body)
+			}
+			if n.Module != nil {
+				program.Module = e.node(n.Module)
+			}
+			v = program
+		}
+	case *Import:
+		v = importJSON{header: h, Path: n.Path}
+	case *Module:
+		v = moduleJSON{header: h, Decls: encodeList(e, n.Decls)}
+	case *SkipCommand:
+		v = h
+	case *AssignCommand:
+		v = bindingJSON{header: h, Name: e.ident(n.Name), Value: e.node(n.Value)}
+	case *CallCommand:
+		v = callJSON{header: h, Name: e.ident(n.Name), Args: encodeList(e, n.Args)}
+	case *IfCommand:
+		v = ifJSON{header: h, Cond: e.node(n.Cond), Then: e.node(n.Then), Else: e.node(n.Else)}
+	case *WhileCommand:
+		v = whileJSON{header: h, Cond: e.node(n.Cond), Body: e.node(n.Body)}
+	case *LetCommand:
+		v = letJSON{header: h, Decls: encodeList(e, n.Decls), Body: e.node(n.Body)}
+	case *BlockCommand:
+		v = blockJSON{header: h, Commands: encodeList(e, n.Commands)}
+	case *ConstDecl:
+		v = bindingJSON{header: h, Name: e.ident(n.Name), Value: e.node(n.Value)}
+	case *VarDecl:
+		{
+			typ := null
+			if n.Type != nil {
+				typ = e.node(n.Type)
+			}
+			v = varJSON{header: h, Name: e.ident(n.Name), Type: typ}
+		}
+	case *ExportDecl:
+		v = exportJSON{header: h, Decl: e.node(n.Decl)}
+	case *TypeDenoter:
+		v = nameJSON{header: h, Name: n.Name}
+	case *Ident:
+		v = nameJSON{header: h, Name: n.Name}
+	case *IntegerLit:
+		v = integerJSON{header: h, Value: n.Value}
+	case *FloatLit:
+		v = floatJSON{header: h, Value: n.Value}
+	case *StringLit:
+		v = stringJSON{header: h, Value: n.Value}
+	case *CallExpr:
+		v = callJSON{header: h, Name: e.ident(n.Name), Args: encodeList(e, n.Args)}
+	case *ParenExpr:
+		v = parenJSON{header: h, X: e.node(n.X)}
+	case *BinaryExpr:
+		v = binaryJSON{header: h, X: e.node(n.X), Op: n.Op.String(), OpPos: n.OpPos, Y: e.node(n.Y)}
+	default:
+		{
+			e.err = fmt.Errorf("ast.Marshal: unknown construct %T", node)
+			return null
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.err = err
+		return null
+	}
+	return data
+}
+
+// ident encodes the name of a construct, which may be missing
+func (e *encoder) ident(ident *Ident) json.RawMessage {
+	if ident == nil {
+		return null
+	}
+	return e.node(ident)
+}
+
+// encodeList encodes the nodes of a list, a nil list stays null
+func encodeList[T Node](e *encoder, nodes []T) []json.RawMessage {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]json.RawMessage, len(nodes))
+	for i, node := range nodes {
+		out[i] = e.node(node)
+	}
+	return out
+}
+
+// Unmarshal decodes a tree encoded by Marshal, the tree is the same down to
+// the positions of its nodes
+func Unmarshal(data []byte) (Node, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != SchemaVersion {
+		return nil, &SchemaError{Msg: fmt.Sprintf("unsupported schema version %d, expected %d", doc.Version, SchemaVersion)}
+	}
+
+	d := &decoder{}
+	root := d.node(doc.Root)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if root == nil {
+		return nil, &SchemaError{Msg: `the document is missing "root"`}
+	}
+	return root, nil
+}
+
+type decoder struct {
+	err error
+}
+
+// errorf records a schema error at pos, only the first error is kept
+func (d *decoder) errorf(pos Position, format string, args ...any) {
+	if d.err == nil {
+		d.err = &SchemaError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+}
+
+// decode unmarshals the object of a node into v, recording the first error
+func (d *decoder) decode(data json.RawMessage, v any) {
+	if d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		d.err = fmt.Errorf("ast.Unmarshal: %w", err)
+	}
+}
+
+// node decodes a node of any kind, null gives nil
+func (d *decoder) node(data json.RawMessage) Node {
+	if d.err != nil || len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	var h header
+	d.decode(data, &h)
+	if d.err != nil {
+		return nil
+	}
+
+	switch h.Kind {
+	case "Program":
+		{
+			var v programJSON
+			d.decode(data, &v)
+			n := &Program{Pos: h.Pos}
+			n.Imports = decodeList[*Import](d, n, "imports", v.Imports)
+			n.Body = decode[Command](d, n, v.Body)
+			n.Module = decode[*Module](d, n, v.Module)
+			if d.err == nil && (n.Body == nil) == (n.Module == nil) {
+				d.errorf(h.Pos, `Program needs exactly one of "body" and "module"`)
+			}
+			return n
+		}
+	case "Import":
+		{
+			var v importJSON
+			d.decode(data, &v)
+			return &Import{Pos: h.Pos, Path: v.Path}
+		}
+	case "Module":
+		{
+			var v moduleJSON
+			d.decode(data, &v)
+			n := &Module{Pos: h.Pos}
+			n.Decls = decodeList[Declaration](d, n, "decls", v.Decls)
+			return n
+		}
+	case "SkipCommand":
+		return &SkipCommand{Pos: h.Pos}
+	case "AssignCommand":
+		{
+			var v bindingJSON
+			d.decode(data, &v)
+			n := &AssignCommand{Pos: h.Pos}
+			n.Name = require[*Ident](d, n, "name", v.Name)
+			n.Value = require[Expression](d, n, "value", v.Value)
+			return n
+		}
+	case "CallCommand":
+		{
+			var v callJSON
+			d.decode(data, &v)
+			n := &CallCommand{Pos: h.Pos}
+			n.Name = require[*Ident](d, n, "name", v.Name)
+			n.Args = decodeList[Expression](d, n, "args", v.Args)
+			return n
+		}
+	case "IfCommand":
+		{
+			var v ifJSON
+			d.decode(data, &v)
+			n := &IfCommand{Pos: h.Pos}
+			n.Cond = require[Expression](d, n, "cond", v.Cond)
+			n.Then = require[Command](d, n, "then", v.Then)
+			n.Else = require[Command](d, n, "else", v.Else)
+			return n
+		}
+	case "WhileCommand":
+		{
+			var v whileJSON
+			d.decode(data, &v)
+			n := &WhileCommand{Pos: h.Pos}
+			n.Cond = require[Expression](d, n, "cond", v.Cond)
+			n.Body = require[Command](d, n, "body", v.Body)
+			return n
+		}
+	case "LetCommand":
+		{
+			var v letJSON
+			d.decode(data, &v)
+			n := &LetCommand{Pos: h.Pos}
+			n.Decls = decodeList[Declaration](d, n, "decls", v.Decls)
+			n.Body = require[Command](d, n, "body", v.Body)
+			return n
+		}
+	case "BlockCommand":
+		{
+			var v blockJSON
+			d.decode(data, &v)
+			n := &BlockCommand{Pos: h.Pos}
+			n.Commands = decodeList[Command](d, n, "commands", v.Commands)
+			return n
+		}
+	case "ConstDecl":
+		{
+			var v bindingJSON
+			d.decode(data, &v)
+			n := &ConstDecl{Pos: h.Pos}
+			n.Name = require[*Ident](d, n, "name", v.Name)
+			n.Value = require[Expression](d, n, "value", v.Value)
+			return n
+		}
+	case "VarDecl":
+		{
+			var v varJSON
+			d.decode(data, &v)
+			n := &VarDecl{Pos: h.Pos}
+			n.Name = require[*Ident](d, n, "name", v.Name)
+			n.Type = require[*TypeDenoter](d, n, "type", v.Type)
+			return n
+		}
+	case "ExportDecl":
+		{
+			var v exportJSON
+			d.decode(data, &v)
+			n := &ExportDecl{Pos: h.Pos}
+			n.Decl = require[Declaration](d, n, "decl", v.Decl)
+			return n
+		}
+	case "TypeDenoter":
+		{
+			var v nameJSON
+			d.decode(data, &v)
+			return &TypeDenoter{Pos: h.Pos, Name: v.Name}
+		}
+	case "Ident":
+		{
+			var v nameJSON
+			d.decode(data, &v)
+			return &Ident{Pos: h.Pos, Name: v.Name}
+		}
+	case "IntegerLit":
+		{
+			var v integerJSON
+			d.decode(data, &v)
+			return &IntegerLit{Pos: h.Pos, Value: v.Value}
+		}
+	case "FloatLit":
+		{
+			var v floatJSON
+			d.decode(data, &v)
+			return &FloatLit{Pos: h.Pos, Value: v.Value}
+		}
+	case "StringLit":
+		{
+			var v stringJSON
+			d.decode(data, &v)
+			return &StringLit{Pos: h.Pos, Value: v.Value}
+		}
+	case "CallExpr":
+		{
+			var v callJSON
+			d.decode(data, &v)
+			n := &CallExpr{Pos: h.Pos}
+			n.Name = require[*Ident](d, n, "name", v.Name)
+			n.Args = decodeList[Expression](d, n, "args", v.Args)
+			return n
+		}
+	case "ParenExpr":
+		{
+			var v parenJSON
+			d.decode(data, &v)
+			n := &ParenExpr{Pos: h.Pos}
+			n.X = require[Expression](d, n, "x", v.X)
+			return n
+		}
+	case "BinaryExpr":
+		{
+			var v binaryJSON
+			d.decode(data, &v)
+			n := &BinaryExpr{Pos: h.Pos, OpPos: v.OpPos}
+			op, ok := operator(v.Op)
+			if !ok && d.err == nil {
+				d.errorf(h.Pos, "unknown operator %q", v.Op)
+			}
+			n.Op = op
+			n.X = require[Expression](d, n, "x", v.X)
+			n.Y = require[Expression](d, n, "y", v.Y)
+			return n
+		}
+	}
+	if h.Kind == "" {
+		d.errorf(h.Pos, `a node is missing "kind"`)
+		return nil
+	}
+	d.errorf(h.Pos, "unknown construct %q", h.Kind)
+	return nil
+}
+
+// decode decodes a child of parent, checking it is of the kind its field
+// holds
+func decode[T Node](d *decoder, parent Node, data json.RawMessage) T {
+	var zero T
+	node := d.node(data)
+	if node == nil {
+		return zero
+	}
+	child, ok := node.(T)
+	if !ok {
+		d.errorf(node.Position(), "%s can't appear in %s", ConstructName(node), ConstructName(parent))
+		return zero
+	}
+	return child
+}
+
+// require decodes a child of parent the tree can't do without, field is
+// the name of the member holding it
+func require[T Node](d *decoder, parent Node, field string, data json.RawMessage) T {
+	if d.err == nil && (len(data) == 0 || string(data) == "null") {
+		d.errorf(parent.Position(), "%s is missing %q", ConstructName(parent), field)
+	}
+	return decode[T](d, parent, data)
+}
+
+// decodeList decodes the children of parent held in a list, a null list
+// stays nil but its elements can't be null
+func decodeList[T Node](d *decoder, parent Node, field string, data []json.RawMessage) []T {
+	if data == nil {
+		return nil
+	}
+	out := make([]T, len(data))
+	for i, child := range data {
+		out[i] = require[T](d, parent, fmt.Sprintf("%s[%d]", field, i), child)
+	}
+	return out
+}
+
+// operator returns the operator written as name
+func operator(name string) (Operator, bool) {
+	for op, opName := range OperatorNames {
+		if opName == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,80 @@
+package ast_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/parser"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	p, err := parser.NewParser(tokenizer.FromString("test.alpha", src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := p.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// TestTruncated decodes every prefix of a document, none is a tree
+func TestTruncated(t *testing.T) {
+	data, err := ast.Marshal(parse(t, "let var x : Integer in if x <= 1 then println(x, 'a') else while x > 0 do x = x - 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i++ {
+		if node, err := ast.Unmarshal(data[:i]); err == nil {
+			t.Fatalf("%s: decoded %s", data[:i], ast.Sprint(node))
+		}
+	}
+	if _, err := ast.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	pos := `"pos": {"file": "a.alpha", "row": 2, "col": 3}`
+	ident := `{"kind": "Ident", ` + pos + `, "name": "x"}`
+	tests := []struct {
+		root, msg string
+	}{
+		{root: `{"kind": "IfCommand"}`, msg: `IfCommand is missing "cond"`},
+		{root: `{"kind": "IfCommand", ` + pos + `, "cond": ` + ident + `, "then": {"kind": "SkipCommand"}}`, msg: `a.alpha:2:3: IfCommand is missing "else"`},
+		{root: `{"kind": "WhileCommand", "cond": ` + ident + `}`, msg: `WhileCommand is missing "body"`},
+		{root: `{"kind": "AssignCommand", "name": ` + ident + `, "value": null}`, msg: `AssignCommand is missing "value"`},
+		{root: `{"kind": "BinaryExpr", "x": ` + ident + `, "op": "+"}`, msg: `BinaryExpr is missing "y"`},
+		{root: `{"kind": "VarDecl", "name": ` + ident + `}`, msg: `VarDecl is missing "type"`},
+		{root: `{"kind": "BlockCommand", "commands": [{"kind": "SkipCommand"}, null]}`, msg: `BlockCommand is missing "commands[1]"`},
+		{root: `{"kind": "Program"}`, msg: `Program needs exactly one of "body" and "module"`},
+		{root: `{"kind": "ParenExpr", "x": {"kind": "SkipCommand"}}`, msg: `SkipCommand can't appear in ParenExpr`},
+		{root: `{"kind": "BinaryExpr", "x": ` + ident + `, "op": "%", "y": ` + ident + `}`, msg: `unknown operator "%"`},
+		{root: `{"kind": "Loop"}`, msg: `unknown construct "Loop"`},
+		{root: `{"name": "x"}`, msg: `a node is missing "kind"`},
+		{root: `null`, msg: `the document is missing "root"`},
+	}
+	for _, test := range tests {
+		_, err := ast.Unmarshal([]byte(`{"version": 1, "root": ` + test.root + `}`))
+		var schemaErr *ast.SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("%s: got %v, want a schema error", test.root, err)
+			continue
+		}
+		if want := "ast.Unmarshal: " + test.msg; err.Error() != want {
+			t.Errorf("%s: got %q, want %q", test.root, err, want)
+		}
+		if strings.Contains(err.Error(), ":0:0") {
+			t.Errorf("%s: %q holds a zero position", test.root, err)
+		}
+	}
+
+	if _, err := ast.Unmarshal([]byte(`{"version": 2, "root": {"kind": "SkipCommand"}}`)); err == nil || err.Error() != "ast.Unmarshal: unsupported schema version 2, expected 1" {
+		t.Errorf("got %v, want an unsupported version", err)
+	}
+}
@@ -0,0 +1,104 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConstructName returns the name of the construct a node is, like
+// "IfCommand"
+func ConstructName(node Node) string {
+	switch node.(type) {
+	case *Program:
+		return "Program"
+	case *Import:
+		return "Import"
+	case *Module:
+		return "Module"
+	case *SkipCommand:
+		return "SkipCommand"
+	case *AssignCommand:
+		return "AssignCommand"
+	case *CallCommand:
+		return "CallCommand"
+	case *IfCommand:
+		return "IfCommand"
+	case *WhileCommand:
+		return "WhileCommand"
+	case *LetCommand:
+		return "LetCommand"
+	case *BlockCommand:
+		return "BlockCommand"
+	case *ConstDecl:
+		return "ConstDecl"
+	case *VarDecl:
+		return "VarDecl"
+	case *ExportDecl:
+		return "ExportDecl"
+	case *TypeDenoter:
+		return "TypeDenoter"
+	case *Ident:
+		return "Ident"
+	case *IntegerLit:
+		return "IntegerLit"
+	case *FloatLit:
+		return "FloatLit"
+	case *StringLit:
+		return "StringLit"
+	case *CallExpr:
+		return "CallExpr"
+	case *ParenExpr:
+		return "ParenExpr"
+	case *BinaryExpr:
+		return "BinaryExpr"
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+// label returns what a node holds besides its children, or an empty string
+func label(node Node) string {
+	switch n := node.(type) {
+	case *Import:
+		return strconv.Quote(n.Path)
+	case *TypeDenoter:
+		return n.Name
+	case *Ident:
+		return n.Name
+	case *IntegerLit:
+		return strconv.Itoa(n.Value)
+	case *FloatLit:
+		return strconv.FormatFloat(n.Value, 'g', -1, 64)
+	case *StringLit:
+		return strconv.Quote(n.Value)
+	case *BinaryExpr:
+		return n.Op.String()
+	}
+	return ""
+}
+
+// Sprint returns the tree below node as an S-expression, one construct per
+// line
+func Sprint(node Node) string {
+	var sb strings.Builder
+	sprint(&sb, node, 0)
+	return sb.String()
+}
+
+func sprint(sb *strings.Builder, node Node, level int) {
+	sb.WriteString(strings.Repeat("    ", level))
+	sb.WriteString(ConstructName(node))
+	if label := label(node); label != "" {
+		sb.WriteString(" " + label)
+	}
+
+	children := children(node)
+	if len(children) > 0 {
+		sb.WriteString(" (")
+		for _, child := range children {
+			sb.WriteString("\n")
+			sprint(sb, child, level+1)
+		}
+		sb.WriteString(")")
+	}
+}
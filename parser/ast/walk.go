@@ -0,0 +1,433 @@
+package ast
+
+import (
+	"fmt"
+)
+
+// A Visitor is called for every node Walk reaches, Enter before the children
+// of the node and Leave after them
+type Visitor interface {
+	// Enter returns the visitor for the children of node, they are skipped
+	// when it returns nil
+	Enter(node Node) (w Visitor)
+	// Leave is called on the visitor that entered node once its children
+	// have been walked, it isn't called when Enter returned nil
+	Leave(node Node)
+}
+
+// Walk traverses the tree below node depth first, in source order
+func Walk(v Visitor, node Node) {
+	w := v.Enter(node)
+	if w == nil {
+		return
+	}
+	for _, child := range children(node) {
+		Walk(w, child)
+	}
+	v.Leave(node)
+}
+
+type inspector func(node Node) bool
+
+func (f inspector) Enter(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+func (f inspector) Leave(node Node) {
+	f(nil)
+}
+
+// Inspect traverses the tree below node depth first, in source order. It
+// calls f(node) and walks the children of node when f returns true, followed
+// by a call of f(nil).
+func Inspect(node Node, f func(node Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// children returns the nodes right below node in source order
+func children(node Node) []Node {
+	out := []Node{}
+	switch n := node.(type) {
+	case *Program:
+		{
+			for _, imp := range n.Imports {
+				out = append(out, imp)
+			}
+			if n.Body != nil {
+				out = append(out, n.Body)
+			}
+			if n.Module != nil {
+				out = append(out, n.Module)
+			}
+		}
+	case *Module:
+		for _, decl := range n.Decls {
+			out = append(out, decl)
+		}
+	case *AssignCommand:
+		out = append(out, n.Name, n.Value)
+	case *CallCommand:
+		{
+			out = append(out, n.Name)
+			for _, arg := range n.Args {
+				out = append(out, arg)
+			}
+		}
+	case *IfCommand:
+		out = append(out, n.Cond, n.Then, n.Else)
+	case *WhileCommand:
+		out = append(out, n.Cond, n.Body)
+	case *LetCommand:
+		{
+			for _, decl := range n.Decls {
+				out = append(out, decl)
+			}
+			out = append(out, n.Body)
+		}
+	case *BlockCommand:
+		for _, command := range n.Commands {
+			out = append(out, command)
+		}
+	case *ConstDecl:
+		out = append(out, n.Name, n.Value)
+	case *VarDecl:
+		out = append(out, n.Name, n.Type)
+	case *ExportDecl:
+		out = append(out, n.Decl)
+	case *CallExpr:
+		{
+			out = append(out, n.Name)
+			for _, arg := range n.Args {
+				out = append(out, arg)
+			}
+		}
+	case *ParenExpr:
+		out = append(out, n.X)
+	case *BinaryExpr:
+		out = append(out, n.X, n.Y)
+	}
+	return out
+}
+
+// positioned is implemented by every node, it lets Rewrite and Reposition
+// move positions
+type positioned interface {
+	pos() *Position
+}
+
+// Rewrite traverses the tree below node depth first and replaces every node
+// by what fn returns for it, the children of a node are rewritten before the
+// node itself. The tree is changed in place and the replacement of node is
+// returned.
+//
+// fn returns its argument to keep a node. A replacement without a position
+// takes the one of the node it replaces, so errors keep pointing at the
+// source. Returning nil removes a node from the commands of a block, the
+// declarations of a let or module, the imports of a program and the
+// arguments of a call, anywhere else it panics as does returning a node of
+// the wrong kind, like an expression in place of a command.
+func Rewrite(node Node, fn func(node Node) Node) Node {
+	r := &rewriter{fn: fn}
+	return r.node(node)
+}
+
+type rewriter struct {
+	fn func(node Node) Node
+}
+
+// node rewrites the children of node and then node itself
+func (r *rewriter) node(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		{
+			n.Imports = list(r, n, n.Imports)
+			if n.Body != nil {
+				n.Body = one(r, n, n.Body)
+			}
+			if n.Module != nil {
+				n.Module = one(r, n, n.Module)
+			}
+		}
+	case *Module:
+		n.Decls = list(r, n, n.Decls)
+	case *AssignCommand:
+		{
+			n.Name = one(r, n, n.Name)
+			n.Value = one(r, n, n.Value)
+		}
+	case *CallCommand:
+		{
+			n.Name = one(r, n, n.Name)
+			n.Args = list(r, n, n.Args)
+		}
+	case *IfCommand:
+		{
+			n.Cond = one(r, n, n.Cond)
+			n.Then = one(r, n, n.Then)
+			n.Else = one(r, n, n.Else)
+		}
+	case *WhileCommand:
+		{
+			n.Cond = one(r, n, n.Cond)
+			n.Body = one(r, n, n.Body)
+		}
+	case *LetCommand:
+		{
+			n.Decls = list(r, n, n.Decls)
+			n.Body = one(r, n, n.Body)
+		}
+	case *BlockCommand:
+		n.Commands = list(r, n, n.Commands)
+	case *ConstDecl:
+		{
+			n.Name = one(r, n, n.Name)
+			n.Value = one(r, n, n.Value)
+		}
+	case *VarDecl:
+		{
+			n.Name = one(r, n, n.Name)
+			n.Type = one(r, n, n.Type)
+		}
+	case *ExportDecl:
+		n.Decl = one(r, n, n.Decl)
+	case *CallExpr:
+		{
+			n.Name = one(r, n, n.Name)
+			n.Args = list(r, n, n.Args)
+		}
+	case *ParenExpr:
+		n.X = one(r, n, n.X)
+	case *BinaryExpr:
+		{
+			n.X = one(r, n, n.X)
+			n.Y = one(r, n, n.Y)
+		}
+	}
+
+	out := r.fn(node)
+	if out == nil || out == node {
+		return out
+	}
+	if p, ok := out.(positioned); ok && *p.pos() == (Position{}) {
+		*p.pos() = node.Position()
+	}
+	return out
+}
+
+// replace rewrites a child of parent and checks its replacement fits where
+// the child was, ok is false when the child was removed
+func replace[T Node](r *rewriter, parent Node, child T) (replacement T, ok bool) {
+	out := r.node(child)
+	if out == nil {
+		return replacement, false
+	}
+	replacement, ok = out.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %s can't replace %s in %s", ConstructName(out), ConstructName(child), ConstructName(parent)))
+	}
+	return replacement, true
+}
+
+// one rewrites a child of parent that can't be removed
+func one[T Node](r *rewriter, parent Node, child T) T {
+	out, ok := replace(r, parent, child)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %s can't be removed from %s", ConstructName(child), ConstructName(parent)))
+	}
+	return out
+}
+
+// list rewrites the children of parent held in a list, leaving out the
+// removed ones
+func list[T Node](r *rewriter, parent Node, children []T) []T {
+	out := children[:0]
+	for _, child := range children {
+		if replacement, ok := replace(r, parent, child); ok {
+			out = append(out, replacement)
+		}
+	}
+	return out
+}
+
+// Copy returns a deep copy of the tree below node, nothing is shared with
+// the original
+func Copy(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		{
+			c := *n
+			c.Imports = copyList(n.Imports)
+			if n.Body != nil {
+				c.Body = Copy(n.Body).(Command)
+			}
+			if n.Module != nil {
+				c.Module = Copy(n.Module).(*Module)
+			}
+			return &c
+		}
+	case *Import:
+		{
+			c := *n
+			return &c
+		}
+	case *Module:
+		{
+			c := *n
+			c.Decls = copyList(n.Decls)
+			return &c
+		}
+	case *SkipCommand:
+		{
+			c := *n
+			return &c
+		}
+	case *AssignCommand:
+		{
+			c := *n
+			c.Name = copyOf(n.Name)
+			c.Value = copyOf(n.Value)
+			return &c
+		}
+	case *CallCommand:
+		{
+			c := *n
+			c.Name = copyOf(n.Name)
+			c.Args = copyList(n.Args)
+			return &c
+		}
+	case *IfCommand:
+		{
+			c := *n
+			c.Cond = copyOf(n.Cond)
+			c.Then = copyOf(n.Then)
+			c.Else = copyOf(n.Else)
+			return &c
+		}
+	case *WhileCommand:
+		{
+			c := *n
+			c.Cond = copyOf(n.Cond)
+			c.Body = copyOf(n.Body)
+			return &c
+		}
+	case *LetCommand:
+		{
+			c := *n
+			c.Decls = copyList(n.Decls)
+			c.Body = copyOf(n.Body)
+			return &c
+		}
+	case *BlockCommand:
+		{
+			c := *n
+			c.Commands = copyList(n.Commands)
+			return &c
+		}
+	case *ConstDecl:
+		{
+			c := *n
+			c.Name = copyOf(n.Name)
+			c.Value = copyOf(n.Value)
+			return &c
+		}
+	case *VarDecl:
+		{
+			c := *n
+			c.Name = copyOf(n.Name)
+			c.Type = copyOf(n.Type)
+			return &c
+		}
+	case *ExportDecl:
+		{
+			c := *n
+			c.Decl = copyOf(n.Decl)
+			return &c
+		}
+	case *TypeDenoter:
+		{
+			c := *n
+			return &c
+		}
+	case *Ident:
+		{
+			c := *n
+			return &c
+		}
+	case *IntegerLit:
+		{
+			c := *n
+			return &c
+		}
+	case *FloatLit:
+		{
+			c := *n
+			return &c
+		}
+	case *StringLit:
+		{
+			c := *n
+			return &c
+		}
+	case *CallExpr:
+		{
+			c := *n
+			c.Name = copyOf(n.Name)
+			c.Args = copyList(n.Args)
+			return &c
+		}
+	case *ParenExpr:
+		{
+			c := *n
+			c.X = copyOf(n.X)
+			return &c
+		}
+	case *BinaryExpr:
+		{
+			c := *n
+			c.X = copyOf(n.X)
+			c.Y = copyOf(n.Y)
+			return &c
+		}
+	}
+	return node
+}
+
+// copyOf copies a child of a node, a child missing from a tree built by hand
+// stays missing instead of making the copy panic
+func copyOf[T Node](node T) T {
+	var zero T
+	if Node(node) == nil {
+		return zero
+	}
+	return Copy(node).(T)
+}
+
+// copyList copies a list of children, a nil list stays nil
+func copyList[T Node](nodes []T) []T {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]T, len(nodes))
+	for i, node := range nodes {
+		out[i] = copyOf(node)
+	}
+	return out
+}
+
+// Reposition replaces every position in the tree below node, including the
+// ones of operators, by what move returns for it. The tree is changed in
+// place.
+func Reposition(node Node, move func(pos Position) Position) {
+	Inspect(node, func(node Node) bool {
+		if p, ok := node.(positioned); ok {
+			*p.pos() = move(*p.pos())
+		}
+		if binary, ok := node.(*BinaryExpr); ok {
+			binary.OpPos = move(binary.OpPos)
+		}
+		return true
+	})
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// SyntaxError is returned when the parser finds a token the grammar doesn't
+// allow where it is
+type SyntaxError struct {
+	Pos ast.Position
+	Got *tokenizer.Token
+	// Expected lists the tokens that could have been there, it may be empty
+	Expected []tokenizer.TokenType
+	// Hint explains a common mistake the error looks like, Replacement is
+	// the text that should take the place of the token then
+	Hint        string
+	Replacement string
+}
+
+// hint looks for common mistakes in the error: a misspelled keyword, '=' or
+// '==' giving a constant its value and '==' assigning one
+func (e *SyntaxError) hint() {
+	switch e.Got.Type {
+	case tokenizer.Identifier:
+		{
+			// the expected keywords come first, any keyword will do when
+			// an identifier can't be there
+			expected, others := []string{}, []string{}
+			for keyword, token := range tokenizer.Keywords() {
+				if slices.Contains(e.Expected, token) {
+					expected = append(expected, keyword)
+				} else {
+					others = append(others, keyword)
+				}
+			}
+			sort.Strings(expected)
+			sort.Strings(others)
+			keyword := diag.Closest(e.Got.Value, expected)
+			if keyword == "" && !slices.Contains(e.Expected, tokenizer.Identifier) {
+				keyword = diag.Closest(e.Got.Value, others)
+			}
+			if keyword != "" {
+				e.Hint = fmt.Sprintf("did you mean '%s'?", keyword)
+				e.Replacement = keyword
+			}
+		}
+	case tokenizer.Equals, tokenizer.Comparison:
+		{
+			switch {
+			case slices.Contains(e.Expected, tokenizer.Tilde):
+				e.Hint = "constants are given their value with '~'"
+				e.Replacement = "~"
+			case e.Got.Type == tokenizer.Comparison && slices.Contains(e.Expected, tokenizer.Equals):
+				e.Hint = "'=' assigns a value, '==' compares two"
+				e.Replacement = "="
+			}
+		}
+	}
+}
+
+// message returns the error without its position
+func (e *SyntaxError) message() string {
+	got := tokenizer.TokenNames[e.Got.Type]
+	switch len(e.Expected) {
+	case 0:
+		return fmt.Sprintf("unexpected token '%s'", got)
+	case 1:
+		return fmt.Sprintf("unexpected token '%s' expected '%s'", got, tokenizer.TokenNames[e.Expected[0]])
+	}
+	expected := Map(e.Expected, func(token tokenizer.TokenType) string {
+		return fmt.Sprintf("'%s'", tokenizer.TokenNames[token])
+	})
+	return fmt.Sprintf("unexpected token '%s' expected one of %s", got, strings.Join(expected, ", "))
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Hint != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Pos, e.message(), e.Hint)
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.message())
+}
+
+// Diagnostic describes the error with a span covering the unexpected token,
+// the hint becomes a suggestion
+func (e *SyntaxError) Diagnostic() *diag.Diagnostic {
+	start, end := e.Got.GetSpan()
+	d := diag.Errorf(e.Pos, "%s", e.message())
+	d.Primary.Len = end - start
+	if e.Hint != "" {
+		d.WithSuggestion(d.Primary, e.Replacement, "%s", e.Hint)
+	}
+	return d
+}
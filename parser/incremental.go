@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+// span holds the tokens a single command was parsed from, end is the index
+// of the token after it
+type span struct {
+	start, end int
+}
+
+// The reusable structure is a command of a previous tree whose tokens are
+// the same after an edit, shift is how many tokens the edit moved them and
+// move, when it isn't nil, where it moved their positions
+type reusable struct {
+	node  ast.Command
+	spans map[ast.Command]span
+	shift int
+	move  func(pos ast.Position) ast.Position
+}
+
+// reuse takes the command of a previous tree starting at the current token,
+// ok is false when there is none. A command moved by the edit is copied so
+// the previous tree keeps its positions.
+func (p *Parser) reuse() (ast.Command, bool) {
+	r, ok := p.reusable[p.currentToken]
+	if !ok {
+		return nil, false
+	}
+
+	node := r.node
+	if r.move != nil {
+		node = ast.Copy(node).(ast.Command)
+		ast.Reposition(node, r.move)
+	}
+	// the commands below are reused with it, they keep their spans for the
+	// next edit
+	old := commands(r.node)
+	for i, command := range commands(node) {
+		s := r.spans[old[i]]
+		p.spans[command] = span{start: s.start + r.shift, end: s.end + r.shift}
+	}
+	p.currentToken = p.spans[node].end
+	return node, true
+}
+
+// commands returns the commands below node in source order, node included
+func commands(node ast.Command) []ast.Command {
+	out := []ast.Command{}
+	ast.Inspect(node, func(node ast.Node) bool {
+		if command, ok := node.(ast.Command); ok {
+			out = append(out, command)
+		}
+		return true
+	})
+	return out
+}
+
+// The Tree structure is a parsed source meant to be edited, like the file
+// open in an editor. Parsing it again after an edit reuses the tokens and
+// the single commands, like an unchanged begin ... end block, the edit
+// didn't touch, the result is the same as parsing the edited source from
+// scratch.
+type Tree struct {
+	Root   *ast.Program
+	name   string
+	src    string
+	tokens []*tokenizer.Token
+	spans  map[ast.Command]span
+}
+
+// ParseTree parses src as if it had been read from the named file
+func ParseTree(name, src string) (*Tree, error) {
+	lexer := tokenizer.FromString(name, src)
+	tokens, err := lexer.GetAllTokens()
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(&Parser{tokens: tokens, lexer: lexer}, name, src)
+}
+
+func parseTree(p *Parser, name, src string) (*Tree, error) {
+	p.spans = map[ast.Command]span{}
+	root, err := p.Program()
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{
+		Root:   root,
+		name:   name,
+		src:    src,
+		tokens: p.tokens,
+		spans:  p.spans,
+	}, nil
+}
+
+// Source returns the source the tree was parsed from
+func (t *Tree) Source() string {
+	return t.src
+}
+
+// Edit parses the source of the tree once edit is applied to it. The tree is
+// left as it was and can still be edited, as when the new source doesn't
+// parse, but the nodes it shares with the new tree must not be changed.
+func (t *Tree) Edit(edit tokenizer.Edit) (*Tree, error) {
+	src, err := edit.Apply(t.src)
+	if err != nil {
+		return nil, err
+	}
+	tokens, change, err := tokenizer.Relex(t.name, t.tokens, src, edit)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := change.NewSync - change.OldSync
+	move := func(pos ast.Position) ast.Position {
+		pos.Row, pos.Col = change.Move(pos.Row, pos.Col)
+		return pos
+	}
+	reusables := map[int]*reusable{}
+	for node, s := range t.spans {
+		if _, ok := node.(*ast.SkipCommand); ok {
+			continue
+		}
+		// a command is parsed from its tokens and the one after them, which
+		// tells where it ends
+		var r *reusable
+		switch {
+		case s.end < change.First:
+			r = &reusable{node: node, spans: t.spans}
+		case s.start >= change.OldSync:
+			{
+				r = &reusable{node: node, spans: t.spans, shift: shift}
+				if change.Moved(node.Position().Row) {
+					r.move = move
+				}
+			}
+		default:
+			continue
+		}
+		// the outermost command starting at a token is the one the parser
+		// asks for
+		start := s.start + r.shift
+		if prev, ok := reusables[start]; ok && t.spans[prev.node].end >= s.end {
+			continue
+		}
+		reusables[start] = r
+	}
+
+	return parseTree(&Parser{
+		tokens:   tokens,
+		lexer:    tokenizer.FromString(t.name, src),
+		reusable: reusables,
+	}, t.name, src)
+}
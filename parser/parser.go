@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"strconv"
-	"strings"
 
 	"github.com/zSnails/alpha/parser/ast"
 	"github.com/zSnails/alpha/tokenizer"
@@ -15,6 +14,10 @@ type Parser struct {
 	tokens       []*tokenizer.Token
 	currentToken int
 	lexer        *tokenizer.Tokenizer
+	// spans records the tokens of every single command when it isn't nil,
+	// reusable holds the commands of a previous tree by their first token
+	spans    map[ast.Command]span
+	reusable map[int]*reusable
 }
 
 // getCurrentoken returns the current token to be worked on
@@ -58,11 +61,46 @@ func (p *Parser) advance() {
 	p.currentToken++
 }
 
+// position returns the position of the token inside of the file being parsed
+func (p *Parser) position(token *tokenizer.Token) ast.Position {
+	row, col := token.GetPosition()
+	return ast.Position{File: p.lexer.GetFileName(), Row: row, Col: col}
+}
+
+// ident returns an identifier for the token
+func (p *Parser) ident(token *tokenizer.Token) *ast.Ident {
+	return &ast.Ident{Pos: p.position(token), Name: token.Value}
+}
+
 // Program parses the basic program construct
 //
-//	program ::= singleCommand
-func (p *Parser) Program() (*ast.Node, error) {
-	node, err := p.SingleCommand()
+//	program ::= (import String)* (singleCommand | module)
+func (p *Parser) Program() (*ast.Program, error) {
+	currentToken, err := p.getCurrentToken()
+	if err != nil {
+		return nil, err
+	}
+	node := &ast.Program{Pos: p.position(currentToken)}
+
+	for p.tokensLeft() && p.mustGetCurrentToken().Type == tokenizer.Import {
+		importToken := p.mustGetCurrentToken()
+		p.advance()
+		next, err := p.getCurrentToken()
+		if err != nil {
+			return nil, err
+		}
+		if next.Type != tokenizer.String {
+			return nil, p.UnexpectedToken(next, tokenizer.String)
+		}
+		p.advance()
+		node.Imports = append(node.Imports, &ast.Import{Pos: p.position(importToken), Path: next.Value[1:]})
+	}
+
+	if isOneOf(p.mustGetCurrentToken(), tokenizer.Const, tokenizer.Var, tokenizer.Export) {
+		node.Module, err = p.Module()
+	} else {
+		node.Body, err = p.SingleCommand()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -73,25 +111,78 @@ func (p *Parser) Program() (*ast.Node, error) {
 	return node, nil
 }
 
+// Module parses the declarations of a file meant to be imported by others,
+// only the declarations marked with export are visible to the importers.
+//
+//	module ::= [export] singleDeclaration (; [export] singleDeclaration)*
+func (p *Parser) Module() (*ast.Module, error) {
+	node := &ast.Module{Pos: p.position(p.mustGetCurrentToken())}
+	for {
+		currentToken, err := p.getCurrentToken()
+		if err != nil {
+			return nil, err
+		}
+
+		exported := currentToken.Type == tokenizer.Export
+		if exported {
+			p.advance()
+		}
+
+		single, err := p.SingleDeclaration()
+		if err != nil {
+			return nil, err
+		}
+
+		if exported {
+			single = &ast.ExportDecl{Pos: p.position(currentToken), Decl: single}
+		}
+		node.Decls = append(node.Decls, single)
+
+		if !p.tokensLeft() || p.mustGetCurrentToken().Type != tokenizer.Semicolon {
+			return node, nil
+		}
+		p.advance()
+	}
+}
+
 // SingleCommand parses the basic singleCommand construct
 //
 //	singleCommand ::=
-//	         Identifier (= expression | (expression))
+//	         Identifier (= expression | arguments)
 //	        | if expression then singleCommand
 //	        | while expression do singleCommand
 //	        | let declaration in singleCommand
 //	        | begin command end
-func (p *Parser) SingleCommand() (*ast.Node, error) {
-	node := ast.NewNode(ast.SingleCommand, nil)
+//	        | ε
+func (p *Parser) SingleCommand() (ast.Command, error) {
+	start := p.currentToken
+	if node, ok := p.reuse(); ok {
+		return node, nil
+	}
+	node, err := p.singleCommand()
+	if err == nil && p.spans != nil {
+		p.spans[node] = span{start: start, end: p.currentToken}
+	}
+	return node, err
+}
+
+func (p *Parser) singleCommand() (ast.Command, error) {
 	currentToken, err := p.getCurrentToken() // this error will always be io.EOF
 	if err != nil {
 		return nil, err
 	}
+	pos := p.position(currentToken)
 
 	switch currentToken.Type {
+	case tokenizer.Semicolon, tokenizer.End, tokenizer.Else, tokenizer.EOF:
+		{
+			// The empty command doesn't consume anything, it just lets
+			// blocks like `begin end` or `begin print("a"); end` through.
+			return &ast.SkipCommand{Pos: pos}, nil
+		}
 	case tokenizer.Identifier:
 		{
-			node.AddChild(ast.NewNode(ast.Identifier, currentToken.Value))
+			name := p.ident(currentToken)
 			p.advance()
 			next, err := p.getCurrentToken()
 			if err != nil {
@@ -100,14 +191,12 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 			switch next.Type {
 			case tokenizer.Equals:
 				{
-					node.AddChild(ast.NewNode(ast.Equals, next.Value))
 					p.advance()
 					expressionNode, err := p.Expression()
 					if err != nil {
 						return nil, err
 					}
-					node.AddChild(expressionNode)
-					return node, nil
+					return &ast.AssignCommand{Pos: pos, Name: name, Value: expressionNode}, nil
 				}
 			case tokenizer.LeftParenthesis:
 				{
@@ -118,34 +207,29 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 							tokenizer.RightParenthesis, tokenizer.Integer,
 							tokenizer.Float, tokenizer.String)
 					}
-					print()
+					node := &ast.CallCommand{Pos: pos, Name: name}
 					if next.Type == tokenizer.RightParenthesis {
 						p.advance()
 						return node, nil
 					}
 
-					expressionNode, err := p.Expression()
+					node.Args, err = p.arguments()
 					if err != nil {
 						return nil, err
 					}
-					err = p.expect(tokenizer.RightParenthesis)
-					if err != nil {
-						return nil, err
-					}
-					node.AddChild(expressionNode)
 					return node, nil
 				}
+			case tokenizer.Comparison:
+				return nil, p.UnexpectedToken(next, tokenizer.Equals, tokenizer.LeftParenthesis)
 			}
 		}
 	case tokenizer.If:
 		{
-			node.AddChild(ast.NewNode(ast.If, nil))
 			p.advance()
 			expressionNode, err := p.Expression()
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(expressionNode)
 			err = p.expect(tokenizer.Then)
 			if err != nil {
 				return nil, err
@@ -154,7 +238,6 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(ifBlockSingleCommand)
 			err = p.expect(tokenizer.Else)
 			if err != nil {
 				return nil, err
@@ -163,18 +246,15 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(elseBlockSingleCommand)
-			return node, nil
+			return &ast.IfCommand{Pos: pos, Cond: expressionNode, Then: ifBlockSingleCommand, Else: elseBlockSingleCommand}, nil
 		}
 	case tokenizer.While:
 		{
-			node.AddChild(ast.NewNode(ast.While, nil))
 			p.advance()
 			while, err := p.Expression()
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(while)
 			err = p.expect(tokenizer.Do)
 			if err != nil {
 				return nil, err
@@ -183,20 +263,17 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(singleCommand)
-			return node, nil
+			return &ast.WhileCommand{Pos: pos, Cond: while, Body: singleCommand}, nil
 		}
 
 	case tokenizer.Let:
 		{
-			node.AddChild(ast.NewNode(ast.Let, nil))
 			p.advance()
 
 			declaration, err := p.Declaration()
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(declaration)
 
 			err = p.expect(tokenizer.In)
 			if err != nil {
@@ -208,8 +285,7 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 				return nil, err
 			}
 
-			node.AddChild(singleCommand)
-			return node, nil
+			return &ast.LetCommand{Pos: pos, Decls: declaration, Body: singleCommand}, nil
 		}
 	case tokenizer.Begin:
 		{
@@ -218,12 +294,11 @@ func (p *Parser) SingleCommand() (*ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(command)
 			err = p.expect(tokenizer.End)
 			if err != nil {
 				return nil, err
 			}
-			return node, nil
+			return &ast.BlockCommand{Pos: pos, Commands: command}, nil
 		}
 	}
 	return nil, p.UnexpectedToken(currentToken, tokenizer.Begin, tokenizer.Let, tokenizer.While, tokenizer.If, tokenizer.Identifier)
@@ -241,31 +316,56 @@ var EOF = &tokenizer.Token{
 	Type: tokenizer.EOF,
 }
 
+// UnexpectedToken returns a *SyntaxError for a token that isn't one of the
+// expected ones, a nil token is the end of the file
 func (p *Parser) UnexpectedToken(got *tokenizer.Token, expected ...tokenizer.TokenType) error {
-	row, col := got.GetPosition()
-	if len(expected) > 1 {
-		tokens := Map(expected, func(token tokenizer.TokenType) string {
-			return fmt.Sprintf("'%s'", tokenizer.TokenNames[token])
-		})
-		expectedTokens := strings.Join(tokens, ", ")
-
-		return fmt.Errorf("%s:%d:%d: unexpected token '%s' expected one of %s", p.lexer.GetFileName(), row, col, tokenizer.TokenNames[got.Type], expectedTokens)
-	} else if len(expected) == 1 {
-		return fmt.Errorf("%s:%d:%d: unexpected token '%s' expected '%s'\n", p.lexer.GetFileName(), row, col, tokenizer.TokenNames[got.Type], tokenizer.TokenNames[expected[0]])
-	}
-	return fmt.Errorf("%s:%d:%d: unexpected token '%s'\n", p.lexer.GetFileName(), row, col, tokenizer.TokenNames[got.Type])
+	if got == nil {
+		got = p.tokens[len(p.tokens)-1]
+	}
+	err := &SyntaxError{
+		Pos:      p.position(got),
+		Got:      got,
+		Expected: expected,
+	}
+	err.hint()
+	return err
+}
+
+// arguments parses the arguments of a call, the opening parenthesis is
+// expected to be consumed already
+//
+//	arguments ::= ( [expression (, expression)*] )
+func (p *Parser) arguments() ([]ast.Expression, error) {
+	args := []ast.Expression{}
+	if p.mustGetCurrentToken().Type != tokenizer.RightParenthesis {
+		for {
+			expressionNode, err := p.Expression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, expressionNode)
+
+			if !p.tokensLeft() || p.mustGetCurrentToken().Type != tokenizer.Comma {
+				break
+			}
+			p.advance()
+		}
+	}
+	return args, p.expect(tokenizer.RightParenthesis)
 }
 
 // Declaration parses the basic declaration construct
 //
 // declaration ::= singleDeclaration (; singleDeclaration)*
-func (p *Parser) Declaration() (*ast.Node, error) {
-	node := ast.NewNode(ast.Declaration, nil)
+func (p *Parser) Declaration() ([]ast.Declaration, error) {
+	if _, err := p.getCurrentToken(); err != nil {
+		return nil, err
+	}
 	singleDeclaration, err := p.SingleDeclaration()
 	if err != nil {
 		return nil, err
 	}
-	node.AddChild(singleDeclaration)
+	decls := []ast.Declaration{singleDeclaration}
 
 	for p.tokensLeft() && p.mustGetCurrentToken().Type == tokenizer.Semicolon {
 		p.advance()
@@ -273,10 +373,10 @@ func (p *Parser) Declaration() (*ast.Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		node.AddChild(single)
+		decls = append(decls, single)
 	}
 
-	return node, nil
+	return decls, nil
 }
 
 // SingleDeclaration parses the basic singleDeclaration construct
@@ -284,16 +384,15 @@ func (p *Parser) Declaration() (*ast.Node, error) {
 //	singleDeclaration ::=
 //	         const Identifier ~ expression
 //	       | var identifier : typeDenoter
-func (p *Parser) SingleDeclaration() (*ast.Node, error) {
+func (p *Parser) SingleDeclaration() (ast.Declaration, error) {
 	currentToken, err := p.getCurrentToken()
 	if err != nil {
 		return nil, err
 	}
-	node := ast.NewNode(ast.SingleDeclaration, nil)
+	pos := p.position(currentToken)
 	switch currentToken.Type {
 	case tokenizer.Const:
 		{
-			node.AddChild(ast.NewNode(ast.Const, nil))
 			p.advance()
 			next, err := p.getCurrentToken()
 			if err != nil {
@@ -303,7 +402,7 @@ func (p *Parser) SingleDeclaration() (*ast.Node, error) {
 				return nil, p.UnexpectedToken(currentToken, tokenizer.Identifier)
 			}
 			p.advance()
-			node.AddChild(ast.NewNode(ast.Identifier, next.Value))
+			name := p.ident(next)
 
 			err = p.expect(tokenizer.Tilde)
 			if err != nil {
@@ -315,12 +414,10 @@ func (p *Parser) SingleDeclaration() (*ast.Node, error) {
 				return nil, err
 			}
 
-			node.AddChild(expression)
-			return node, nil
+			return &ast.ConstDecl{Pos: pos, Name: name, Value: expression}, nil
 		}
 	case tokenizer.Var:
 		{
-			node.AddChild(ast.NewNode(ast.Var, nil))
 			p.advance()
 
 			next, err := p.getCurrentToken()
@@ -330,7 +427,7 @@ func (p *Parser) SingleDeclaration() (*ast.Node, error) {
 			if next.Type != tokenizer.Identifier {
 				return nil, p.UnexpectedToken(currentToken, tokenizer.Identifier)
 			}
-			node.AddChild(ast.NewNode(ast.Identifier, next.Value))
+			name := p.ident(next)
 			p.advance()
 			err = p.expect(tokenizer.Colon)
 			if err != nil {
@@ -340,30 +437,52 @@ func (p *Parser) SingleDeclaration() (*ast.Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			node.AddChild(typeDenoter)
-			return node, nil
+			return &ast.VarDecl{Pos: pos, Name: name, Type: typeDenoter}, nil
 		}
 	}
-	return nil, nil
+	return nil, p.UnexpectedToken(currentToken, tokenizer.Const, tokenizer.Var)
+}
+
+// outOfRange returns a *tokenizer.LexError for a numeric literal that
+// doesn't fit in its type
+func (p *Parser) outOfRange(token *tokenizer.Token, kind string) error {
+	start, _ := token.GetSpan()
+	return &tokenizer.LexError{
+		Pos:    p.position(token),
+		Offset: start,
+		Msg:    fmt.Sprintf("%s %s out of range", kind, token.Value),
+	}
 }
 
 func isOperator(token *tokenizer.Token) bool {
-	return isOneOf(token, tokenizer.PlusOperator, tokenizer.MinusOperator,
-		tokenizer.DivisionOperator, tokenizer.MultiplicationOperator, tokenizer.Equals, tokenizer.Comparison,
-		tokenizer.LessThan, tokenizer.GreaterThan, tokenizer.LessThanEqual, tokenizer.GreaterThanEqual)
+	_, ok := operators[token.Type]
+	return ok
+}
+
+var operators = map[tokenizer.TokenType]ast.Operator{
+	tokenizer.PlusOperator:           ast.Add,
+	tokenizer.MinusOperator:          ast.Sub,
+	tokenizer.MultiplicationOperator: ast.Mul,
+	tokenizer.DivisionOperator:       ast.Div,
+	tokenizer.LessThan:               ast.Less,
+	tokenizer.GreaterThan:            ast.Greater,
+	tokenizer.LessThanEqual:          ast.LessEqual,
+	tokenizer.GreaterThanEqual:       ast.GreaterEqual,
+	tokenizer.Equals:                 ast.Equals,
+	tokenizer.Comparison:             ast.Comparison,
 }
 
 // TypeDenoter parses the basic typeDenoter construct
 //
 // typeDenoter ::= Identifier
-func (p *Parser) TypeDenoter() (*ast.Node, error) {
+func (p *Parser) TypeDenoter() (*ast.TypeDenoter, error) {
 	currentToken, err := p.getCurrentToken()
 	if err != nil {
 		return nil, err
 	}
 	if currentToken.Type == tokenizer.Identifier {
 		p.advance()
-		return ast.NewNode(ast.TypeDenoter, currentToken.Value), nil
+		return &ast.TypeDenoter{Pos: p.position(currentToken), Name: currentToken.Value}, nil
 	}
 
 	return nil, p.UnexpectedToken(currentToken, tokenizer.Identifier)
@@ -373,30 +492,36 @@ func (p *Parser) tokensLeft() bool {
 	return p.currentToken < len(p.tokens)
 }
 
-// Expression parses the expression construct
+// Expression parses the expression construct, operators have no precedence
+// so every operation takes the ones to its left as its first operand
 //
 // expression ::= primaryExpression (operator primaryExpression)*
-func (p *Parser) Expression() (*ast.Node, error) {
-	node := ast.NewNode(ast.Expression, nil)
-	primaryExpressionNode, err := p.PrimaryExpression()
+func (p *Parser) Expression() (ast.Expression, error) {
+	if _, err := p.getCurrentToken(); err != nil {
+		return nil, err
+	}
+	node, err := p.PrimaryExpression()
 	if err != nil {
 		return nil, err
 	}
-	node.AddChild(primaryExpressionNode)
 
 	for p.tokensLeft() && isOperator(p.mustGetCurrentToken()) {
 		operator, err := p.getCurrentToken()
 		if err != nil {
 			return nil, err
 		}
-		operatorNode := ast.NewNode(ast.Operator, operator)
-		node.AddChild(operatorNode)
 		p.advance()
-		primaryExpressionNode, err = p.PrimaryExpression()
+		primaryExpressionNode, err := p.PrimaryExpression()
 		if err != nil {
 			return nil, err
 		}
-		node.AddChild(primaryExpressionNode)
+		node = &ast.BinaryExpr{
+			Pos:   node.Position(),
+			X:     node,
+			Op:    operators[operator.Type],
+			OpPos: p.position(operator),
+			Y:     primaryExpressionNode,
+		}
 	}
 
 	return node, nil
@@ -413,30 +538,31 @@ func isOneOf(token *tokenizer.Token, types ...tokenizer.TokenType) bool {
 
 // PrimaryExpression parses the basic primaryExpression construct
 //
-// primaryExpression ::= Literal | Identifier | ( expression )
-func (p *Parser) PrimaryExpression() (*ast.Node, error) {
+// primaryExpression ::= Literal | Identifier [arguments] | ( expression )
+func (p *Parser) PrimaryExpression() (ast.Expression, error) {
 	currentToken, err := p.getCurrentToken()
 	if err != nil {
 		return nil, err
 	}
+	pos := p.position(currentToken)
 	switch currentToken.Type {
 	case tokenizer.Integer:
 		{
 			p.advance()
 			value, err := strconv.Atoi(currentToken.Value)
 			if err != nil {
-				return nil, err
+				return nil, p.outOfRange(currentToken, "integer")
 			}
-			return ast.NewNode(ast.Integer, value), nil
+			return &ast.IntegerLit{Pos: pos, Value: value}, nil
 		}
 	case tokenizer.Float:
 		{
 			p.advance()
 			value, err := strconv.ParseFloat(currentToken.Value, 64)
 			if err != nil {
-				return nil, err
+				return nil, p.outOfRange(currentToken, "float")
 			}
-			return ast.NewNode(ast.Integer, value), nil
+			return &ast.FloatLit{Pos: pos, Value: value}, nil
 		}
 	case tokenizer.LeftParenthesis:
 		{
@@ -446,18 +572,28 @@ func (p *Parser) PrimaryExpression() (*ast.Node, error) {
 				return nil, err
 			}
 			err = p.expect(tokenizer.RightParenthesis)
-			return res, err
+			return &ast.ParenExpr{Pos: pos, X: res}, err
 		}
 
 	case tokenizer.Identifier:
 		{
 			p.advance()
-			return ast.NewNode(ast.Identifier, currentToken.Value), nil
+			identifier := p.ident(currentToken)
+			if !p.tokensLeft() || p.mustGetCurrentToken().Type != tokenizer.LeftParenthesis {
+				return identifier, nil
+			}
+
+			p.advance()
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &ast.CallExpr{Pos: pos, Name: identifier, Args: args}, nil
 		}
 	case tokenizer.String:
 		{
 			p.advance()
-			return ast.NewNode(ast.String, currentToken.Value[1:]), nil
+			return &ast.StringLit{Pos: pos, Value: currentToken.Value[1:]}, nil
 		}
 	}
 	return nil, p.UnexpectedToken(currentToken, tokenizer.Identifier, tokenizer.String, tokenizer.Integer, tokenizer.Float, tokenizer.LeftParenthesis)
@@ -466,14 +602,16 @@ func (p *Parser) PrimaryExpression() (*ast.Node, error) {
 // Command parses the basic command construct
 //
 //	command ::= singleCommand (; singleCommand)*
-func (p *Parser) Command() (*ast.Node, error) {
-	node := ast.NewNode(ast.Command, nil)
+func (p *Parser) Command() ([]ast.Command, error) {
+	if _, err := p.getCurrentToken(); err != nil {
+		return nil, err
+	}
 	singleCommand, err := p.SingleCommand()
 	if err != nil {
 		return nil, err
 	}
 
-	node.AddChild(singleCommand)
+	commands := []ast.Command{singleCommand}
 
 	for p.tokensLeft() && p.mustGetCurrentToken().Type == tokenizer.Semicolon {
 		p.advance()
@@ -482,7 +620,7 @@ func (p *Parser) Command() (*ast.Node, error) {
 			return nil, err
 		}
 
-		node.AddChild(single)
+		commands = append(commands, single)
 	}
-	return node, nil
+	return commands, nil
 }
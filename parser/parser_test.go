@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/tokenizer"
+)
+
+func parse(src string) (*ast.Program, error) {
+	p, err := NewParser(tokenizer.FromString("test.alpha", src))
+	if err != nil {
+		return nil, err
+	}
+	return p.Program()
+}
+
+func TestEmptyDeclaration(t *testing.T) {
+	tests := []struct {
+		src string
+		pos ast.Position
+	}{
+		{src: "let in println(1)", pos: ast.Position{Row: 1, Col: 5}},
+		{src: "let var x : Integer; in x = 1", pos: ast.Position{Row: 1, Col: 22}},
+		{src: "export ; const x ~ 1", pos: ast.Position{Row: 1, Col: 8}},
+		{src: "const x ~ 1;", pos: ast.Position{Row: 1, Col: 13}},
+	}
+	for _, test := range tests {
+		_, err := parse(test.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: got %v, want a syntax error", test.src, err)
+			continue
+		}
+		if syntaxErr.Pos.Row != test.pos.Row || syntaxErr.Pos.Col != test.pos.Col {
+			t.Errorf("%q: error at %s, want %d:%d", test.src, syntaxErr.Pos, test.pos.Row, test.pos.Col)
+		}
+	}
+}
+
+func TestDeclarations(t *testing.T) {
+	root, err := parse("let var x : Integer; const y ~ 2 in x = y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	let := root.Body.(*ast.LetCommand)
+	if len(let.Decls) != 2 {
+		t.Fatalf("got %d declarations, want 2", len(let.Decls))
+	}
+	for i, decl := range let.Decls {
+		if decl == nil {
+			t.Errorf("declaration %d is nil", i)
+		}
+	}
+}
+
+func TestLiteralOutOfRange(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{src: "x = 99999999999999999999", msg: "integer 99999999999999999999 out of range"},
+		{src: "x = 1" + strings.Repeat("0", 400) + ".5", msg: "float 1" + strings.Repeat("0", 400) + ".5 out of range"},
+	}
+	for _, test := range tests {
+		_, err := parse(test.src)
+		var lexErr *tokenizer.LexError
+		if !errors.As(err, &lexErr) {
+			t.Errorf("%q: got %v, want a lexing error", test.src, err)
+			continue
+		}
+		if lexErr.Msg != test.msg || lexErr.Pos.Row != 1 || lexErr.Pos.Col != 5 || lexErr.Offset != 4 {
+			t.Errorf("%q: got %q at %s offset %d", test.src, lexErr.Msg, lexErr.Pos, lexErr.Offset)
+		}
+	}
+}
+
+func TestComparisonOperators(t *testing.T) {
+	tests := []struct {
+		src string
+		op  ast.Operator
+	}{
+		{src: "x = a <= b", op: ast.LessEqual},
+		{src: "x = a >= b", op: ast.GreaterEqual},
+		{src: "x = a < b", op: ast.Less},
+		{src: "x = a > b", op: ast.Greater},
+		{src: "x = a<=b", op: ast.LessEqual},
+	}
+	for _, test := range tests {
+		root, err := parse(test.src)
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		expr, ok := root.Body.(*ast.AssignCommand).Value.(*ast.BinaryExpr)
+		if !ok || expr.Op != test.op {
+			t.Errorf("%q: got %s, want the operator %s", test.src, ast.Sprint(root), test.op)
+		}
+	}
+
+	root, err := parse("if x >= 1 then y = 1 else y = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond, ok := root.Body.(*ast.IfCommand).Cond.(*ast.BinaryExpr); !ok || cond.Op != ast.GreaterEqual {
+		t.Errorf("got %s, want a >= condition", ast.Sprint(root))
+	}
+}
+
+// large returns a program of about size bytes
+func large(size int) string {
+	var sb strings.Builder
+	sb.WriteString("let var x : Integer in begin\n  x = 0;\n")
+	for sb.Len() < size {
+		sb.WriteString("  x = x + 1; // count\n  println(x, \"hola\");\n")
+	}
+	sb.WriteString("  x = 1\nend\n")
+	return sb.String()
+}
+
+func BenchmarkParseTree(b *testing.B) {
+	src := large(50_000)
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseTree("test.alpha", src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEdit(b *testing.B) {
+	src := large(50_000)
+	tree, err := ParseTree("test.alpha", src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	edit := tokenizer.Edit{Start: len(src) / 2, End: len(src) / 2, Text: " "}
+	for i := 0; i < b.N; i++ {
+		if _, err := tree.Edit(edit); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
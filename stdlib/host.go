@@ -0,0 +1,162 @@
+package stdlib
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/zSnails/alpha/tokenizer"
+	"github.com/zSnails/alpha/types"
+)
+
+var errorType = reflect.TypeFor[error]()
+
+// RegisterFunc exposes a Go function to alpha programs under the given name,
+// its signature is derived from the Go one:
+//
+//   - int types map to Integer, float types to Float, string to String,
+//     bool to Boolean and any to any alpha value.
+//   - A variadic Go function is a variadic alpha function.
+//   - It can return nothing, a single value, an error, or a value and an
+//     error. Returning a non nil error stops the program with a runtime error.
+//
+// For example:
+//
+//	lib.RegisterFunc("fetchUser", func(id int) (string, error) { ... })
+//
+// lets programs write `name = fetchUser(42)`.
+func (l *Library) RegisterFunc(name string, fn any) error {
+	if !IsIdentifier(name) {
+		return fmt.Errorf("register %s: not a valid identifier", name)
+	}
+
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return fmt.Errorf("register %s: expected a function, got %T", name, fn)
+	}
+	if value.IsNil() {
+		return fmt.Errorf("register %s: nil function", name)
+	}
+
+	sig, err := signatureOf(value.Type())
+	if err != nil {
+		return fmt.Errorf("register %s: %w", name, err)
+	}
+
+	l.Define(&Function{
+		Name: name,
+		Sig:  sig,
+		Impl: reflectImpl(value),
+	})
+	return nil
+}
+
+// IsIdentifier reports whether the tokenizer reads the whole name as a
+// single identifier, which rules out keywords.
+func IsIdentifier(name string) bool {
+	token, err := tokenizer.NewTokenizer(name).GetNextToken()
+	return err == nil && token.Type == tokenizer.Identifier && token.Value == name
+}
+
+func signatureOf(fn reflect.Type) (types.Signature, error) {
+	sig := types.Signature{Variadic: fn.IsVariadic(), Result: types.Void}
+
+	for i := range fn.NumIn() {
+		in := fn.In(i)
+		if sig.Variadic && i == fn.NumIn()-1 {
+			in = in.Elem()
+		}
+		t := TypeOf(in)
+		if t == types.Invalid {
+			return sig, fmt.Errorf("unsupported parameter type %s", in)
+		}
+		sig.Params = append(sig.Params, t)
+	}
+
+	outs := fn.NumOut()
+	if outs > 0 && fn.Out(outs-1) == errorType {
+		outs--
+	}
+	switch outs {
+	case 0:
+	case 1:
+		{
+			sig.Result = TypeOf(fn.Out(0))
+			if sig.Result == types.Invalid || sig.Result == types.Any {
+				return sig, fmt.Errorf("unsupported result type %s", fn.Out(0))
+			}
+		}
+	default:
+		return sig, fmt.Errorf("too many results")
+	}
+	return sig, nil
+}
+
+// TypeOf maps Go types to the alpha type of their values, Invalid is
+// returned for the ones alpha can't represent
+func TypeOf(t reflect.Type) types.Type {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return types.Integer
+	case reflect.Float32, reflect.Float64:
+		return types.Float
+	case reflect.String:
+		return types.String
+	case reflect.Bool:
+		return types.Boolean
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return types.Any
+		}
+	}
+	return types.Invalid
+}
+
+// reflectImpl adapts a Go function to the builtin calling convention
+func reflectImpl(fn reflect.Value) Impl {
+	fnType := fn.Type()
+	return func(_ *IO, args []any) (result any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var param reflect.Type
+			if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+				param = fnType.In(fnType.NumIn() - 1).Elem()
+			} else {
+				param = fnType.In(i)
+			}
+			in[i] = reflect.ValueOf(arg).Convert(param)
+		}
+
+		out := fn.Call(in)
+		if len(out) > 0 && fnType.Out(len(out)-1) == errorType {
+			if e := out[len(out)-1]; !e.IsNil() {
+				return nil, e.Interface().(error)
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+		return ValueOf(out[0]), nil
+	}
+}
+
+// ValueOf converts a Go value into the representation used for alpha values
+func ValueOf(v reflect.Value) any {
+	switch TypeOf(v.Type()) {
+	case types.Integer:
+		return int(v.Int())
+	case types.Float:
+		return v.Float()
+	case types.String:
+		return v.String()
+	case types.Boolean:
+		return v.Bool()
+	}
+	return v.Interface()
+}
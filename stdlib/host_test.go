@@ -0,0 +1,143 @@
+package stdlib_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+)
+
+// run checks and runs src calling the builtins of lib
+func run(t *testing.T, lib *stdlib.Library, src string) (string, error) {
+	t.Helper()
+	program, err := loader.NewLoader(nil, lib.Scope(types.Universe())).LoadSource("test.alpha", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = interpreter.NewInterpreter(program.Info, lib, strings.NewReader(""), &out).Run(context.Background(), program.Root)
+	return out.String(), err
+}
+
+func TestRegisterUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   any
+		err  string
+	}{
+		{name: "slice", fn: func(s []int) {}, err: "register slice: unsupported parameter type []int"},
+		{name: "pointer", fn: func(p *int) {}, err: "register pointer: unsupported parameter type *int"},
+		{name: "unsigned", fn: func(u uint) {}, err: "register unsigned: unsupported parameter type uint"},
+		{name: "variadic", fn: func(s ...[]string) {}, err: "register variadic: unsupported parameter type []string"},
+		{name: "reader", fn: func(r interface{ Read([]byte) (int, error) }) {}, err: "register reader: unsupported parameter type interface { Read([]uint8) (int, error) }"},
+		{name: "result", fn: func() map[string]int { return nil }, err: "register result: unsupported result type map[string]int"},
+		{name: "anyResult", fn: func() any { return nil }, err: "register anyResult: unsupported result type interface {}"},
+		{name: "results", fn: func() (int, int) { return 0, 0 }, err: "register results: too many results"},
+		{name: "value", fn: 42, err: "register value: expected a function, got int"},
+		{name: "nothing", fn: nil, err: "register nothing: expected a function, got <nil>"},
+		{name: "nilFunc", fn: (func())(nil), err: "register nilFunc: nil function"},
+		{name: "while", fn: func() {}, err: "register while: not a valid identifier"},
+	}
+	for _, test := range tests {
+		lib := stdlib.NewLibrary()
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: panicked: %v", test.name, r)
+				}
+			}()
+			err = lib.RegisterFunc(test.name, test.fn)
+		}()
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: got %v, want %q", test.name, err, test.err)
+		}
+		if lib.Lookup(test.name) != nil {
+			t.Errorf("%s: registered despite the error", test.name)
+		}
+	}
+}
+
+var errNotFound = errors.New("user not found")
+
+func TestHostErrors(t *testing.T) {
+	lib := stdlib.Prelude()
+	err := lib.RegisterFunc("fetchUser", func(id int) (string, error) {
+		if id != 42 {
+			return "", errNotFound
+		}
+		return "Aaron", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.RegisterFunc("check", func(ok bool) error {
+		if !ok {
+			return errNotFound
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := run(t, lib, `begin println(fetchUser(42)); check(true) end`)
+	if err != nil || out != "Aaron\n" {
+		t.Errorf("printed %q and got %v", out, err)
+	}
+
+	tests := []struct {
+		src string
+		col int
+	}{
+		{src: `begin println(fetchUser(42)); println(fetchUser(7)) end`, col: 39},
+		{src: `begin println(fetchUser(42)); check(false) end`, col: 31},
+	}
+	for _, test := range tests {
+		out, err := run(t, lib, test.src)
+		var runtimeErr *interpreter.RuntimeError
+		if !errors.As(err, &runtimeErr) || !errors.Is(err, errNotFound) {
+			t.Errorf("%q: got %v, want a runtime error wrapping %v", test.src, err, errNotFound)
+			continue
+		}
+		if runtimeErr.Pos.Row != 1 || runtimeErr.Pos.Col != test.col || runtimeErr.Msg != errNotFound.Error() {
+			t.Errorf("%q: got %q at %s, want it at 1:%d", test.src, runtimeErr.Msg, runtimeErr.Pos, test.col)
+		}
+		// the output before the failing call is kept
+		if out != "Aaron\n" {
+			t.Errorf("%q: printed %q", test.src, out)
+		}
+	}
+}
+
+func TestHostVariadic(t *testing.T) {
+	lib := stdlib.Prelude()
+	if err := lib.RegisterFunc("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.RegisterFunc("sum", func(first float64, rest ...int8) float64 {
+		for _, n := range rest {
+			first += float64(n)
+		}
+		return first
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sig := lib.Lookup("join").Sig
+	if !sig.Variadic || len(sig.Params) != 2 || sig.Params[1] != types.String || sig.Result != types.String {
+		t.Errorf("join has the signature %+v", sig)
+	}
+
+	out, err := run(t, lib, `begin println(join("-")); println(join("-", "a")); println(join("-", "a", "b", "c")); println(sum(1.5, 1, 2)) end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\na\na-b-c\n4.5\n"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+}
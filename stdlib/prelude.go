@@ -0,0 +1,164 @@
+package stdlib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/types"
+)
+
+// Prelude returns a new library holding the builtins every program can use
+func Prelude() *Library {
+	lib := NewLibrary()
+	for _, fn := range prelude {
+		lib.Define(fn)
+	}
+	return lib
+}
+
+// Failures holds the messages the builtins of the prelude fail with, as
+// formats of the fmt package taking %d for an integer and %q for a string.
+// The runtimes of the compiled targets are generated from it so they print
+// the text of the interpreter.
+var Failures = map[string]string{
+	"readInt": "readInt: invalid integer %q",
+	"substr":  "substr: %d bytes from %d out of range with length %d",
+}
+
+// Printf returns a failure as a format of printf in C, which takes long long
+// integers and strings already quoted the way %q quotes them
+func Printf(name string) string {
+	return strings.NewReplacer("%d", "%lld", "%q", "%s").Replace(Failures[name])
+}
+
+var prelude = []*Function{
+	{
+		Name: "print",
+		Sig:  types.Signature{Params: []types.Type{types.Any}, Variadic: true, Result: types.Void},
+		Impl: func(io *IO, args []any) (any, error) {
+			return nil, write(io, args, "")
+		},
+	},
+	{
+		Name: "println",
+		Sig:  types.Signature{Params: []types.Type{types.Any}, Variadic: true, Result: types.Void},
+		Impl: func(io *IO, args []any) (any, error) {
+			return nil, write(io, args, "\n")
+		},
+	},
+	{
+		Name: "readLine",
+		Sig:  types.Signature{Result: types.String},
+		Impl: func(io *IO, args []any) (any, error) {
+			return readLine(io)
+		},
+	},
+	{
+		Name: "readInt",
+		Sig:  types.Signature{Result: types.Integer},
+		Impl: func(io *IO, args []any) (any, error) {
+			line, err := readLine(io)
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.Atoi(strings.TrimSpace(line))
+			if err != nil {
+				return nil, fmt.Errorf(Failures["readInt"], line)
+			}
+			return value, nil
+		},
+	},
+	{
+		Name: "length",
+		Sig:  types.Signature{Params: []types.Type{types.String}, Result: types.Integer},
+		Impl: func(io *IO, args []any) (any, error) {
+			return len(args[0].(string)), nil
+		},
+	},
+	{
+		Name: "concat",
+		Sig:  types.Signature{Params: []types.Type{types.String, types.String}, Result: types.String},
+		Impl: func(io *IO, args []any) (any, error) {
+			return args[0].(string) + args[1].(string), nil
+		},
+	},
+	{
+		Name: "substr",
+		Sig:  types.Signature{Params: []types.Type{types.String, types.Integer, types.Integer}, Result: types.String},
+		Impl: func(io *IO, args []any) (any, error) {
+			s, start, n := args[0].(string), args[1].(int), args[2].(int)
+			// start+n could overflow, n is compared to what is left instead
+			if start < 0 || n < 0 || start > len(s) || n > len(s)-start {
+				return nil, fmt.Errorf(Failures["substr"], n, start, len(s))
+			}
+			return s[start : start+n], nil
+		},
+	},
+	{
+		Name: "toInt",
+		Sig:  types.Signature{Params: []types.Type{types.Float}, Result: types.Integer},
+		Impl: func(io *IO, args []any) (any, error) {
+			return int(args[0].(float64)), nil
+		},
+	},
+	{
+		Name: "toFloat",
+		Sig:  types.Signature{Params: []types.Type{types.Integer}, Result: types.Float},
+		Impl: func(io *IO, args []any) (any, error) {
+			return float64(args[0].(int)), nil
+		},
+	},
+	{
+		Name: "abs",
+		Sig:  types.Signature{Params: []types.Type{types.Float}, Result: types.Float},
+		Impl: func(io *IO, args []any) (any, error) {
+			return math.Abs(args[0].(float64)), nil
+		},
+	},
+	{
+		Name: "sqrt",
+		Sig:  types.Signature{Params: []types.Type{types.Float}, Result: types.Float},
+		Impl: func(io *IO, args []any) (any, error) {
+			return math.Sqrt(args[0].(float64)), nil
+		},
+	},
+	{
+		Name: "pow",
+		Sig:  types.Signature{Params: []types.Type{types.Float, types.Float}, Result: types.Float},
+		Impl: func(io *IO, args []any) (any, error) {
+			return math.Pow(args[0].(float64), args[1].(float64)), nil
+		},
+	},
+	{
+		Name: "exit",
+		Sig:  types.Signature{Params: []types.Type{types.Integer}, Result: types.Void},
+		Impl: func(io *IO, args []any) (any, error) {
+			return nil, &ExitError{Code: args[0].(int)}
+		},
+	},
+}
+
+func write(io *IO, args []any, end string) error {
+	var sb strings.Builder
+	for _, arg := range args {
+		sb.WriteString(Format(arg))
+	}
+	sb.WriteString(end)
+	_, err := fmt.Fprint(io.Out, sb.String())
+	return err
+}
+
+// readLine reads a line without its line terminator, an empty string is
+// returned once the input is exhausted
+func readLine(in *IO) (string, error) {
+	line, err := in.In.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
@@ -0,0 +1,34 @@
+package stdlib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubstr(t *testing.T) {
+	substr := Prelude().Lookup("substr")
+	tests := []struct {
+		start, n int
+		want     string
+		fails    bool
+	}{
+		{start: 0, n: 5, want: "hello"},
+		{start: 1, n: 3, want: "ell"},
+		{start: 5, n: 0, want: ""},
+		{start: 2, n: 4, fails: true},
+		{start: 6, n: 0, fails: true},
+		{start: -1, n: 1, fails: true},
+		{start: 1, n: -1, fails: true},
+		{start: 1, n: math.MaxInt, fails: true},
+		{start: math.MaxInt, n: math.MaxInt, fails: true},
+	}
+	for _, test := range tests {
+		got, err := substr.Impl(nil, []any{"hello", test.start, test.n})
+		switch {
+		case test.fails && err == nil:
+			t.Errorf("substr(%d, %d) = %q, want an error", test.start, test.n, got)
+		case !test.fails && (err != nil || got != test.want):
+			t.Errorf("substr(%d, %d) = %q, %v, want %q", test.start, test.n, got, err, test.want)
+		}
+	}
+}
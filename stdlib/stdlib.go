@@ -0,0 +1,111 @@
+package stdlib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/zSnails/alpha/types"
+)
+
+// IO is what builtins use to talk with the outside world
+type IO struct {
+	In  *bufio.Reader
+	Out io.Writer
+}
+
+func NewIO(in io.Reader, out io.Writer) *IO {
+	return &IO{
+		In:  bufio.NewReader(in),
+		Out: out,
+	}
+}
+
+// Impl implements a builtin, arguments are already converted to the types
+// in the signature: int for Integer, float64 for Float, string for String
+// and bool for Boolean. Functions returning Void return a nil value.
+type Impl func(io *IO, args []any) (any, error)
+
+// Function is a builtin procedure or function
+type Function struct {
+	Name string
+	Sig  types.Signature
+	Impl Impl
+}
+
+// ExitError is returned by the exit builtin to stop the running program
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// The Library structure holds a set of builtins
+type Library struct {
+	functions []*Function
+	byName    map[string]*Function
+}
+
+func NewLibrary() *Library {
+	return &Library{
+		byName: map[string]*Function{},
+	}
+}
+
+// Define adds a function to the library replacing any previous function
+// with the same name
+func (l *Library) Define(fn *Function) {
+	if _, ok := l.byName[fn.Name]; !ok {
+		l.functions = append(l.functions, fn)
+	} else {
+		for i, prev := range l.functions {
+			if prev.Name == fn.Name {
+				l.functions[i] = fn
+			}
+		}
+	}
+	l.byName[fn.Name] = fn
+}
+
+func (l *Library) Lookup(name string) *Function {
+	return l.byName[name]
+}
+
+// Functions returns the functions of the library in definition order
+func (l *Library) Functions() []*Function {
+	return l.functions
+}
+
+// Scope returns a new scope declaring every function of the library,
+// enclosed by parent
+func (l *Library) Scope(parent *types.Scope) *types.Scope {
+	scope := types.NewScope(parent)
+	for _, fn := range l.functions {
+		sig := fn.Sig
+		scope.Insert(&types.Symbol{
+			Name: fn.Name,
+			Kind: types.Func,
+			Type: sig.Result,
+			Sig:  &sig,
+		})
+	}
+	return scope
+}
+
+// Format returns the textual representation of a value as print writes it
+func Format(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return fmt.Sprintf("%.6g", v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,55 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+// LexError is returned when the source can't be split into tokens, the
+// tokenizer skips what it couldn't recognize so it can go on
+type LexError struct {
+	Pos ast.Position
+	// Offset is the byte offset of Pos in the source
+	Offset int
+	Msg    string
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%s: syntax error: %s", e.Pos, e.Msg)
+}
+
+func (e *LexError) Diagnostic() *diag.Diagnostic {
+	return diag.Errorf(e.Pos, "syntax error: %s", e.Msg)
+}
+
+// ErrorList holds the errors found in a file in the order they were found,
+// errors.As and errors.Is look into every one of them
+type ErrorList []error
+
+// Add appends an error to the list
+func (l *ErrorList) Add(err error) {
+	*l = append(*l, err)
+}
+
+// Err returns the list as an error, or nil when it is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l ErrorList) Unwrap() []error {
+	return l
+}
@@ -0,0 +1,128 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The Edit structure replaces the bytes of a source from Start up to End
+// with Text, an insertion has Start equal to End and a deletion an empty
+// Text
+type Edit struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// Apply returns src once edited, the edit must fit in src
+func (e Edit) Apply(src string) (string, error) {
+	if e.Start < 0 || e.Start > e.End || e.End > len(src) {
+		return "", fmt.Errorf("edit of bytes %d to %d out of a source of %d bytes", e.Start, e.End, len(src))
+	}
+	return src[:e.Start] + e.Text + src[e.End:], nil
+}
+
+// The Change structure tells which tokens Relex kept. The tokens before
+// First are the old ones, at the same index. From NewSync on the tokens are
+// the old ones from OldSync on, moved to where the edit left them.
+type Change struct {
+	First   int
+	OldSync int
+	NewSync int
+	// row is the line of the old token at OldSync, the tokens left on it
+	// move by cols columns and every token after the edit moves by rows
+	// lines
+	row, rows, cols int
+}
+
+// Move returns where the edit moved a position of the old source that is
+// after OldSync
+func (c *Change) Move(row, col int) (int, int) {
+	if row == c.row {
+		col += c.cols
+	}
+	return row + c.rows, col
+}
+
+// Moved reports whether the edit moved positions of the old source after
+// OldSync that are on the given line or after it
+func (c *Change) Moved(row int) bool {
+	return c.rows != 0 || (row <= c.row && c.cols != 0)
+}
+
+// Relex returns the tokens of the source tokens were read from once edit is
+// applied to it, given the edited source src. Only the tokens around the
+// edit are lexed again: the lexer starts over at the end of the last token
+// the edit can't change and stops at the first token starting where an old
+// one did, the rest of the tokens are the old ones moved.
+func Relex(name string, tokens []*Token, src string, edit Edit) ([]*Token, *Change, error) {
+	delta := len(edit.Text) - (edit.End - edit.Start)
+
+	// a token ending right before the edit may grow into it, and an integer
+	// one byte before it may become a float
+	first := 0
+	for first < len(tokens)-1 && tokens[first].end+1 < edit.Start {
+		first++
+	}
+
+	t := FromString(name, src)
+	if first > 0 {
+		prev := tokens[first-1]
+		t.cursor = prev.end
+		t.line = prev.row
+		t.char = prev.col + prev.end - prev.start
+	}
+
+	out := append([]*Token{}, tokens[:first]...)
+	old := first
+	var errs ErrorList
+	for {
+		tok, err := t.GetNextToken()
+		var lexErr *LexError
+		switch {
+		case errors.As(err, &lexErr):
+			{
+				errs.Add(lexErr)
+				continue
+			}
+		case err != nil && !errors.Is(err, io.EOF):
+			return nil, nil, err
+		}
+
+		if tok.start >= edit.Start+len(edit.Text) {
+			for old < len(tokens) && tokens[old].start+delta < tok.start {
+				old++
+			}
+			if old < len(tokens) && tokens[old].start+delta == tok.start {
+				change := &Change{
+					First:   first,
+					OldSync: old,
+					NewSync: len(out),
+					row:     tokens[old].row,
+					rows:    tok.row - tokens[old].row,
+					cols:    tok.col - tokens[old].col,
+				}
+				for _, tok := range tokens[old:] {
+					moved := *tok
+					moved.row, moved.col = change.Move(tok.row, tok.col)
+					moved.start += delta
+					moved.end += delta
+					out = append(out, &moved)
+				}
+				if err := errs.Err(); err != nil {
+					return nil, nil, err
+				}
+				return out, change, nil
+			}
+		}
+
+		out = append(out, tok)
+		if errors.Is(err, io.EOF) {
+			if err := errs.Err(); err != nil {
+				return nil, nil, err
+			}
+			return out, &Change{First: first, OldSync: len(tokens), NewSync: len(out)}, nil
+		}
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zSnails/alpha/parser/ast"
 )
 
 type TokenType int8
@@ -15,14 +19,22 @@ type Token struct {
 	Type     TokenType `json:"type"`
 	Value    string    `json:"value"`
 	row, col int
+	start    int
+	end      int
 }
 
 func (t *Token) GetPosition() (row int, col int) {
 	return t.row, t.col
 }
 
+// GetSpan returns the byte offsets of the source where the token starts and
+// ends, the span of a string includes both quotes
+func (t *Token) GetSpan() (start int, end int) {
+	return t.start, t.end
+}
+
 func (t *Token) String() string {
-	return fmt.Sprintf("[<%s>@%d:%d %s]", TokenNames[t.Type], t.row, t.col, t.Value)
+	return fmt.Sprintf("[<%s>@%d:%d %q]", TokenNames[t.Type], t.row, t.col, t.Value)
 }
 
 const (
@@ -59,6 +71,10 @@ const (
 	Colon
 	Semicolon
 	String
+	Import
+	Export
+	Comma
+	Comment
 )
 
 type Spec struct {
@@ -76,7 +92,7 @@ var SPECS = []Spec{
 		Spec: `^[ \t]+`,
 	},
 	{
-		Type: Whitespace,
+		Type: Comment,
 		Spec: `^\/\/.*`,
 	},
 	{
@@ -107,6 +123,10 @@ var SPECS = []Spec{
 		Type: Semicolon,
 		Spec: `^\;`,
 	},
+	{
+		Type: Comma,
+		Spec: `^,`,
+	},
 	{
 		Type: Then,
 		Spec: `^then\b`,
@@ -157,14 +177,6 @@ var SPECS = []Spec{
 		Type: Equals,
 		Spec: `^=`,
 	},
-	{
-		Type: LessThan,
-		Spec: `^<`,
-	},
-	{
-		Type: GreaterThan,
-		Spec: `^>`,
-	},
 	{
 		Type: LessThanEqual,
 		Spec: `^<=`,
@@ -173,6 +185,14 @@ var SPECS = []Spec{
 		Type: GreaterThanEqual,
 		Spec: `^>=`,
 	},
+	{
+		Type: LessThan,
+		Spec: `^<`,
+	},
+	{
+		Type: GreaterThan,
+		Spec: `^>`,
+	},
 	{
 		Type: While,
 		Spec: `^while\b`,
@@ -201,15 +221,50 @@ var SPECS = []Spec{
 		Type: Begin,
 		Spec: `^begin\b`,
 	},
+	{
+		Type: Import,
+		Spec: `^import\b`,
+	},
+	{
+		Type: Export,
+		Spec: `^export\b`,
+	},
 	{
 		Type: Identifier,
 		Spec: `^[_a-zA-Z][_a-zA-Z0-9]*`,
 	},
 }
 
+// patterns holds the expressions of SPECS compiled once, in the same order
+var patterns = compile(SPECS)
+
+func compile(specs []Spec) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(specs))
+	for i, spec := range specs {
+		patterns[i] = regexp.MustCompile(spec.Spec)
+	}
+	return patterns
+}
+
+var keywordSpec = regexp.MustCompile(`^\^([a-z]+)\\b$`)
+
+// Keywords maps the reserved words of the language to their token type, they
+// are the SPECS matching a whole word
+func Keywords() map[string]TokenType {
+	keywords := map[string]TokenType{}
+	for _, spec := range SPECS {
+		if match := keywordSpec.FindStringSubmatch(spec.Spec); match != nil {
+			keywords[match[1]] = spec.Type
+		}
+	}
+	return keywords
+}
+
 var TokenNames = map[TokenType]string{
 	EOF:                    "EOF",
 	Whitespace:             "whitespace",
+	NewLine:                "newline",
+	Comment:                "comment",
 	If:                     "if",
 	Then:                   "then",
 	Else:                   "else",
@@ -240,6 +295,9 @@ var TokenNames = map[TokenType]string{
 	Tilde:                  "~",
 	Colon:                  ":",
 	Semicolon:              ";",
+	Import:                 "import",
+	Export:                 "export",
+	Comma:                  ",",
 }
 
 type Tokenizer struct {
@@ -248,6 +306,7 @@ type Tokenizer struct {
 	file    string
 	line    int
 	char    int
+	trivia  bool
 }
 
 func (t *Tokenizer) GetFileName() string {
@@ -270,41 +329,65 @@ func FromFile(name string) (*Tokenizer, error) {
 }
 
 func NewTokenizer(content string) *Tokenizer {
+	return FromString("<stdin>", content)
+}
+
+// FromString returns a tokenizer for content that reports positions as if it
+// had been read from the named file.
+func FromString(name, content string) *Tokenizer {
 	return &Tokenizer{
 		content: content,
 		cursor:  0,
 		char:    1,
 		line:    1,
-		file:    "<stdin>",
+		file:    name,
 	}
 }
 
-func (t *Tokenizer) match(spec, content string) (string, int) {
-	re := regexp.MustCompile(spec)
-	matched := re.Find([]byte(content))
+// KeepTrivia makes the tokenizer return the whitespace, comments and new
+// lines it skips otherwise, the parser doesn't expect them
+func (t *Tokenizer) KeepTrivia() {
+	t.trivia = true
+}
+
+// match consumes what pattern matches at the start of input, which starts
+// at the cursor
+func (t *Tokenizer) match(pattern *regexp.Regexp, input string) (string, int) {
+	matched := pattern.FindString(input)
 
 	size := len(matched)
 	t.cursor += size
 	t.char += size
-	return string(matched), size
+	return matched, size
 }
 
 func (t *Tokenizer) hasMoreTokens() bool {
 	return t.cursor < len(t.content)
 }
 
+// GetAllTokens returns every token of the input stream ending with an EOF
+// token, the lexing errors are collected into an ErrorList
 func (t *Tokenizer) GetAllTokens() ([]*Token, error) {
 	out := []*Token{}
+	var errs ErrorList
 	for {
 		tok, err := t.GetNextToken()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				out = append(out, tok)
-				return out, nil
+		var lexErr *LexError
+		switch {
+		case errors.Is(err, io.EOF):
+			{
+				if err := errs.Err(); err != nil {
+					return nil, err
+				}
+				return append(out, tok), nil
 			}
+		case errors.As(err, &lexErr):
+			errs.Add(lexErr)
+		case err != nil:
 			return nil, err
+		default:
+			out = append(out, tok)
 		}
-		out = append(out, tok)
 	}
 }
 
@@ -315,42 +398,82 @@ func (t *Tokenizer) GetAllTokens() ([]*Token, error) {
 func (t *Tokenizer) GetNextToken() (*Token, error) {
 	if !t.hasMoreTokens() {
 		return &Token{
-			Type: EOF,
-			row:  t.line,
-			col:  t.char,
+			Type:  EOF,
+			row:   t.line,
+			col:   t.char,
+			start: t.cursor,
+			end:   t.cursor,
 		}, io.EOF
 	}
 
-	for _, spec := range SPECS {
-		matched, size := t.match(spec.Spec, t.content[t.cursor:])
+	start := t.cursor
+	// only strings go past the end of the line, the other expressions are
+	// matched against the line so their cost doesn't grow with the source
+	rest := t.content[t.cursor:]
+	line := rest
+	if end := strings.IndexByte(rest, '\n'); end >= 0 {
+		line = rest[:end+1]
+	}
+	for i, spec := range SPECS {
+		input := line
+		if spec.Type == String {
+			input = rest
+		}
+		matched, size := t.match(patterns[i], input)
 		if size == 0 {
 			continue
 		}
 
-		if spec.Type == NewLine {
-			t.line++
-			t.char = 1
-			return t.GetNextToken()
-		}
-
-		if spec.Type == Whitespace {
+		if spec.Type == NewLine || spec.Type == Whitespace || spec.Type == Comment {
+			token := &Token{
+				Type:  spec.Type,
+				Value: matched,
+				col:   t.char - size,
+				row:   t.line,
+				start: start,
+				end:   t.cursor,
+			}
+			if spec.Type == NewLine {
+				t.line++
+				t.char = 1
+			}
+			if t.trivia {
+				return token, nil
+			}
 			return t.GetNextToken()
 		}
 
+		col := t.char - size
 		if spec.Type == String {
 			if !t.hasMoreTokens() || (t.content[t.cursor] != '"' && t.content[t.cursor] != '\'') {
-				return nil, fmt.Errorf("%s:%d:%d: syntax error: missing string closing quote", t.file, t.line, t.char)
+				return nil, t.errorf("missing string closing quote")
 			}
 			t.cursor++ // Skip the closing quote on strings
+			t.char++
 		}
 
 		return &Token{
 			Type:  spec.Type,
 			Value: matched,
-			col:   t.char - size,
+			col:   col,
 			row:   t.line,
+			start: start,
+			end:   t.cursor,
 		}, nil
 	}
 
-	return nil, fmt.Errorf("%s:%d:%d: syntax error: unexpected token '%c'\n", t.file, t.line, t.char, t.content[t.cursor])
+	r, size := utf8.DecodeRuneInString(t.content[t.cursor:])
+	err := t.errorf("unexpected token '%c'", r)
+	t.cursor += size
+	t.char += size
+	return nil, err
+}
+
+// errorf returns a lexing error at the current position
+func (t *Tokenizer) errorf(format string, args ...any) *LexError {
+	return &LexError{
+		Pos:    ast.Position{File: t.file, Row: t.line, Col: t.char},
+		Offset: t.cursor,
+		Msg:    fmt.Sprintf(format, args...),
+	}
 }
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"sort"
+
+	"github.com/zSnails/alpha/parser/ast"
+)
+
+type Type int8
+
+const (
+	Invalid Type = iota
+	Void
+	Any
+	Integer
+	Float
+	String
+	Boolean
+)
+
+var TypeNames = map[Type]string{
+	Invalid: "invalid",
+	Void:    "void",
+	Any:     "any",
+	Integer: "Integer",
+	Float:   "Float",
+	String:  "String",
+	Boolean: "Boolean",
+}
+
+func (t Type) String() string {
+	return TypeNames[t]
+}
+
+// IsNumeric reports whether arithmetic can be done on values of the type
+func (t Type) IsNumeric() bool {
+	return t == Integer || t == Float
+}
+
+// AssignableTo reports whether a value of type t can be stored in a location
+// of type dst, integers are silently promoted to floats.
+func (t Type) AssignableTo(dst Type) bool {
+	return t == dst || dst == Any || (t == Integer && dst == Float)
+}
+
+type SymbolKind int8
+
+const (
+	TypeName SymbolKind = iota
+	Const
+	Var
+	Func
+)
+
+var SymbolKindNames = map[SymbolKind]string{
+	TypeName: "type",
+	Const:    "constant",
+	Var:      "variable",
+	Func:     "function",
+}
+
+// Signature describes the parameters and result of a function, the last
+// parameter of a variadic function can be repeated any number of times.
+type Signature struct {
+	Params   []Type
+	Variadic bool
+	Result   Type
+}
+
+// Symbol is anything a name can be bound to
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Type is the type of the value for constants and variables, the
+	// denoted type for type names and the result type for functions.
+	Type Type
+	Sig  *Signature
+	Pos  ast.Position
+	// Value holds the value of predeclared constants
+	Value any
+	// Exported is set for the declarations a module exports
+	Exported bool
+}
+
+// Scope maps names to symbols, lookups fall back to the enclosing scope
+type Scope struct {
+	parent  *Scope
+	symbols map[string]*Symbol
+}
+
+func NewScope(parent *Scope) *Scope {
+	return &Scope{
+		parent:  parent,
+		symbols: map[string]*Symbol{},
+	}
+}
+
+func (s *Scope) Parent() *Scope {
+	return s.parent
+}
+
+// Insert adds the symbol to the scope, if the name was already declared in
+// this same scope the previous symbol is returned and nothing is inserted.
+func (s *Scope) Insert(sym *Symbol) *Symbol {
+	if prev, ok := s.symbols[sym.Name]; ok {
+		return prev
+	}
+	s.symbols[sym.Name] = sym
+	return nil
+}
+
+// LookupLocal finds a name without looking at the enclosing scopes
+func (s *Scope) LookupLocal(name string) *Symbol {
+	return s.symbols[name]
+}
+
+// Names returns the names visible from this scope, sorted
+func (s *Scope) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for scope := s; scope != nil; scope = scope.parent {
+		for name := range scope.symbols {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup finds a name in this scope or any of the enclosing ones
+func (s *Scope) Lookup(name string) *Symbol {
+	for scope := s; scope != nil; scope = scope.parent {
+		if sym, ok := scope.symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Universe returns a new scope holding the predeclared types and constants
+func Universe() *Scope {
+	scope := NewScope(nil)
+	for _, t := range []Type{Integer, Float, String, Boolean} {
+		scope.Insert(&Symbol{Name: t.String(), Kind: TypeName, Type: t})
+	}
+	scope.Insert(&Symbol{Name: "true", Kind: Const, Type: Boolean, Value: true})
+	scope.Insert(&Symbol{Name: "false", Kind: Const, Type: Boolean, Value: false})
+	return scope
+}
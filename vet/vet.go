@@ -0,0 +1,290 @@
+package vet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zSnails/alpha/checker"
+	"github.com/zSnails/alpha/diag"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// Code identifies the kind of a warning, codes never change meaning
+type Code int
+
+const (
+	// UninitializedRead is a variable read before any assignment
+	UninitializedRead Code = iota + 1
+	// UnusedDeclaration is a constant or variable that is never read
+	UnusedDeclaration
+	// DeadAssignment is an assignment whose value is never read
+	DeadAssignment
+)
+
+var CodeNames = map[Code]string{
+	UninitializedRead: "W001",
+	UnusedDeclaration: "W002",
+	DeadAssignment:    "W003",
+}
+
+func (c Code) String() string {
+	return CodeNames[c]
+}
+
+// The Warning structure describes code that is valid but likely wrong
+type Warning struct {
+	Pos  ast.Position
+	Code Code
+	Msg  string
+}
+
+func (w *Warning) Error() string {
+	return fmt.Sprintf("%s: warning %s: %s", w.Pos, w.Code, w.Msg)
+}
+
+func (w *Warning) Diagnostic() *diag.Diagnostic {
+	return &diag.Diagnostic{
+		Severity: diag.Warning,
+		Code:     w.Code.String(),
+		Msg:      w.Msg,
+		Primary:  diag.Span{Pos: w.Pos},
+	}
+}
+
+// Vet lowers a checked program and analyses it, without the guards of
+// ir.Lower so only the reads of the source are seen
+func Vet(root ast.Command, info *checker.Info) ([]*Warning, error) {
+	fn, err := ir.NewBuilder(info).Program(root)
+	if err != nil {
+		return nil, err
+	}
+	return Check(fn), nil
+}
+
+// Check runs every analysis on a function that isn't in SSA form yet, the
+// warnings are sorted by position
+func Check(fn *ir.Func) []*Warning {
+	if fn.SSA {
+		return nil
+	}
+	read := readVars(fn)
+	warnings := uninitializedReads(fn)
+	warnings = append(warnings, unusedDeclarations(fn, read)...)
+	warnings = append(warnings, deadAssignments(fn, read)...)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].Pos, warnings[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	return warnings
+}
+
+// variable returns the variable of the source an argument reads, or nil
+func variable(value ir.Value) *ir.Var {
+	v, ok := value.(*ir.Var)
+	if !ok || v.Sym == nil {
+		return nil
+	}
+	return v
+}
+
+// argPos returns the position of the argument i of an instruction
+func argPos(instr *ir.Instr, i int) ast.Position {
+	if i < len(instr.ArgPos) {
+		return instr.ArgPos[i]
+	}
+	return instr.Pos
+}
+
+// readVars returns the variables of the source some instruction reads
+func readVars(fn *ir.Func) map[*ir.Var]bool {
+	read := map[*ir.Var]bool{}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			for _, arg := range instr.Args {
+				if v := variable(arg); v != nil {
+					read[v] = true
+				}
+			}
+		}
+	}
+	return read
+}
+
+// unassigned holds the variables declared but not assigned yet
+type unassigned map[*ir.Var]bool
+
+// uninitializedReads reports the reads of variables some path reaches
+// without assigning them since their declaration. Two forward analyses run
+// at once: may holds the variables unassigned on some path and must those
+// unassigned on every path, predecessors must hasn't reached yet are left
+// out of the intersection.
+func uninitializedReads(fn *ir.Func) []*Warning {
+	order := fn.ReversePostorder()
+	may := map[*ir.Block]unassigned{}
+	must := map[*ir.Block]unassigned{}
+
+	transfer := func(b *ir.Block, set unassigned, visit func(instr *ir.Instr, set unassigned)) unassigned {
+		out := unassigned{}
+		for v := range set {
+			out[v] = true
+		}
+		for _, instr := range b.Instrs {
+			if visit != nil {
+				visit(instr, out)
+			}
+			if instr.Dst == nil || instr.Dst.Sym == nil {
+				continue
+			}
+			if instr.Op == ir.Declare {
+				out[instr.Dst] = true
+			} else {
+				delete(out, instr.Dst)
+			}
+		}
+		return out
+	}
+
+	in := func(b *ir.Block) (unassigned, unassigned) {
+		mayIn, mustIn := unassigned{}, unassigned(nil)
+		for _, pred := range b.Preds {
+			for v := range may[pred] {
+				mayIn[v] = true
+			}
+			if must[pred] == nil {
+				continue
+			}
+			if mustIn == nil {
+				mustIn = unassigned{}
+				for v := range must[pred] {
+					mustIn[v] = true
+				}
+				continue
+			}
+			for v := range mustIn {
+				if !must[pred][v] {
+					delete(mustIn, v)
+				}
+			}
+		}
+		if mustIn == nil {
+			mustIn = unassigned{}
+		}
+		return mayIn, mustIn
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, b := range order {
+			mayIn, mustIn := in(b)
+			mayOut, mustOut := transfer(b, mayIn, nil), transfer(b, mustIn, nil)
+			if !equal(may[b], mayOut) || must[b] == nil || !equal(must[b], mustOut) {
+				may[b], must[b] = mayOut, mustOut
+				changed = true
+			}
+		}
+	}
+
+	warnings := []*Warning{}
+	for _, b := range order {
+		mayIn, definitely := in(b)
+		transfer(b, mayIn, func(instr *ir.Instr, set unassigned) {
+			for i, arg := range instr.Args {
+				v := variable(arg)
+				if v == nil || !set[v] {
+					continue
+				}
+				msg := fmt.Sprintf("%s may be read before being assigned", v.Sym.Name)
+				if definitely[v] {
+					msg = fmt.Sprintf("%s is read before being assigned", v.Sym.Name)
+				}
+				warnings = append(warnings, &Warning{Pos: argPos(instr, i), Code: UninitializedRead, Msg: msg})
+			}
+			if instr.Dst == nil || instr.Dst.Sym == nil {
+				return
+			}
+			if instr.Op == ir.Declare {
+				definitely[instr.Dst] = true
+			} else {
+				delete(definitely, instr.Dst)
+			}
+		})
+	}
+	return warnings
+}
+
+func equal(a, b unassigned) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for v := range a {
+		if !b[v] {
+			return false
+		}
+	}
+	return true
+}
+
+// unusedDeclarations reports the constants and variables that are never
+// read, declarations exported by modules are left out since other programs
+// may read them
+func unusedDeclarations(fn *ir.Func, read map[*ir.Var]bool) []*Warning {
+	warnings := []*Warning{}
+	seen := map[*ir.Var]bool{}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			v := instr.Dst
+			if v == nil || v.Sym == nil || seen[v] || read[v] || v.Sym.Exported {
+				continue
+			}
+			seen[v] = true
+			warnings = append(warnings, &Warning{
+				Pos:  v.Sym.Pos,
+				Code: UnusedDeclaration,
+				Msg:  fmt.Sprintf("%s %s is declared but never read", types.SymbolKindNames[v.Sym.Kind], v.Sym.Name),
+			})
+		}
+	}
+	return warnings
+}
+
+// deadAssignments reports the assignments to variables whose value no path
+// reads before the variable is assigned or declared again. Variables that
+// are never read are already reported as unused.
+func deadAssignments(fn *ir.Func, read map[*ir.Var]bool) []*Warning {
+	_, liveOut := fn.Liveness()
+	warnings := []*Warning{}
+	for _, b := range fn.Blocks {
+		live := map[*ir.Var]bool{}
+		for v := range liveOut[b] {
+			live[v] = true
+		}
+		for i := len(b.Instrs) - 1; i >= 0; i-- {
+			instr := b.Instrs[i]
+			if v := instr.Dst; v != nil && v.Sym != nil {
+				if instr.Op == ir.Copy && v.Sym.Kind == types.Var && read[v] && !live[v] {
+					warnings = append(warnings, &Warning{
+						Pos:  instr.Pos,
+						Code: DeadAssignment,
+						Msg:  fmt.Sprintf("value assigned to %s is never read", v.Sym.Name),
+					})
+				}
+				delete(live, v)
+			}
+			for _, arg := range instr.Args {
+				if v := variable(arg); v != nil {
+					live[v] = true
+				}
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,58 @@
+package vet_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+	"github.com/zSnails/alpha/vet"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestGolden compares the warnings of the programs in testdata, and of the
+// while-do example, with their golden files in testdata, go test -update
+// rewrites them
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, filepath.Join("..", "tests", "while-do.alpha"))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".alpha")
+		t.Run(name, func(t *testing.T) {
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).Load(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := vet.Vet(program.Root, program.Info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got strings.Builder
+			for _, w := range warnings {
+				got.WriteString(w.Error() + "\n")
+			}
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got.String()), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("the warnings differ from %s\n%s", golden, got.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package watgen
+
+import (
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/watgen/wat"
+)
+
+// The ExitError structure is the error the host stops a module calling exit
+// with
+type ExitError struct {
+	Code int32
+}
+
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(int(e.Code))
+}
+
+// Host returns the functions of the "alpha" namespace for running modules
+// with the wat package, they print to out formatting values as the
+// interpreter does
+func Host(out io.Writer) wat.Imports {
+	return wat.Imports{
+		"alpha.print_i64": func(in *wat.Instance, args []any) ([]any, error) {
+			_, err := io.WriteString(out, stdlib.Format(int(args[0].(int64))))
+			return nil, err
+		},
+		"alpha.print_f64": func(in *wat.Instance, args []any) ([]any, error) {
+			_, err := io.WriteString(out, stdlib.Format(args[0].(float64)))
+			return nil, err
+		},
+		"alpha.print_str": func(in *wat.Instance, args []any) ([]any, error) {
+			start := int(args[0].(int32))
+			_, err := out.Write(in.Memory()[start : start+int(args[1].(int32))])
+			return nil, err
+		},
+		"alpha.pow": func(in *wat.Instance, args []any) ([]any, error) {
+			return []any{math.Pow(args[0].(float64), args[1].(float64))}, nil
+		},
+		"alpha.exit": func(in *wat.Instance, args []any) ([]any, error) {
+			return nil, &ExitError{Code: args[0].(int32)}
+		},
+	}
+}
@@ -0,0 +1,291 @@
+package watgen
+
+// builtins lists the prelude builtins the module implements, print and
+// println are expanded by the generator itself
+var builtins = map[string]bool{
+	"length":  true,
+	"concat":  true,
+	"substr":  true,
+	"toInt":   true,
+	"toFloat": true,
+	"abs":     true,
+	"sqrt":    true,
+	"pow":     true,
+	"exit":    true,
+}
+
+// imports lists the functions the host provides to every module, strings are
+// passed as the address and length of their bytes in the exported memory.
+const imports = `  (import "alpha" "print_i64" (func $print_i64 (param i64)))
+  (import "alpha" "print_f64" (func $print_f64 (param f64)))
+  (import "alpha" "print_str" (func $print_str (param i32 i32)))
+`
+
+// optionalImports lists the functions the host only provides to modules
+// calling the builtin of the same name
+var optionalImports = map[string]string{
+	"pow":  `  (import "alpha" "pow" (func $pow (param f64 f64) (result f64)))` + "\n",
+	"exit": `  (import "alpha" "exit" (func $exit (param i32)))` + "\n",
+}
+
+// runtime is added to every module, a string is the address of its length
+// stored as an i32 followed by its bytes. Address 0 always holds the empty
+// string so zeroed locals are valid strings. Strings are never freed, the
+// heap starts after the literals and grows the memory as needed.
+const runtime = `  (func $alpha_alloc (param $n i32) (result i32)
+    (local $p i32)
+    global.get $alpha_heap
+    local.set $p
+    global.get $alpha_heap
+    local.get $n
+    i32.add
+    i32.const 7
+    i32.add
+    i32.const -8
+    i32.and
+    global.set $alpha_heap
+    block $ok
+      global.get $alpha_heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.le_u
+      br_if $ok
+      global.get $alpha_heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.sub
+      i32.const 65535
+      i32.add
+      i32.const 16
+      i32.shr_u
+      memory.grow
+      i32.const -1
+      i32.ne
+      br_if $ok
+      unreachable
+    end
+    local.get $p)
+
+  (func $alpha_copy (param $dst i32) (param $src i32) (param $n i32)
+    block $done
+      loop $next
+        local.get $n
+        i32.eqz
+        br_if $done
+        local.get $dst
+        local.get $src
+        i32.load8_u
+        i32.store8
+        local.get $dst
+        i32.const 1
+        i32.add
+        local.set $dst
+        local.get $src
+        i32.const 1
+        i32.add
+        local.set $src
+        local.get $n
+        i32.const 1
+        i32.sub
+        local.set $n
+        br $next
+      end
+    end)
+
+  (func $alpha_string (param $n i32) (result i32)
+    (local $p i32)
+    local.get $n
+    i32.const 4
+    i32.add
+    call $alpha_alloc
+    local.tee $p
+    local.get $n
+    i32.store
+    local.get $p)
+
+  (func $alpha_div (param $a i64) (param $b i64) (result i64)
+    local.get $b
+    i64.eqz
+    if
+      unreachable
+    end
+    local.get $b
+    i64.const -1
+    i64.eq
+    if
+      i64.const 0
+      local.get $a
+      i64.sub
+      return
+    end
+    local.get $a
+    local.get $b
+    i64.div_s)
+
+  (func $alpha_print (param $s i32)
+    local.get $s
+    i32.const 4
+    i32.add
+    local.get $s
+    i32.load
+    call $print_str)
+
+  (func $alpha_equal (param $a i32) (param $b i32) (result i32)
+    (local $n i32)
+    local.get $a
+    i32.load
+    local.tee $n
+    local.get $b
+    i32.load
+    i32.ne
+    if
+      i32.const 0
+      return
+    end
+    block $done
+      loop $next
+        local.get $n
+        i32.eqz
+        br_if $done
+        local.get $a
+        i32.const 4
+        i32.add
+        i32.load8_u
+        local.get $b
+        i32.const 4
+        i32.add
+        i32.load8_u
+        i32.ne
+        if
+          i32.const 0
+          return
+        end
+        local.get $a
+        i32.const 1
+        i32.add
+        local.set $a
+        local.get $b
+        i32.const 1
+        i32.add
+        local.set $b
+        local.get $n
+        i32.const 1
+        i32.sub
+        local.set $n
+        br $next
+      end
+    end
+    i32.const 1)
+
+  (func $alpha_length (param $s i32) (result i64)
+    local.get $s
+    i32.load
+    i64.extend_i32_u)
+
+  (func $alpha_concat (param $a i32) (param $b i32) (result i32)
+    (local $p i32)
+    local.get $a
+    i32.load
+    local.get $b
+    i32.load
+    i32.add
+    call $alpha_string
+    local.tee $p
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.load
+    call $alpha_copy
+    local.get $p
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.load
+    i32.add
+    local.get $b
+    i32.const 4
+    i32.add
+    local.get $b
+    i32.load
+    call $alpha_copy
+    local.get $p)
+
+  (func $alpha_substr (param $s i32) (param $start i64) (param $n i64) (result i32)
+    (local $p i32)
+    local.get $start
+    i64.const 0
+    i64.lt_s
+    local.get $n
+    i64.const 0
+    i64.lt_s
+    i32.or
+    local.get $start
+    local.get $s
+    i32.load
+    i64.extend_i32_u
+    i64.gt_s
+    i32.or
+    local.get $n
+    local.get $s
+    i32.load
+    i64.extend_i32_u
+    local.get $start
+    i64.sub
+    i64.gt_s
+    i32.or
+    if
+      unreachable
+    end
+    local.get $n
+    i32.wrap_i64
+    call $alpha_string
+    local.tee $p
+    i32.const 4
+    i32.add
+    local.get $s
+    i32.const 4
+    i32.add
+    local.get $start
+    i32.wrap_i64
+    i32.add
+    local.get $n
+    i32.wrap_i64
+    call $alpha_copy
+    local.get $p)
+
+  (func $alpha_toInt (param $f f64) (result i64)
+    local.get $f
+    i64.trunc_f64_s)
+
+  (func $alpha_toFloat (param $i i64) (result f64)
+    local.get $i
+    f64.convert_i64_s)
+
+  (func $alpha_abs (param $f f64) (result f64)
+    local.get $f
+    f64.abs)
+
+  (func $alpha_sqrt (param $f f64) (result f64)
+    local.get $f
+    f64.sqrt)
+`
+
+// wrappers maps the builtins implemented by the host to the function adapting
+// them to the types of alpha
+var wrappers = map[string]string{
+	"pow": `  (func $alpha_pow (param $x f64) (param $y f64) (result f64)
+    local.get $x
+    local.get $y
+    call $pow)
+`,
+	"exit": `  (func $alpha_exit (param $code i64)
+    local.get $code
+    i32.wrap_i64
+    call $exit)
+`,
+}
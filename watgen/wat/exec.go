@@ -0,0 +1,657 @@
+package wat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// known lists the instructions the interpreter runs
+var known = map[string]bool{
+	"block": true, "loop": true, "if": true, "else": true, "end": true,
+	"br": true, "br_if": true, "br_table": true, "return": true, "call": true,
+	"unreachable": true, "nop": true, "drop": true, "select": true,
+	"local.get": true, "local.set": true, "local.tee": true,
+	"global.get": true, "global.set": true,
+	"memory.size": true, "memory.grow": true,
+	"i32.load": true, "i32.load8_u": true, "i64.load": true, "f64.load": true,
+	"i32.store": true, "i32.store8": true, "i64.store": true, "f64.store": true,
+	"i32.const": true, "i64.const": true, "f64.const": true,
+
+	"i32.eqz": true, "i32.eq": true, "i32.ne": true,
+	"i32.lt_s": true, "i32.lt_u": true, "i32.gt_s": true, "i32.gt_u": true,
+	"i32.le_s": true, "i32.le_u": true, "i32.ge_s": true, "i32.ge_u": true,
+	"i32.add": true, "i32.sub": true, "i32.mul": true, "i32.div_s": true, "i32.div_u": true,
+	"i32.and": true, "i32.or": true, "i32.xor": true, "i32.shl": true, "i32.shr_s": true, "i32.shr_u": true,
+
+	"i64.eqz": true, "i64.eq": true, "i64.ne": true,
+	"i64.lt_s": true, "i64.gt_s": true, "i64.le_s": true, "i64.ge_s": true,
+	"i64.add": true, "i64.sub": true, "i64.mul": true, "i64.div_s": true, "i64.rem_s": true,
+
+	"f64.eq": true, "f64.ne": true, "f64.lt": true, "f64.gt": true, "f64.le": true, "f64.ge": true,
+	"f64.add": true, "f64.sub": true, "f64.mul": true, "f64.div": true,
+	"f64.abs": true, "f64.neg": true, "f64.sqrt": true,
+
+	"i32.wrap_i64": true, "i64.extend_i32_s": true, "i64.extend_i32_u": true,
+	"i64.trunc_f64_s": true, "i64.trunc_sat_f64_s": true, "f64.convert_i64_s": true,
+}
+
+// The Trap structure is the error of a run stopped by the module
+type Trap struct {
+	Msg string
+}
+
+func (t *Trap) Error() string {
+	return "wasm trap: " + t.Msg
+}
+
+// HostFunc implements an imported function, values are passed as int32,
+// int64 and float64
+type HostFunc func(in *Instance, args []any) ([]any, error)
+
+// Imports maps the "module.field" names of imports to their implementation
+type Imports map[string]HostFunc
+
+// The Instance structure holds the memory and globals of a module
+// instantiated with its imports
+type Instance struct {
+	module  *Module
+	imports []HostFunc
+	memory  []byte
+	globals []any
+	// MaxSteps bounds the instructions a call runs, 0 means no limit
+	MaxSteps int
+	steps    int
+	depth    int
+}
+
+// maxDepth bounds the nesting of calls
+const maxDepth = 1000
+
+// Instantiate provides the imports of the module and initializes its
+// memory and globals
+func (m *Module) Instantiate(imports Imports) (*Instance, error) {
+	in := &Instance{module: m, imports: make([]HostFunc, len(m.Funcs))}
+	for i, fn := range m.Funcs {
+		if fn.body != nil || fn.Module == "" {
+			continue
+		}
+		host, ok := imports[fn.Module+"."+fn.Field]
+		if !ok {
+			return nil, fmt.Errorf("wat: missing import %s.%s", fn.Module, fn.Field)
+		}
+		in.imports[i] = host
+	}
+	if m.pages > 0 {
+		in.memory = make([]byte, m.pages<<16)
+	}
+	for _, seg := range m.data {
+		if seg.offset+len(seg.bytes) > len(in.memory) {
+			return nil, fmt.Errorf("wat: data segment at %d out of bounds", seg.offset)
+		}
+		copy(in.memory[seg.offset:], seg.bytes)
+	}
+	for _, g := range m.globals {
+		in.globals = append(in.globals, g.init)
+	}
+	return in, nil
+}
+
+// Memory returns the memory of the instance
+func (in *Instance) Memory() []byte {
+	return in.memory
+}
+
+// Call runs an exported function, a trap or the error of an import stops it
+func (in *Instance) Call(name string, args ...any) (results []any, err error) {
+	index, ok := in.module.exports[name]
+	if !ok {
+		return nil, fmt.Errorf("wat: no function exported as %s", name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			stop, ok := r.(stop)
+			if !ok {
+				panic(r)
+			}
+			err = stop.err
+		}
+	}()
+	in.steps = 0
+	return in.call(index, args), nil
+}
+
+// stop carries the error ending a run up the Go stack
+type stop struct {
+	err error
+}
+
+func trap(format string, args ...any) {
+	panic(stop{&Trap{Msg: fmt.Sprintf(format, args...)}})
+}
+
+// label is an entered block, branches to a loop continue after its start
+// and branches to other blocks after their end
+type label struct {
+	loop   bool
+	start  int
+	end    int
+	height int
+}
+
+// The frame structure is a running function
+type frame struct {
+	fn     *Func
+	locals []any
+	stack  []any
+	labels []label
+}
+
+func (f *frame) push(v any) {
+	f.stack = append(f.stack, v)
+}
+
+func (f *frame) pop() any {
+	if len(f.stack) == 0 {
+		trap("%s: stack underflow", f.fn.Name)
+	}
+	v := f.stack[len(f.stack)-1]
+	f.stack = f.stack[:len(f.stack)-1]
+	return v
+}
+
+func (f *frame) i32() int32 {
+	v, ok := f.pop().(int32)
+	if !ok {
+		trap("%s: type mismatch, expected i32", f.fn.Name)
+	}
+	return v
+}
+
+func (f *frame) i64() int64 {
+	v, ok := f.pop().(int64)
+	if !ok {
+		trap("%s: type mismatch, expected i64", f.fn.Name)
+	}
+	return v
+}
+
+func (f *frame) f64() float64 {
+	v, ok := f.pop().(float64)
+	if !ok {
+		trap("%s: type mismatch, expected f64", f.fn.Name)
+	}
+	return v
+}
+
+func b2i(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func typeOf(v any) Type {
+	switch v.(type) {
+	case int32:
+		return I32
+	case int64:
+		return I64
+	case float64:
+		return F64
+	}
+	return ""
+}
+
+// checkTypes traps unless the values have the given types
+func checkTypes(fn *Func, what string, values []any, types []Type) {
+	if len(values) != len(types) {
+		trap("%s: %d %s, expected %d", fn.Name, len(values), what, len(types))
+	}
+	for i, v := range values {
+		if typeOf(v) != types[i] {
+			trap("%s: %s %d is %s, expected %s", fn.Name, what, i, typeOf(v), types[i])
+		}
+	}
+}
+
+// call runs the function at index with its arguments
+func (in *Instance) call(index int, args []any) []any {
+	fn := in.module.Funcs[index]
+	checkTypes(fn, "arguments", args, fn.Params)
+	if host := in.imports[index]; host != nil {
+		results, err := host(in, args)
+		if err != nil {
+			panic(stop{err})
+		}
+		checkTypes(fn, "results", results, fn.Results)
+		return results
+	}
+
+	if in.depth++; in.depth > maxDepth {
+		trap("call stack exhausted")
+	}
+	defer func() { in.depth-- }()
+
+	f := &frame{fn: fn, locals: make([]any, len(fn.locals))}
+	copy(f.locals, args)
+	for i := len(args); i < len(fn.locals); i++ {
+		f.locals[i] = zero(fn.locals[i])
+	}
+	in.run(f)
+	if len(f.stack) != len(fn.Results) {
+		trap("%s: %d values on the stack at the end, expected %d", fn.Name, len(f.stack), len(fn.Results))
+	}
+	checkTypes(fn, "results", f.stack, fn.Results)
+	return f.stack
+}
+
+// branch continues after the label at depth, or returns from the function
+// when depth reaches past the outermost block. It reports the index of the
+// next instruction.
+func (f *frame) branch(depth int) (int, bool) {
+	if depth >= len(f.labels) {
+		return 0, false
+	}
+	target := len(f.labels) - 1 - depth
+	l := f.labels[target]
+	f.stack = f.stack[:l.height]
+	if l.loop {
+		f.labels = f.labels[:target+1]
+		return l.start + 1, true
+	}
+	f.labels = f.labels[:target]
+	return l.end + 1, true
+}
+
+// ret leaves the results of the function alone on the stack
+func (f *frame) ret() {
+	n := len(f.fn.Results)
+	if len(f.stack) < n {
+		trap("%s: stack underflow", f.fn.Name)
+	}
+	f.stack = f.stack[len(f.stack)-n:]
+}
+
+func (in *Instance) address(f *frame, instr *instr, size int) int {
+	addr := uint64(uint32(f.i32())) + uint64(instr.offset)
+	if addr+uint64(size) > uint64(len(in.memory)) {
+		trap("out of bounds memory access at %d", addr)
+	}
+	return int(addr)
+}
+
+// run runs the body of the frame's function
+func (in *Instance) run(f *frame) {
+	body := f.fn.body
+	for pc := 0; pc < len(body); {
+		instr := body[pc]
+		pc++
+		if in.steps++; in.MaxSteps > 0 && in.steps > in.MaxSteps {
+			panic(stop{errors.New("wat: too many steps")})
+		}
+
+		switch instr.op {
+		case "nop":
+		case "unreachable":
+			trap("unreachable executed at line %d", instr.line)
+		case "block":
+			f.labels = append(f.labels, label{end: instr.end, height: len(f.stack)})
+		case "loop":
+			f.labels = append(f.labels, label{loop: true, start: pc - 1, end: instr.end, height: len(f.stack)})
+		case "if":
+			{
+				cond := f.i32()
+				f.labels = append(f.labels, label{end: instr.end, height: len(f.stack)})
+				switch {
+				case cond != 0:
+				case instr.els >= 0:
+					pc = instr.els + 1
+				default:
+					pc = instr.end
+				}
+			}
+		case "else":
+			pc = f.labels[len(f.labels)-1].end
+		case "end":
+			{
+				l := f.labels[len(f.labels)-1]
+				if len(f.stack) != l.height {
+					trap("%s: %d values left on the stack at the end of a block at line %d", f.fn.Name, len(f.stack)-l.height, instr.line)
+				}
+				f.labels = f.labels[:len(f.labels)-1]
+			}
+		case "br":
+			{
+				next, ok := f.branch(instr.index)
+				if !ok {
+					f.ret()
+					return
+				}
+				pc = next
+			}
+		case "br_if":
+			{
+				if f.i32() == 0 {
+					continue
+				}
+				next, ok := f.branch(instr.index)
+				if !ok {
+					f.ret()
+					return
+				}
+				pc = next
+			}
+		case "br_table":
+			{
+				i := uint32(f.i32())
+				depth := instr.labels[len(instr.labels)-1]
+				if i < uint32(len(instr.labels)-1) {
+					depth = instr.labels[i]
+				}
+				next, ok := f.branch(depth)
+				if !ok {
+					f.ret()
+					return
+				}
+				pc = next
+			}
+		case "return":
+			{
+				f.ret()
+				return
+			}
+		case "call":
+			{
+				callee := in.module.Funcs[instr.index]
+				n := len(callee.Params)
+				if len(f.stack) < n {
+					trap("%s: stack underflow calling %s", f.fn.Name, callee.Name)
+				}
+				args := append([]any{}, f.stack[len(f.stack)-n:]...)
+				f.stack = f.stack[:len(f.stack)-n]
+				f.stack = append(f.stack, in.call(instr.index, args)...)
+			}
+		case "drop":
+			f.pop()
+		case "select":
+			{
+				cond, b, a := f.i32(), f.pop(), f.pop()
+				if typeOf(a) != typeOf(b) {
+					trap("%s: select between %s and %s", f.fn.Name, typeOf(a), typeOf(b))
+				}
+				if cond != 0 {
+					f.push(a)
+				} else {
+					f.push(b)
+				}
+			}
+		case "local.get":
+			f.push(f.locals[instr.index])
+		case "local.set", "local.tee":
+			{
+				v := f.pop()
+				if typeOf(v) != f.fn.locals[instr.index] {
+					trap("%s: storing %s in a local of type %s", f.fn.Name, typeOf(v), f.fn.locals[instr.index])
+				}
+				f.locals[instr.index] = v
+				if instr.op == "local.tee" {
+					f.push(v)
+				}
+			}
+		case "global.get":
+			f.push(in.globals[instr.index])
+		case "global.set":
+			{
+				v := f.pop()
+				if typeOf(v) != in.module.globals[instr.index].t {
+					trap("%s: storing %s in a global of type %s", f.fn.Name, typeOf(v), in.module.globals[instr.index].t)
+				}
+				in.globals[instr.index] = v
+			}
+		case "memory.size":
+			f.push(int32(len(in.memory) >> 16))
+		case "memory.grow":
+			{
+				n := uint32(f.i32())
+				pages := len(in.memory) >> 16
+				if uint64(pages)+uint64(n) > 1<<16 {
+					f.push(int32(-1))
+					continue
+				}
+				in.memory = append(in.memory, make([]byte, int(n)<<16)...)
+				f.push(int32(pages))
+			}
+		case "i32.load":
+			{
+				addr := in.address(f, instr, 4)
+				f.push(int32(binary.LittleEndian.Uint32(in.memory[addr:])))
+			}
+		case "i32.load8_u":
+			{
+				addr := in.address(f, instr, 1)
+				f.push(int32(in.memory[addr]))
+			}
+		case "i64.load":
+			{
+				addr := in.address(f, instr, 8)
+				f.push(int64(binary.LittleEndian.Uint64(in.memory[addr:])))
+			}
+		case "f64.load":
+			{
+				addr := in.address(f, instr, 8)
+				f.push(math.Float64frombits(binary.LittleEndian.Uint64(in.memory[addr:])))
+			}
+		case "i32.store":
+			{
+				v := f.i32()
+				binary.LittleEndian.PutUint32(in.memory[in.address(f, instr, 4):], uint32(v))
+			}
+		case "i32.store8":
+			{
+				v := f.i32()
+				in.memory[in.address(f, instr, 1)] = byte(v)
+			}
+		case "i64.store":
+			{
+				v := f.i64()
+				binary.LittleEndian.PutUint64(in.memory[in.address(f, instr, 8):], uint64(v))
+			}
+		case "f64.store":
+			{
+				v := f.f64()
+				binary.LittleEndian.PutUint64(in.memory[in.address(f, instr, 8):], math.Float64bits(v))
+			}
+		case "i32.const", "i64.const", "f64.const":
+			f.push(instr.value)
+		default:
+			numeric(f, instr.op)
+		}
+	}
+}
+
+// numeric runs the operations on numbers
+func numeric(f *frame, op string) {
+	switch op {
+	case "i32.eqz":
+		f.push(b2i(f.i32() == 0))
+	case "i64.eqz":
+		f.push(b2i(f.i64() == 0))
+	case "f64.abs":
+		f.push(math.Abs(f.f64()))
+	case "f64.neg":
+		f.push(-f.f64())
+	case "f64.sqrt":
+		f.push(math.Sqrt(f.f64()))
+	case "i32.wrap_i64":
+		f.push(int32(f.i64()))
+	case "i64.extend_i32_s":
+		f.push(int64(f.i32()))
+	case "i64.extend_i32_u":
+		f.push(int64(uint32(f.i32())))
+	case "f64.convert_i64_s":
+		f.push(float64(f.i64()))
+	case "i64.trunc_f64_s":
+		{
+			v := f.f64()
+			if math.IsNaN(v) {
+				trap("invalid conversion to integer")
+			}
+			if v < -9223372036854775808.0 || v >= 9223372036854775808.0 {
+				trap("integer overflow")
+			}
+			f.push(int64(v))
+		}
+	case "i64.trunc_sat_f64_s":
+		{
+			v := f.f64()
+			switch {
+			case math.IsNaN(v):
+				f.push(int64(0))
+			case v < -9223372036854775808.0:
+				f.push(int64(math.MinInt64))
+			case v >= 9223372036854775808.0:
+				f.push(int64(math.MaxInt64))
+			default:
+				f.push(int64(v))
+			}
+		}
+	default:
+		binaryOp(f, op)
+	}
+}
+
+// binaryOp runs the operations taking two operands of the same type
+func binaryOp(f *frame, op string) {
+	switch op[:3] {
+	case "i32":
+		{
+			b, a := f.i32(), f.i32()
+			ua, ub := uint32(a), uint32(b)
+			switch op[4:] {
+			case "eq":
+				f.push(b2i(a == b))
+			case "ne":
+				f.push(b2i(a != b))
+			case "lt_s":
+				f.push(b2i(a < b))
+			case "lt_u":
+				f.push(b2i(ua < ub))
+			case "gt_s":
+				f.push(b2i(a > b))
+			case "gt_u":
+				f.push(b2i(ua > ub))
+			case "le_s":
+				f.push(b2i(a <= b))
+			case "le_u":
+				f.push(b2i(ua <= ub))
+			case "ge_s":
+				f.push(b2i(a >= b))
+			case "ge_u":
+				f.push(b2i(ua >= ub))
+			case "add":
+				f.push(a + b)
+			case "sub":
+				f.push(a - b)
+			case "mul":
+				f.push(a * b)
+			case "div_s":
+				{
+					if b == 0 {
+						trap("integer divide by zero")
+					}
+					if a == math.MinInt32 && b == -1 {
+						trap("integer overflow")
+					}
+					f.push(a / b)
+				}
+			case "div_u":
+				{
+					if b == 0 {
+						trap("integer divide by zero")
+					}
+					f.push(int32(ua / ub))
+				}
+			case "and":
+				f.push(a & b)
+			case "or":
+				f.push(a | b)
+			case "xor":
+				f.push(a ^ b)
+			case "shl":
+				f.push(int32(ua << (ub % 32)))
+			case "shr_s":
+				f.push(a >> (ub % 32))
+			case "shr_u":
+				f.push(int32(ua >> (ub % 32)))
+			}
+		}
+	case "i64":
+		{
+			b, a := f.i64(), f.i64()
+			switch op[4:] {
+			case "eq":
+				f.push(b2i(a == b))
+			case "ne":
+				f.push(b2i(a != b))
+			case "lt_s":
+				f.push(b2i(a < b))
+			case "gt_s":
+				f.push(b2i(a > b))
+			case "le_s":
+				f.push(b2i(a <= b))
+			case "ge_s":
+				f.push(b2i(a >= b))
+			case "add":
+				f.push(a + b)
+			case "sub":
+				f.push(a - b)
+			case "mul":
+				f.push(a * b)
+			case "div_s", "rem_s":
+				{
+					if b == 0 {
+						trap("integer divide by zero")
+					}
+					switch {
+					case op == "i64.rem_s" && b == -1:
+						f.push(int64(0))
+					case op == "i64.rem_s":
+						f.push(a % b)
+					case a == math.MinInt64 && b == -1:
+						trap("integer overflow")
+					default:
+						f.push(a / b)
+					}
+				}
+			}
+		}
+	case "f64":
+		{
+			b, a := f.f64(), f.f64()
+			switch op[4:] {
+			case "eq":
+				f.push(b2i(a == b))
+			case "ne":
+				f.push(b2i(a != b))
+			case "lt":
+				f.push(b2i(a < b))
+			case "gt":
+				f.push(b2i(a > b))
+			case "le":
+				f.push(b2i(a <= b))
+			case "ge":
+				f.push(b2i(a >= b))
+			case "add":
+				f.push(a + b)
+			case "sub":
+				f.push(a - b)
+			case "mul":
+				f.push(a * b)
+			case "div":
+				f.push(a / b)
+			}
+		}
+	}
+}
@@ -0,0 +1,708 @@
+// Package wat parses and runs the subset of the WebAssembly text format the
+// watgen package writes: imported and defined functions, one memory, data
+// segments, globals and the plain, unfolded form of the instructions. It
+// lets the modules be checked and run without a toolchain outside of Go.
+//
+// Parsing resolves every name and rejects what a validator would on the
+// code watgen writes, such as duplicate locals, unknown labels or calls to
+// functions that don't exist. Running checks the type of every operand.
+package wat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The sexpr structure is a node of the text format, either an atom or a
+// parenthesized list
+type sexpr struct {
+	atom   string
+	quoted bool
+	list   []*sexpr
+	isList bool
+	line   int
+}
+
+func (s *sexpr) String() string {
+	if !s.isList {
+		return s.atom
+	}
+	return "(" + s.head() + " ...)"
+}
+
+// head returns the first atom of a list
+func (s *sexpr) head() string {
+	if !s.isList || len(s.list) == 0 || s.list[0].isList {
+		return ""
+	}
+	return s.list[0].atom
+}
+
+// The lexer structure splits the source into parentheses, atoms and strings
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return fmt.Errorf("wat: line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+// skip skips whitespace and comments
+func (l *lexer) skip() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], ";;"):
+			{
+				for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+					l.pos++
+				}
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parse reads the next expression
+func (l *lexer) parse() (*sexpr, error) {
+	l.skip()
+	if l.pos >= len(l.src) {
+		return nil, l.errorf("unexpected end of input")
+	}
+	line := l.line
+	switch l.src[l.pos] {
+	case '(':
+		{
+			l.pos++
+			node := &sexpr{isList: true, line: line}
+			for {
+				l.skip()
+				if l.pos >= len(l.src) {
+					return nil, l.errorf("unclosed list started at line %d", line)
+				}
+				if l.src[l.pos] == ')' {
+					l.pos++
+					return node, nil
+				}
+				child, err := l.parse()
+				if err != nil {
+					return nil, err
+				}
+				node.list = append(node.list, child)
+			}
+		}
+	case ')':
+		return nil, l.errorf("unexpected )")
+	case '"':
+		return l.string()
+	}
+	start := l.pos
+	for l.pos < len(l.src) && !strings.ContainsRune(" \t\r\n()\";", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	return &sexpr{atom: l.src[start:l.pos], line: line}, nil
+}
+
+// string reads a string literal and decodes its escapes
+func (l *lexer) string() (*sexpr, error) {
+	line := l.line
+	l.pos++
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return nil, l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		l.pos++
+		switch {
+		case c == '"':
+			return &sexpr{atom: sb.String(), quoted: true, line: line}, nil
+		case c != '\\':
+			sb.WriteByte(c)
+		case l.pos >= len(l.src):
+			return nil, l.errorf("unterminated string")
+		default:
+			{
+				e := l.src[l.pos]
+				l.pos++
+				switch e {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				case '"', '\'', '\\':
+					sb.WriteByte(e)
+				default:
+					{
+						if l.pos >= len(l.src) {
+							return nil, l.errorf("unterminated string")
+						}
+						b, err := strconv.ParseUint(l.src[l.pos-1:l.pos+1], 16, 8)
+						if err != nil {
+							return nil, l.errorf("invalid escape \\%s", l.src[l.pos-1:l.pos+1])
+						}
+						l.pos++
+						sb.WriteByte(byte(b))
+					}
+				}
+			}
+		}
+	}
+}
+
+// Type is a value type, values of i32, i64 and f64 are held in Go as int32,
+// int64 and float64
+type Type string
+
+const (
+	I32 Type = "i32"
+	I64 Type = "i64"
+	F64 Type = "f64"
+)
+
+func parseType(s *sexpr) (Type, error) {
+	switch t := Type(s.atom); {
+	case s.isList:
+		return "", fmt.Errorf("wat: line %d: expected a type, found %s", s.line, s)
+	case t == I32 || t == I64 || t == F64:
+		return t, nil
+	}
+	return "", fmt.Errorf("wat: line %d: unsupported type %s", s.line, s.atom)
+}
+
+// zero returns the zero value of a type
+func zero(t Type) any {
+	switch t {
+	case I32:
+		return int32(0)
+	case I64:
+		return int64(0)
+	}
+	return float64(0)
+}
+
+// The Func structure is a function of a module, imported functions have no
+// body
+type Func struct {
+	Name    string
+	Params  []Type
+	Results []Type
+	// Module and Field name the import providing the function
+	Module, Field string
+	locals        []Type
+	body          []*instr
+}
+
+// The instr structure is an instruction with its immediates resolved
+type instr struct {
+	op   string
+	line int
+	// index of the local, global or function, or the depth of the label
+	index int
+	// depths of the labels of br_table
+	labels []int
+	value  any
+	offset uint32
+	// matching end and else of block, loop and if, els is -1 without else
+	end, els int
+}
+
+// The global structure is a global of the module
+type global struct {
+	t       Type
+	mutable bool
+	init    any
+}
+
+// The Module structure is a parsed module
+type Module struct {
+	Funcs   []*Func
+	funcs   map[string]int
+	globals []global
+	names   map[string]int
+	// pages is the initial size of the memory, -1 without memory
+	pages   int
+	data    []segment
+	exports map[string]int
+	memory  string
+}
+
+type segment struct {
+	offset int
+	bytes  []byte
+}
+
+// Parse parses and checks a module
+func Parse(src string) (*Module, error) {
+	l := &lexer{src: src, line: 1}
+	root, err := l.parse()
+	if err != nil {
+		return nil, err
+	}
+	if l.skip(); l.pos < len(l.src) {
+		return nil, l.errorf("unexpected input after the module")
+	}
+	if root.head() != "module" {
+		return nil, fmt.Errorf("wat: line %d: expected a module", root.line)
+	}
+
+	m := &Module{
+		funcs:   map[string]int{},
+		names:   map[string]int{},
+		pages:   -1,
+		exports: map[string]int{},
+	}
+	// functions and globals are declared before any body is resolved, a
+	// body may call the functions defined after it
+	bodies := map[*Func]*sexpr{}
+	for _, field := range root.list[1:] {
+		if err := m.declare(field, bodies); err != nil {
+			return nil, err
+		}
+	}
+	for _, fn := range m.Funcs {
+		if node, ok := bodies[fn]; ok {
+			if err := m.function(fn, node); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func fieldError(field *sexpr, format string, args ...any) error {
+	return fmt.Errorf("wat: line %d: %s", field.line, fmt.Sprintf(format, args...))
+}
+
+// id returns the $name at index i of a list, or ""
+func id(field *sexpr, i int) string {
+	if i < len(field.list) && !field.list[i].isList && strings.HasPrefix(field.list[i].atom, "$") {
+		return field.list[i].atom
+	}
+	return ""
+}
+
+// declare reads a field of the module
+func (m *Module) declare(field *sexpr, bodies map[*Func]*sexpr) error {
+	switch field.head() {
+	case "import":
+		{
+			if len(field.list) != 4 || !field.list[1].quoted || !field.list[2].quoted || field.list[3].head() != "func" {
+				return fieldError(field, "only function imports are supported")
+			}
+			fn := &Func{Module: field.list[1].atom, Field: field.list[2].atom}
+			return m.signature(fn, field.list[3])
+		}
+	case "func":
+		{
+			fn := &Func{}
+			if err := m.signature(fn, field); err != nil {
+				return err
+			}
+			bodies[fn] = field
+		}
+	case "memory":
+		{
+			if m.pages >= 0 {
+				return fieldError(field, "a second memory")
+			}
+			m.memory = id(field, 1)
+			last := field.list[len(field.list)-1]
+			pages, err := strconv.Atoi(last.atom)
+			if err != nil || last.isList || pages > 1<<16 {
+				return fieldError(field, "invalid memory size %s", last)
+			}
+			m.pages = pages
+		}
+	case "export":
+		{
+			if len(field.list) != 3 || !field.list[1].quoted || !field.list[2].isList {
+				return fieldError(field, "invalid export")
+			}
+			name, desc := field.list[1].atom, field.list[2]
+			switch desc.head() {
+			case "memory":
+				{
+					if m.pages < 0 || id(desc, 1) != m.memory {
+						return fieldError(field, "unknown memory %s", id(desc, 1))
+					}
+				}
+			case "func":
+				{
+					index, ok := m.funcs[id(desc, 1)]
+					if !ok {
+						return fieldError(field, "unknown function %s", id(desc, 1))
+					}
+					m.exports[name] = index
+				}
+			default:
+				return fieldError(field, "unsupported export %s", desc)
+			}
+		}
+	case "data":
+		{
+			if len(field.list) < 2 {
+				return fieldError(field, "invalid data segment")
+			}
+			offset := field.list[1]
+			if offset.head() != "i32.const" || len(offset.list) != 2 {
+				return fieldError(field, "the offset of a data segment must be an i32.const")
+			}
+			value, err := constant(I32, offset.list[1].atom)
+			if err != nil {
+				return fieldError(field, "%s", err)
+			}
+			seg := segment{offset: int(uint32(value.(int32)))}
+			for _, s := range field.list[2:] {
+				if !s.quoted {
+					return fieldError(field, "expected a string, found %s", s)
+				}
+				seg.bytes = append(seg.bytes, s.atom...)
+			}
+			m.data = append(m.data, seg)
+		}
+	case "global":
+		{
+			name := id(field, 1)
+			if len(field.list) != 4 || name == "" {
+				return fieldError(field, "invalid global")
+			}
+			if _, ok := m.names[name]; ok {
+				return fieldError(field, "duplicate global %s", name)
+			}
+			g := global{}
+			desc := field.list[2]
+			if desc.head() == "mut" && len(desc.list) == 2 {
+				g.mutable, desc = true, desc.list[1]
+			}
+			t, err := parseType(desc)
+			if err != nil {
+				return err
+			}
+			init := field.list[3]
+			if init.head() != string(t)+".const" || len(init.list) != 2 {
+				return fieldError(field, "the initial value of a global must be a %s.const", t)
+			}
+			if g.init, err = constant(t, init.list[1].atom); err != nil {
+				return fieldError(field, "%s", err)
+			}
+			g.t = t
+			m.names[name] = len(m.globals)
+			m.globals = append(m.globals, g)
+		}
+	default:
+		return fieldError(field, "unsupported field %s", field)
+	}
+	return nil
+}
+
+// signature reads the name, inline export, parameters and results of a
+// function and adds it to the module
+func (m *Module) signature(fn *Func, field *sexpr) error {
+	fn.Name = id(field, 1)
+	if fn.Name != "" {
+		if _, ok := m.funcs[fn.Name]; ok {
+			return fieldError(field, "duplicate function %s", fn.Name)
+		}
+		m.funcs[fn.Name] = len(m.Funcs)
+	}
+	for _, part := range field.list[1:] {
+		switch part.head() {
+		case "export":
+			{
+				if len(part.list) != 2 || !part.list[1].quoted {
+					return fieldError(part, "invalid export")
+				}
+				m.exports[part.list[1].atom] = len(m.Funcs)
+			}
+		case "result":
+			{
+				for _, s := range part.list[1:] {
+					t, err := parseType(s)
+					if err != nil {
+						return err
+					}
+					fn.Results = append(fn.Results, t)
+				}
+			}
+		case "param":
+			{
+				types := part.list[1:]
+				if id(part, 1) != "" {
+					types = part.list[2:]
+				}
+				for _, s := range types {
+					t, err := parseType(s)
+					if err != nil {
+						return err
+					}
+					fn.Params = append(fn.Params, t)
+				}
+			}
+		}
+	}
+	m.Funcs = append(m.Funcs, fn)
+	return nil
+}
+
+// immediates lists the instructions taking immediates besides block, loop,
+// if, br_table and the memory instructions
+var immediates = map[string]bool{
+	"local.get": true, "local.set": true, "local.tee": true,
+	"global.get": true, "global.set": true,
+	"call": true, "br": true, "br_if": true,
+	"i32.const": true, "i64.const": true, "f64.const": true,
+}
+
+// memoryOps lists the loads and stores, which take an optional offset
+var memoryOps = map[string]bool{
+	"i32.load": true, "i32.load8_u": true, "i64.load": true, "f64.load": true,
+	"i32.store": true, "i32.store8": true, "i64.store": true, "f64.store": true,
+}
+
+// function reads the locals and the body of a function, resolving every
+// name the instructions use
+func (m *Module) function(fn *Func, field *sexpr) error {
+	locals := map[string]int{}
+	addLocal := func(part *sexpr, name string, t Type) error {
+		if name != "" {
+			if _, ok := locals[name]; ok {
+				return fieldError(part, "duplicate local %s in %s", name, fn.Name)
+			}
+			locals[name] = len(fn.locals)
+		}
+		fn.locals = append(fn.locals, t)
+		return nil
+	}
+
+	var code []*sexpr
+	for i, part := range field.list[1:] {
+		switch part.head() {
+		case "export", "result":
+			continue
+		case "param", "local":
+			{
+				name, types := id(part, 1), part.list[1:]
+				if name != "" {
+					types = part.list[2:]
+					if len(types) != 1 {
+						return fieldError(part, "a named %s has one type", part.head())
+					}
+				}
+				for _, s := range types {
+					t, err := parseType(s)
+					if err != nil {
+						return err
+					}
+					if err := addLocal(part, name, t); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+		if i == 0 && id(field, 1) != "" {
+			continue
+		}
+		code = append(code, part)
+	}
+
+	// labels holds the names of the enclosing blocks, innermost last
+	labels := []string{}
+	// open holds the indexes of the instructions opening them
+	open := []int{}
+	depth := func(node *sexpr) (int, error) {
+		if n, err := strconv.Atoi(node.atom); err == nil {
+			if n < 0 || n > len(labels) {
+				return 0, fieldError(node, "unknown label %d", n)
+			}
+			return n, nil
+		}
+		for i := len(labels) - 1; i >= 0; i-- {
+			if labels[i] == node.atom {
+				return len(labels) - 1 - i, nil
+			}
+		}
+		return 0, fieldError(node, "unknown label %s", node.atom)
+	}
+
+	for i := 0; i < len(code); i++ {
+		node := code[i]
+		if node.isList || node.quoted {
+			return fieldError(node, "expected an instruction, found %s, folded instructions aren't supported", node)
+		}
+		in := &instr{op: node.atom, line: node.line, els: -1}
+		arg := func() (*sexpr, error) {
+			if i+1 >= len(code) || code[i+1].isList {
+				return nil, fieldError(node, "%s expects an immediate", node.atom)
+			}
+			i++
+			return code[i], nil
+		}
+
+		switch op := node.atom; {
+		case op == "block" || op == "loop" || op == "if":
+			{
+				name := ""
+				if i+1 < len(code) && !code[i+1].isList && strings.HasPrefix(code[i+1].atom, "$") {
+					i++
+					name = code[i].atom
+				}
+				if i+1 < len(code) && code[i+1].head() == "result" {
+					return fieldError(node, "blocks with results aren't supported")
+				}
+				labels = append(labels, name)
+				open = append(open, len(fn.body))
+			}
+		case op == "else":
+			{
+				if len(open) == 0 || fn.body[open[len(open)-1]].op != "if" || fn.body[open[len(open)-1]].els >= 0 {
+					return fieldError(node, "else without if")
+				}
+				fn.body[open[len(open)-1]].els = len(fn.body)
+			}
+		case op == "end":
+			{
+				if len(open) == 0 {
+					return fieldError(node, "end without block")
+				}
+				fn.body[open[len(open)-1]].end = len(fn.body)
+				labels, open = labels[:len(labels)-1], open[:len(open)-1]
+			}
+		case op == "br_table":
+			{
+				for i+1 < len(code) && !code[i+1].isList && !code[i+1].quoted && !known[code[i+1].atom] {
+					i++
+					d, err := depth(code[i])
+					if err != nil {
+						return err
+					}
+					in.labels = append(in.labels, d)
+				}
+				if len(in.labels) == 0 {
+					return fieldError(node, "br_table expects labels")
+				}
+			}
+		case memoryOps[op]:
+			{
+				for i+1 < len(code) && !code[i+1].isList && strings.Contains(code[i+1].atom, "=") {
+					i++
+					key, value, _ := strings.Cut(code[i].atom, "=")
+					n, err := strconv.ParseUint(value, 0, 32)
+					if err != nil || key != "offset" && key != "align" {
+						return fieldError(node, "invalid memory argument %s", code[i].atom)
+					}
+					if key == "offset" {
+						in.offset = uint32(n)
+					}
+				}
+			}
+		case immediates[op]:
+			{
+				imm, err := arg()
+				if err != nil {
+					return err
+				}
+				if err := m.resolve(in, imm, locals, fn, depth); err != nil {
+					return err
+				}
+			}
+		case !known[op]:
+			return fieldError(node, "unknown instruction %s", op)
+		}
+		fn.body = append(fn.body, in)
+	}
+	if len(open) > 0 {
+		return fieldError(field, "%s: block without end", fn.Name)
+	}
+	return nil
+}
+
+// resolve sets the immediate of an instruction
+func (m *Module) resolve(in *instr, imm *sexpr, locals map[string]int, fn *Func, depth func(*sexpr) (int, error)) error {
+	lookup := func(names map[string]int, count int, kind string) (int, error) {
+		if index, ok := names[imm.atom]; ok {
+			return index, nil
+		}
+		if index, err := strconv.Atoi(imm.atom); err == nil && index >= 0 && index < count {
+			return index, nil
+		}
+		return 0, fieldError(imm, "unknown %s %s", kind, imm.atom)
+	}
+
+	var err error
+	switch in.op {
+	case "local.get", "local.set", "local.tee":
+		in.index, err = lookup(locals, len(fn.locals), "local")
+	case "global.get", "global.set":
+		{
+			in.index, err = lookup(m.names, len(m.globals), "global")
+			if err == nil && in.op == "global.set" && !m.globals[in.index].mutable {
+				err = fieldError(imm, "global %s is immutable", imm.atom)
+			}
+		}
+	case "call":
+		in.index, err = lookup(m.funcs, len(m.Funcs), "function")
+	case "br", "br_if":
+		in.index, err = depth(imm)
+	default:
+		{
+			in.value, err = constant(Type(strings.TrimSuffix(in.op, ".const")), imm.atom)
+			if err != nil {
+				err = fieldError(imm, "%s", err)
+			}
+		}
+	}
+	return err
+}
+
+// constant parses the literal of a value of type t
+func constant(t Type, s string) (any, error) {
+	s = strings.ReplaceAll(s, "_", "")
+	switch t {
+	case I32:
+		{
+			n, err := strconv.ParseInt(s, 0, 64)
+			if err != nil || n < math.MinInt32 || n > math.MaxUint32 {
+				return nil, fmt.Errorf("invalid i32 %s", s)
+			}
+			return int32(n), nil
+		}
+	case I64:
+		{
+			n, err := strconv.ParseInt(s, 0, 64)
+			if err != nil {
+				u, uerr := strconv.ParseUint(s, 0, 64)
+				if uerr != nil {
+					return nil, fmt.Errorf("invalid i64 %s", s)
+				}
+				n = int64(u)
+			}
+			return n, nil
+		}
+	}
+	switch s {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid f64 %s", s)
+	}
+	return f, nil
+}
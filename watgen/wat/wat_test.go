@@ -0,0 +1,122 @@
+package wat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{src: `(module (func $f (local $a i32) (local $a i64)))`, msg: "duplicate local $a"},
+		{src: `(module (func $f (param $a i32) (local $a i32)))`, msg: "duplicate local $a"},
+		{src: `(module (func $f block $b br $c end))`, msg: "unknown label $c"},
+		{src: `(module (func $f local.get $x))`, msg: "unknown local $x"},
+		{src: `(module (func $f call $g))`, msg: "unknown function $g"},
+		{src: `(module (func $f i32.popcnt))`, msg: "unknown instruction i32.popcnt"},
+		{src: `(module (func $f block))`, msg: "block without end"},
+		{src: `(module (func $f (i32.add (i32.const 1) (i32.const 2))))`, msg: "folded instructions"},
+		{src: `(module (global $g i32 (i32.const 0)) (func $f i32.const 1 global.set $g))`, msg: "immutable"},
+		{src: `(module (data (i32.const 0) "\zz"))`, msg: "invalid escape"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.src)
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: got %v, want %q", test.src, err, test.msg)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	src := `(module
+  (import "env" "print" (func $print (param i64)))
+  (memory $memory 1)
+  (data (i32.const 8) "\2a\00\00\00")
+  (func $sum (param $n i64) (result i64)
+    (local $total i64)
+    block $done
+      loop $next
+        local.get $n
+        i64.eqz
+        br_if $done
+        local.get $total
+        local.get $n
+        i64.add
+        local.set $total
+        local.get $n
+        i64.const 1
+        i64.sub
+        local.set $n
+        br $next
+      end
+    end
+    local.get $total)
+  (func $main (export "main")
+    i64.const 10
+    call $sum
+    call $print
+    i32.const 8
+    i32.load
+    i64.extend_i32_u
+    call $print
+    i32.const 1
+    if
+      i64.const 1
+      call $print
+    else
+      unreachable
+    end))`
+	m, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed := []int64{}
+	in, err := m.Instantiate(Imports{
+		"env.print": func(in *Instance, args []any) ([]any, error) {
+			printed = append(printed, args[0].(int64))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Call("main"); err != nil {
+		t.Fatal(err)
+	}
+	if len(printed) != 3 || printed[0] != 55 || printed[1] != 42 || printed[2] != 1 {
+		t.Errorf("printed %v, want [55 42 1]", printed)
+	}
+}
+
+func TestTraps(t *testing.T) {
+	tests := []struct {
+		body, msg string
+	}{
+		{body: "unreachable", msg: "unreachable"},
+		{body: "i64.const 1 i64.const 0 i64.div_s drop", msg: "integer divide by zero"},
+		{body: "i32.const 70000 i32.load drop", msg: "out of bounds"},
+		{body: "i32.const 1 i64.const 1 i64.add drop", msg: "type mismatch"},
+		{body: "i32.const 1", msg: "values on the stack"},
+		{body: "loop $l br $l end", msg: "too many steps"},
+	}
+	for _, test := range tests {
+		m, err := Parse(`(module (memory 1) (func $main (export "main") ` + test.body + `))`)
+		if err != nil {
+			t.Fatalf("%s: %v", test.body, err)
+		}
+		in, err := m.Instantiate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in.MaxSteps = 1000
+		_, err = in.Call("main")
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: got %v, want %q", test.body, err, test.msg)
+		}
+		if test.msg != "too many steps" && !errors.As(err, new(*Trap)) {
+			t.Errorf("%s: %v isn't a trap", test.body, err)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package watgen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/parser/ast"
+	"github.com/zSnails/alpha/types"
+)
+
+// The Generator structure translates functions of the intermediate
+// representation into a WebAssembly module in the text format. The module
+// exports its memory and a main function running the program.
+type Generator struct {
+	fn      *ir.Func
+	out     bytes.Buffer
+	indent  int
+	locals  []string
+	data    bytes.Buffer
+	strings map[string]int
+	used    map[string]bool
+	errors  []error
+}
+
+func NewGenerator(fn *ir.Func) *Generator {
+	g := &Generator{
+		fn:      fn,
+		strings: map[string]int{},
+		used:    map[string]bool{},
+	}
+	// address 0 holds the empty string
+	g.data.Write(make([]byte, 4))
+	g.strings[""] = 0
+	return g
+}
+
+// Generate translates fn, which must not be in SSA form, into a module
+// importing the functions it prints with from the "alpha" namespace
+func Generate(fn *ir.Func) ([]byte, error) {
+	return NewGenerator(fn).Program()
+}
+
+func (g *Generator) errorf(pos ast.Position, format string, args ...any) {
+	g.errors = append(g.errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+// line writes an indented instruction
+func (g *Generator) line(format string, args ...any) {
+	g.out.WriteString(strings.Repeat("  ", g.indent))
+	fmt.Fprintf(&g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+// comment points the following instructions back to their position in the
+// source
+func (g *Generator) comment(pos ast.Position) {
+	if pos.File != "" {
+		g.line(";; %s", pos)
+	}
+}
+
+// literal returns the address of a string literal, equal literals share
+// their bytes
+func (g *Generator) literal(s string) int {
+	if address, ok := g.strings[s]; ok {
+		return address
+	}
+	for g.data.Len()%4 != 0 {
+		g.data.WriteByte(0)
+	}
+	address := g.data.Len()
+	g.data.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	g.data.WriteString(s)
+	g.strings[s] = address
+	return address
+}
+
+// Program translates the function into the main function of the module.
+// Structured control flow can't jump between arbitrary blocks, so main is a
+// loop dispatching on the index of the block to run next with br_table.
+// Every block is nested in the ones after it and its code follows the end of
+// its own, branching to it runs its code and falling out of its code runs the
+// block laid out next.
+func (g *Generator) Program() ([]byte, error) {
+	if g.fn.SSA {
+		return nil, fmt.Errorf("watgen: %s is in SSA form", g.fn.Name)
+	}
+	for _, v := range g.fn.Vars() {
+		g.locals = append(g.locals, fmt.Sprintf("(local $%s %s)", v.Ident(), watType(v.T)))
+	}
+	g.locals = append(g.locals, "(local $block i32)")
+
+	g.indent = 2
+	g.line("loop $dispatch")
+	g.indent++
+	labels := make([]string, len(g.fn.Blocks))
+	for i := len(g.fn.Blocks) - 1; i >= 0; i-- {
+		labels[i] = "$" + g.fn.Blocks[i].String()
+		g.line("block %s", labels[i])
+		g.indent++
+	}
+	g.line("local.get $block")
+	g.line("br_table %s", strings.Join(labels, " "))
+	for _, b := range g.fn.Blocks {
+		g.indent--
+		g.line("end")
+		g.Block(b)
+	}
+	g.indent--
+	g.line("end")
+
+	if len(g.errors) > 0 {
+		return nil, g.errors[0]
+	}
+	body := g.out.String()
+
+	heap := (g.data.Len() + 7) &^ 7
+	pages := max(1, (heap+0xffff)>>16)
+
+	used := []string{}
+	for name := range g.used {
+		used = append(used, name)
+	}
+	sort.Strings(used)
+
+	var out bytes.Buffer
+	out.WriteString(";; Code generated by alpha compile. DO NOT EDIT.\n")
+	out.WriteString("(module\n")
+	out.WriteString(imports)
+	for _, name := range used {
+		out.WriteString(optionalImports[name])
+	}
+	fmt.Fprintf(&out, "\n  (memory $memory %d)\n", pages)
+	out.WriteString("  (export \"memory\" (memory $memory))\n")
+	fmt.Fprintf(&out, "  (data (i32.const 0) %s)\n", quote(g.data.Bytes()))
+	fmt.Fprintf(&out, "  (global $alpha_heap (mut i32) (i32.const %d))\n\n", heap)
+	out.WriteString(runtime)
+	for _, name := range used {
+		out.WriteString("\n" + wrappers[name])
+	}
+	out.WriteString("\n  (func $main (export \"main\")\n")
+	for _, local := range g.locals {
+		out.WriteString("    " + local + "\n")
+	}
+	out.WriteString(body)
+	out.WriteString("  )\n)\n")
+	return out.Bytes(), nil
+}
+
+// goTo continues with the given block, falling through when it is the next
+// one
+func (g *Generator) goTo(from, to *ir.Block) {
+	if to.Index == from.Index+1 {
+		return
+	}
+	g.line("i32.const %d", to.Index)
+	g.line("local.set $block")
+	g.line("br $dispatch")
+}
+
+// Block translates the instructions of a basic block
+func (g *Generator) Block(b *ir.Block) {
+	g.line(";; %s", b)
+	for _, instr := range b.Instrs {
+		g.comment(instr.Pos)
+		switch {
+		case instr.Op == ir.Copy || instr.Op == ir.Declare:
+			{
+				g.value(instr.Args[0])
+				g.line("local.set $%s", instr.Dst.Ident())
+			}
+		case instr.Op == ir.Convert:
+			{
+				g.value(instr.Args[0])
+				g.line("f64.convert_i64_s")
+				g.line("local.set $%s", instr.Dst.Ident())
+			}
+		case instr.Op.IsBinary():
+			g.binary(instr)
+		case instr.Op == ir.Call:
+			g.call(instr)
+		case instr.Op == ir.Assert:
+			{
+				g.value(instr.Args[0])
+				g.line("i32.eqz")
+				g.line("if")
+				g.line("  unreachable")
+				g.line("end")
+			}
+		case instr.Op == ir.Jump:
+			g.goTo(b, b.Succs[0])
+		case instr.Op == ir.Branch:
+			{
+				then, otherwise := b.Succs[0], b.Succs[1]
+				g.line("i32.const %d", then.Index)
+				g.line("i32.const %d", otherwise.Index)
+				g.value(instr.Args[0])
+				g.line("select")
+				g.line("local.set $block")
+				g.line("br $dispatch")
+			}
+		case instr.Op == ir.Return:
+			g.line("return")
+		default:
+			g.errorf(instr.Pos, "%s can't be translated to WebAssembly", instr.Op)
+		}
+	}
+}
+
+// call translates a call to a builtin, print and println take values of any
+// type so they are expanded into a call per argument.
+func (g *Generator) call(instr *ir.Instr) {
+	sym := instr.Callee
+	switch {
+	case sym.Name == "print" || sym.Name == "println":
+		{
+			for _, arg := range instr.Args {
+				g.value(arg)
+				g.print(arg.Type())
+			}
+			if sym.Name == "println" {
+				g.line("i32.const %d", g.literal("\n"))
+				g.print(types.String)
+			}
+			return
+		}
+	case !builtins[sym.Name]:
+		{
+			g.errorf(instr.Pos, "%s can't be translated to WebAssembly, modules can't read input or call host functions", sym.Name)
+			return
+		}
+	}
+	if _, ok := wrappers[sym.Name]; ok {
+		g.used[sym.Name] = true
+	}
+
+	for _, arg := range instr.Args {
+		g.value(arg)
+	}
+	g.line("call $alpha_%s", sym.Name)
+	switch {
+	case instr.Dst != nil:
+		g.line("local.set $%s", instr.Dst.Ident())
+	case sym.Sig.Result != types.Void:
+		g.line("drop")
+	}
+}
+
+// print prints the value on top of the stack
+func (g *Generator) print(t types.Type) {
+	switch t {
+	case types.Integer:
+		g.line("call $print_i64")
+	case types.Float:
+		g.line("call $print_f64")
+	case types.Boolean:
+		{
+			g.line("if")
+			g.line("  i32.const %d", g.literal("true"))
+			g.line("  call $alpha_print")
+			g.line("else")
+			g.line("  i32.const %d", g.literal("false"))
+			g.line("  call $alpha_print")
+			g.line("end")
+		}
+	default:
+		g.line("call $alpha_print")
+	}
+}
+
+// value pushes an operand
+func (g *Generator) value(value ir.Value) {
+	if v, ok := value.(*ir.Var); ok {
+		g.line("local.get $%s", v.Ident())
+		return
+	}
+	c := value.(*ir.Const)
+	if c.Value == nil {
+		c = ir.Zero(c.T)
+	}
+	switch v := c.Value.(type) {
+	case int:
+		g.line("i64.const %d", v)
+	case float64:
+		g.line("f64.const %s", formatFloat(v))
+	case string:
+		g.line("i32.const %d", g.literal(v))
+	case bool:
+		{
+			if v {
+				g.line("i32.const 1")
+			} else {
+				g.line("i32.const 0")
+			}
+		}
+	}
+}
+
+// binary translates an operation, strings can only be compared for
+// equality
+func (g *Generator) binary(instr *ir.Instr) {
+	g.value(instr.Args[0])
+	g.value(instr.Args[1])
+	switch t := instr.Args[0].Type(); t {
+	case types.String:
+		{
+			if instr.Op != ir.Equal {
+				g.errorf(instr.Pos, "operator %s not defined on strings", instr.Op)
+				return
+			}
+			g.line("call $alpha_equal")
+		}
+	case types.Boolean:
+		g.line("i32.%s", operators[instr.Op])
+	case types.Float:
+		g.line("f64.%s", floatOperators[instr.Op])
+	default:
+		{
+			// div_s traps on the smallest integer divided by -1, the
+			// runtime wraps around like the other targets do
+			if instr.Op == ir.Div {
+				g.line("call $alpha_div")
+				break
+			}
+			g.line("i64.%s", operators[instr.Op])
+		}
+	}
+	g.line("local.set $%s", instr.Dst.Ident())
+}
+
+// formatFloat writes a float the way the text format reads it back exactly
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// quote writes the bytes of a data segment as a string, every byte outside
+// of printable ASCII is escaped in hexadecimal.
+func quote(data []byte) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range data {
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// operators maps the operations to the integer instructions, booleans share
+// the comparisons of i32. Divisions go through the runtime.
+var operators = map[ir.Op]string{
+	ir.Add:          "add",
+	ir.Sub:          "sub",
+	ir.Mul:          "mul",
+	ir.Less:         "lt_s",
+	ir.Greater:      "gt_s",
+	ir.LessEqual:    "le_s",
+	ir.GreaterEqual: "ge_s",
+	ir.Equal:        "eq",
+}
+
+var floatOperators = map[ir.Op]string{
+	ir.Add:          "add",
+	ir.Sub:          "sub",
+	ir.Mul:          "mul",
+	ir.Div:          "div",
+	ir.Less:         "lt",
+	ir.Greater:      "gt",
+	ir.LessEqual:    "le",
+	ir.GreaterEqual: "ge",
+	ir.Equal:        "eq",
+}
+
+func watType(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "i64"
+	case types.Float:
+		return "f64"
+	}
+	return "i32"
+}
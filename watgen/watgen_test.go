@@ -0,0 +1,138 @@
+package watgen_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zSnails/alpha/interpreter"
+	"github.com/zSnails/alpha/ir"
+	"github.com/zSnails/alpha/loader"
+	"github.com/zSnails/alpha/stdlib"
+	"github.com/zSnails/alpha/types"
+	"github.com/zSnails/alpha/watgen"
+	"github.com/zSnails/alpha/watgen/wat"
+)
+
+// run runs the main function of a module printing to out
+func run(src []byte, out *bytes.Buffer) error {
+	module, err := wat.Parse(string(src))
+	if err != nil {
+		return err
+	}
+	instance, err := module.Instantiate(watgen.Host(out))
+	if err != nil {
+		return err
+	}
+	instance.MaxSteps = 1_000_000
+	_, err = instance.Call("main")
+	return err
+}
+
+// compare runs a program with the interpreter and as a module, before and
+// after optimizing, the output and whether the run failed must match
+func compare(t *testing.T, program *loader.Program) {
+	t.Helper()
+	var want bytes.Buffer
+	wantErr := interpreter.NewInterpreter(program.Info, stdlib.Prelude(), strings.NewReader(""), &want).Run(context.Background(), program.Root)
+
+	for _, optimize := range []bool{false, true} {
+		fn, err := ir.Lower(program.Root, program.Info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if optimize {
+			fn.Optimize(nil)
+			fn.FromSSA()
+		}
+		src, err := watgen.Generate(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		err = run(src, &got)
+		if err != nil && !errors.As(err, new(*wat.Trap)) && !errors.As(err, new(*watgen.ExitError)) {
+			t.Fatalf("optimized %t: the module doesn't run: %v\n%s", optimize, err, src)
+		}
+		if got.String() != want.String() {
+			t.Errorf("optimized %t: the module prints %q, the interpreter %q", optimize, got.String(), want.String())
+		}
+		if (wantErr == nil) != (err == nil) {
+			t.Errorf("optimized %t: the module stops with %v, the interpreter with %v", optimize, err, wantErr)
+		}
+	}
+}
+
+func load(t *testing.T, name, src string) *loader.Program {
+	t.Helper()
+	program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource(name, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestPrograms(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "tests", "*.alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, err := loader.NewLoader(nil, stdlib.Prelude().Scope(types.Universe())).LoadSource(file, string(src))
+			if err != nil {
+				t.Skip(err)
+			}
+			if filepath.Base(file) == "while-do.alpha" {
+				t.Skip("loops forever")
+			}
+			compare(t, program)
+		})
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	tests := []string{
+		`let var s : String in println(s)`,
+		`let var s : String; var t : String in begin s = "a"; if s == "a" then t = "b" else begin end; println(s, t) end`,
+		`let var i : Integer in begin i = 0; while i < 3 do let var s : String in begin if i == 1 then s = "x" else begin end; println(s); i = i + 1 end end`,
+		`println(1 / 0)`,
+		`let var x : Integer in begin x = 0 - 9223372036854775807 - 1; println(x / (0 - 1), x / 1, 7 / (0 - 1)) end`,
+		`begin println(concat("ho", "la"), length("hola"), substr("hola", 1, 2)); println(substr("hola", 3, 2)) end`,
+		`println(1.5 * 2, toInt(2.7), toFloat(3), abs(0.0 - 1.5), sqrt(16.0), pow(2.0, 10.0), 1.0 / 0.0)`,
+		`begin println("a" == "a", "a" == "b", true == false); exit(3); println(1) end`,
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			compare(t, load(t, "test.alpha", src))
+		})
+	}
+}
+
+// TestStringOperators checks that strings are only compared for equality,
+// the checker rejects the other operators but the IR allows them
+func TestStringOperators(t *testing.T) {
+	program := load(t, "test.alpha", `let var b : Boolean in b = "a" == "b"`)
+	fn, err := ir.Lower(program.Root, program.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Op == ir.Equal {
+				instr.Op = ir.Less
+			}
+		}
+	}
+	if _, err := watgen.Generate(fn); err == nil || !strings.Contains(err.Error(), "not defined on strings") {
+		t.Errorf("got %v, want an error", err)
+	}
+}